@@ -34,6 +34,106 @@ type EnvironmentSpec struct {
 
 	// Clusters defines the values specific for each cluster instance.
 	Clusters []ClusterSpec `json:"clusters,omitempty"`
+
+	// Rollout defines the order in which cluster changes are rolled out.
+	// If the rollout spec is omitted all clusters are changed in the order they are specified.
+	// +optional
+	Rollout RolloutSpec `json:"rollout,omitempty"`
+
+	// RunRetention defines how long EnvironmentRun audit records are kept.
+	// +optional
+	RunRetention RunRetentionSpec `json:"runRetention,omitempty"`
+
+	// Notifications defines the endpoints that are notified of step transitions.
+	// +optional
+	Notifications []NotificationSpec `json:"notifications,omitempty"`
+}
+
+// NotificationSpec defines an endpoint that is notified of step transitions.
+type NotificationSpec struct {
+	// Name of the notification.
+	Name string `json:"name"`
+
+	// Type of the endpoint.
+	Type NotificationType `json:"type"`
+
+	// URL of the endpoint, for example a Slack or Teams incoming webhook URL.
+	// The URL can be a vault reference.
+	URL string `json:"url"`
+
+	// Events are the events to notify, empty notifies all events.
+	// +optional
+	Events []NotificationEvent `json:"events,omitempty"`
+
+	// Template is a Go text/template that renders the message text from an event with the fields
+	// Type, Namespace, Environment, Step, State, Message, Time and Suppressed.
+	// +optional
+	Template string `json:"template,omitempty"`
+
+	// MinInterval is the min time between notifications of an environment to the endpoint, notifications that
+	// arrive sooner are suppressed. Only StepStarted notifications are suppressed.
+	// Zero defaults to 10s.
+	// +optional
+	MinInterval metav1.Duration `json:"minInterval,omitempty"`
+}
+
+// NotificationType is the type of endpoint that is notified.
+// +kubebuilder:validation:Enum=slack;teams;webhook
+type NotificationType string
+
+const (
+	NotificationSlack   NotificationType = "slack"
+	NotificationTeams   NotificationType = "teams"
+	NotificationWebhook NotificationType = "webhook"
+)
+
+// NotificationEvent is a step transition that is notified.
+// +kubebuilder:validation:Enum=StepStarted;StepSucceeded;StepFailed;BudgetExceeded
+type NotificationEvent string
+
+const (
+	EventStepStarted    NotificationEvent = "StepStarted"
+	EventStepSucceeded  NotificationEvent = "StepSucceeded"
+	EventStepFailed     NotificationEvent = "StepFailed"
+	EventBudgetExceeded NotificationEvent = "BudgetExceeded"
+)
+
+// RunRetentionSpec defines which EnvironmentRuns are kept.
+type RunRetentionSpec struct {
+	// MaxRuns is the max number of EnvironmentRuns kept per step.
+	// Zero defaults to 10.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxRuns int32 `json:"maxRuns,omitempty"`
+
+	// MaxAge is the max age of an EnvironmentRun.
+	// Zero keeps EnvironmentRuns regardless of their age.
+	// +optional
+	MaxAge metav1.Duration `json:"maxAge,omitempty"`
+}
+
+// RolloutSpec defines how cluster changes are rolled out in batches.
+// Between batches a health gate waits for SoakTime and then checks the health of the clusters in the batch.
+// When a health gate fails the rollout halts.
+type RolloutSpec struct {
+	// Canaries are the names of the clusters that are changed first, the canaries form the first batch.
+	// +optional
+	Canaries []string `json:"canaries,omitempty"`
+
+	// BatchSize is the maximum number of (non canary) clusters that are changed in a batch.
+	// Zero changes all clusters in one batch.
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	BatchSize int32 `json:"batchSize,omitempty"`
+
+	// SoakTime is the time to wait after a batch has been changed before its health is checked.
+	// +optional
+	SoakTime metav1.Duration `json:"soakTime,omitempty"`
+
+	// HealthNamespaces are the namespaces in which all pods must be healthy to pass the health gate.
+	// If omitted the pods in all namespaces are checked.
+	// +optional
+	HealthNamespaces []string `json:"healthNamespaces,omitempty"`
 }
 
 // InfraSpec defines the infrastructure that is used by all clusters.
@@ -51,10 +151,26 @@ type InfraSpec struct {
 	// +optional
 	Budget InfraBudget `json:"budget,omitempty"`
 
+	// PlanRules deny a terraform plan when one of the planned resource changes matches a rule.
+	// The rules are checked by the Infra step, a Destroy (spec.destroy: true) ignores them.
+	// +optional
+	PlanRules []PlanRule `json:"planRules,omitempty"`
+
 	// Schedule is a CRON formatted string defining when changed can be applied.
 	// If the schedule is omitted then changes will be applied immediately.
 	// +optional
-	Schedule string `json:"schedule,omitempty"`
+	Schedule string `json:"schedule,omitempty" hash:"ignore"`
+
+	// Windows are the maintenance windows in which changes can be applied.
+	// When both Schedule and Windows are omitted changes will be applied immediately.
+	// Schedule and Windows apply to the Infra, AKSPool and Destroy steps.
+	// +optional
+	Windows []MaintenanceWindow `json:"windows,omitempty" hash:"ignore"`
+
+	// Blackouts are periods in which no changes are applied, for example holidays or change freezes.
+	// Blackouts apply to all steps and take precedence over schedules and windows.
+	// +optional
+	Blackouts []MaintenanceWindow `json:"blackouts,omitempty" hash:"ignore"`
 
 	// Source is the repository that contains Terraform infrastructure code.
 	Source SourceSpec `json:"source,omitempty"`
@@ -74,11 +190,35 @@ type InfraSpec struct {
 	// +optional
 	AZ AZSpec `json:"az,omitempty"`
 
+	// Credentials are the cloud credentials and KeyVault of this environment.
+	// If the credentials spec is omitted the operator wide credentials and vault are used.
+	// +optional
+	Credentials CredentialsSpec `json:"credentials,omitempty" hash:"ignore"`
+
 	// X are extension values (when regular values don't fit the need)
 	// +optional
 	X map[string]string `json:"x,omitempty"`
 }
 
+// CredentialsSpec references the cloud credentials of an environment.
+type CredentialsSpec struct {
+	// SecretName is the name of a Secret in the namespace of the Environment.
+	// The Secret key 'credentials.json' contains the ServicePrincipal with the same JSON fields as the operator
+	// --credentials-file.
+	// If empty the operator wide identity is used.
+	// +optional
+	SecretName string `json:"secretName,omitempty"`
+
+	// Vault is the name of the KeyVault that contains the secrets referenced from this environment.
+	// Vault requires SecretName, the vault is read with the credentials in the Secret.
+	// If empty the operator wide vault is used.
+	// +optional
+	Vault string `json:"vault,omitempty"`
+}
+
+// CredentialsSecretKey is the key of the Secret data that contains the ServicePrincipal.
+const CredentialsSecretKey = "credentials.json"
+
 // InfraBudget defines how many changes the operator is allowed to make.
 type InfraBudget struct {
 	// AddLimit is the maximum number of resources that the operator is allowed to add.
@@ -95,8 +235,97 @@ type InfraBudget struct {
 	// Exceeded this number will result in an error.
 	// +optional
 	DeleteLimit *int32 `json:"deleteLimit,omitempty"`
+
+	// ReplaceLimit is the maximum number of resources that the operator is allowed to replace (delete and create).
+	// A replace also counts as an add and a delete.
+	// Exceeded this number will result in an error.
+	// +optional
+	ReplaceLimit *int32 `json:"replaceLimit,omitempty"`
+
+	// ResourceTypes are limits per terraform resource type, for example azurerm_kubernetes_cluster.
+	// These limits apply in addition to the overall limits.
+	// +optional
+	ResourceTypes map[string]ResourceBudget `json:"resourceTypes,omitempty"`
+}
+
+// ResourceBudget defines how many changes the operator is allowed to make to resources of a specific type.
+type ResourceBudget struct {
+	// AddLimit is the maximum number of resources of this type that the operator is allowed to add.
+	// +optional
+	AddLimit *int32 `json:"addLimit,omitempty"`
+
+	// UpdateLimit is the maximum number of resources of this type that the operator is allowed to update.
+	// +optional
+	UpdateLimit *int32 `json:"updateLimit,omitempty"`
+
+	// DeleteLimit is the maximum number of resources of this type that the operator is allowed to delete.
+	// +optional
+	DeleteLimit *int32 `json:"deleteLimit,omitempty"`
+
+	// ReplaceLimit is the maximum number of resources of this type that the operator is allowed to replace.
+	// +optional
+	ReplaceLimit *int32 `json:"replaceLimit,omitempty"`
 }
 
+// MaintenanceWindow is a period that starts at each Schedule firing and lasts for Duration.
+type MaintenanceWindow struct {
+	// Name of the window, for example 'nightly' or 'christmas'.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Schedule is a CRON formatted string defining when the window starts.
+	// For example "0 22 * * MON-FRI" starts a window at 22:00 on working days.
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window lasts, for example "4h" or "72h".
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone name (for example Europe/Amsterdam) in which Schedule is evaluated.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// PlanRule denies planned resource changes that match all of the (non-empty) rule fields.
+type PlanRule struct {
+	// Name of the rule, it is used in messages to refer to the rule.
+	Name string `json:"name,omitempty"`
+
+	// Type is the terraform resource type to match, for example azurerm_kubernetes_cluster.
+	// An empty type matches all resource types.
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Address is a glob pattern that matches the resource address, for example module.aks*.azurerm_subnet.*
+	// See https://golang.org/pkg/path/#Match for the pattern syntax.
+	// An empty address matches all addresses.
+	// +optional
+	Address string `json:"address,omitempty"`
+
+	// Actions are the changes that are denied.
+	// A delete or create also matches the delete or create part of a replace.
+	// An empty list denies all changes.
+	// +optional
+	Actions []PlanAction `json:"actions,omitempty"`
+
+	// Tags match resources that have all these tags with the same value.
+	// An empty value matches any value.
+	// For example {protected: ""} matches all resources with a 'protected' tag.
+	// +optional
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// PlanAction is a change to a resource.
+// +kubebuilder:validation:Enum=create;update;delete;replace
+type PlanAction string
+
+const (
+	PlanActionCreate  PlanAction = "create"
+	PlanActionUpdate  PlanAction = "update"
+	PlanActionDelete  PlanAction = "delete"
+	PlanActionReplace PlanAction = "replace"
+)
+
 // ClusterSpec defines cluster specific infra and k8s resources.
 type ClusterSpec struct {
 	// Name is the cluster name.
@@ -360,8 +589,14 @@ type ClusterAZSpec struct {
 type ClusterAddonSpec struct {
 	// Schedule is a CRON formatted string defining when changed can be applied.
 	// If the schedule is omitted then changes will be applied immediately.
+	// Schedule and Windows apply to the AKSAddonPreflight and Addons steps of the cluster.
+	// +optional
+	Schedule string `json:"schedule,omitempty" hash:"ignore"`
+
+	// Windows are the maintenance windows in which changes can be applied.
+	// When both Schedule and Windows are omitted changes will be applied immediately.
 	// +optional
-	Schedule string `json:"schedule,omitempty"`
+	Windows []MaintenanceWindow `json:"windows,omitempty" hash:"ignore"`
 
 	// Source is the repository that contains the k8s addons resources.
 	Source SourceSpec `json:"source,omitempty"`
@@ -385,6 +620,72 @@ type EnvironmentStatus struct {
 
 	// Step contains the latest available observations of the Environment's state.
 	Steps map[string]StepStatus `json:"steps,omitempty"`
+
+	// Rollout shows the progress of a batched rollout.
+	// +optional
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// NextWindow is the time the next step is allowed to run.
+	// It is only set when a step is waiting for its maintenance window.
+	// +optional
+	NextWindow *metav1.Time `json:"nextWindow,omitempty"`
+
+	// ObservedGeneration is the metadata.generation of the Environment that is planned.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Clusters summarize the state of the steps per cluster.
+	// +optional
+	Clusters []ClusterStatus `json:"clusters,omitempty"`
+
+	// History contains the most recent runs per step, oldest first.
+	// History is kept when a step is reset and removed when the step is no longer part of the plan.
+	// +optional
+	History map[string]StepRuns `json:"history,omitempty"`
+}
+
+// StepRuns are runs of a step, oldest first.
+type StepRuns []StepRun
+
+// StepRun is an execution of a step.
+type StepRun struct {
+	// Start is the time the step started running.
+	Start metav1.Time `json:"start"`
+	// End is the time the step reached its final state.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+	// State is the outcome of the run, Running when the run hasn't ended.
+	State StepState `json:"state"`
+	// Message is the last message of the run.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Source is the source revision applied by the run.
+	// +optional
+	Source *StepSource `json:"source,omitempty"`
+	// Added, Changed and Deleted are the number of objects affected by the run.
+	// +optional
+	Added int `json:"added,omitempty"`
+	// +optional
+	Changed int `json:"changed,omitempty"`
+	// +optional
+	Deleted int `json:"deleted,omitempty"`
+	// LogID identifies the logs of the run in the log sink.
+	// +optional
+	LogID string `json:"logID,omitempty"`
+}
+
+// ClusterStatus is a synopsis of the steps of a cluster.
+type ClusterStatus struct {
+	// Name of the cluster.
+	Name string `json:"name"`
+	// Status is True when all cluster steps are Ready, one of True, False, Unknown.
+	Status metav1.ConditionStatus `json:"status"`
+	// Reason is the state of the cluster steps in CamelCase.
+	// +optional
+	Reason EnvironmentConditionReason `json:"reason,omitempty"`
+	// Message shows the number of steps per state.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // StepStatus is the last observed status of a Step.
@@ -402,6 +703,44 @@ type StepStatus struct {
 	// An opaque value representing the config/parameters applied by a step.
 	// Only valid when state=Ready.
 	Hash string `json:"hash,omitempty"`
+	// Source is the source revision applied by a step.
+	// Only valid when state=Ready.
+	// +optional
+	Source *StepSource `json:"source,omitempty"`
+	// Generation is the metadata.generation of the Environment that is applied by a step.
+	// Only valid when state=Ready.
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+}
+
+// StepSource identifies the source revision applied by a step.
+type StepSource struct {
+	// URL of the source repo.
+	URL string `json:"url,omitempty"`
+	// Ref is the reference as specified in the SourceSpec.
+	// +optional
+	Ref string `json:"ref,omitempty"`
+	// Commit is the revision Ref resolved to, for type=git this is the commit SHA.
+	// +optional
+	Commit string `json:"commit,omitempty"`
+	// Area is the directory path of the part of the repo that is used.
+	// +optional
+	Area string `json:"area,omitempty"`
+	// AreaHash is the hash of the content of Area.
+	// +optional
+	AreaHash string `json:"areaHash,omitempty"`
+}
+
+// RolloutStatus is the progress of a batched rollout.
+type RolloutStatus struct {
+	// Batch is the number of the batch that is being rolled out (starting at 1).
+	// Batch is 0 when all batches are rolled out.
+	Batch int32 `json:"batch"`
+	// Batches is the total number of batches.
+	Batches int32 `json:"batches"`
+	// Clusters are the names of the clusters in the batch that is being rolled out.
+	// +optional
+	Clusters []string `json:"clusters,omitempty"`
 }
 
 // StepState is the current state of the step.
@@ -418,7 +757,7 @@ const (
 type EnvironmentCondition struct {
 	// Type of condition in CamelCase.
 	// +required
-	Type string `json:"type" protobuf:"bytes,1,opt,name=type"`
+	Type EnvironmentConditionType `json:"type" protobuf:"bytes,1,opt,name=type"`
 	// Status of the condition, one of True, False, Unknown.
 	// +required
 	Status metav1.ConditionStatus `json:"status" protobuf:"bytes,2,opt,name=status"`
@@ -438,9 +777,39 @@ type EnvironmentCondition struct {
 type EnvironmentConditionReason string
 
 const (
+	ReasonPending EnvironmentConditionReason = "Pending"
 	ReasonRunning EnvironmentConditionReason = "Running"
 	ReasonReady   EnvironmentConditionReason = "Ready"
 	ReasonFailed  EnvironmentConditionReason = "Failed"
+
+	ReasonFrozen     EnvironmentConditionReason = "Frozen"
+	ReasonNotFrozen  EnvironmentConditionReason = "NotFrozen"
+	ReasonOverridden EnvironmentConditionReason = "Overridden"
+
+	ReasonExpiring    EnvironmentConditionReason = "Expiring"
+	ReasonExpired     EnvironmentConditionReason = "Expired"
+	ReasonNotExpiring EnvironmentConditionReason = "NotExpiring"
+	ReasonLoginFailed EnvironmentConditionReason = "LoginFailed"
+)
+
+// EnvironmentConditionType is the type of condition.
+type EnvironmentConditionType string
+
+const (
+	// ConditionReady is True when all steps are Ready.
+	ConditionReady EnvironmentConditionType = "Ready"
+	// ConditionInfraReady is True when all infra steps are Ready.
+	ConditionInfraReady EnvironmentConditionType = "InfraReady"
+	// ConditionClustersReady is True when all cluster steps are Ready.
+	ConditionClustersReady EnvironmentConditionType = "ClustersReady"
+	// ConditionReconciling is True when steps are running or waiting to run.
+	ConditionReconciling EnvironmentConditionType = "Reconciling"
+	// ConditionStalled is True when a step has failed and needs a reset to continue.
+	ConditionStalled EnvironmentConditionType = "Stalled"
+	// ConditionFrozen is True when a Freeze stops all changes to the Environment.
+	ConditionFrozen EnvironmentConditionType = "Frozen"
+	// ConditionCredentialsExpiring is True when the cloud credentials expire soon or have expired.
+	ConditionCredentialsExpiring EnvironmentConditionType = "CredentialsExpiring"
 )
 
 // +genclient
@@ -0,0 +1,99 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// EnvironmentPromotionSpec defines from which Environment to which Environment source revisions are promoted.
+type EnvironmentPromotionSpec struct {
+	// From is the name of the Environment (in the same namespace) that is promoted.
+	From string `json:"from"`
+
+	// To is the name of the Environment (in the same namespace) that receives the source revisions of From.
+	// The Ref of each git SourceSpec in To with an URL that is also used by From is set to the commit From is running.
+	To string `json:"to"`
+
+	// SoakTime is the time From must be Ready before its revisions are promoted.
+	// +optional
+	SoakTime metav1.Duration `json:"soakTime,omitempty"`
+
+	// RequireApproval is true when a promotion has to be approved by setting ApprovedRevision.
+	// +optional
+	RequireApproval bool `json:"requireApproval,omitempty"`
+
+	// ApprovedRevision is the revision that is approved for promotion, see status.pending.
+	// Only used when RequireApproval is true.
+	// +optional
+	ApprovedRevision string `json:"approvedRevision,omitempty"`
+}
+
+// EnvironmentPromotionStatus is the observed state of an EnvironmentPromotion.
+type EnvironmentPromotionStatus struct {
+	// Pending is the revision that waits for soak time or approval before it's promoted.
+	// The revision is a commit SHA or a comma separated list of commits when multiple repos are promoted.
+	// +optional
+	Pending string `json:"pending,omitempty"`
+
+	// Message explains what the promotion is waiting for.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// History contains the most recent promotions, newest first.
+	// +optional
+	History []PromotionRecord `json:"history,omitempty"`
+}
+
+// PromotionRecord is a promotion that has been performed.
+type PromotionRecord struct {
+	// URL is the source repo.
+	URL string `json:"url"`
+	// Commit is the commit the To Environment is pinned to.
+	Commit string `json:"commit"`
+	// Time is when the promotion took place.
+	Time metav1.Time `json:"time"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=envpromo
+// +kubebuilder:printcolumn:name="From",type="string",JSONPath=".spec.from"
+// +kubebuilder:printcolumn:name="To",type="string",JSONPath=".spec.to"
+// +kubebuilder:printcolumn:name="Commit",type="string",JSONPath=".status.history[0].commit"
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".status.message"
+
+// EnvironmentPromotion pins the source revisions of an Environment to the revisions another Environment is running.
+type EnvironmentPromotion struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   EnvironmentPromotionSpec   `json:"spec,omitempty"`
+	Status EnvironmentPromotionStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EnvironmentPromotionList contains a list of EnvironmentPromotions.
+type EnvironmentPromotionList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EnvironmentPromotion `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EnvironmentPromotion{}, &EnvironmentPromotionList{})
+}
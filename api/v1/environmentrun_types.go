@@ -0,0 +1,107 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels of EnvironmentRuns.
+const (
+	// RunEnvironmentLabel is the name of the Environment a run belongs to.
+	RunEnvironmentLabel = "clusterops.mmlt.nl/environment"
+	// RunStepLabel is the name of the step that has run.
+	RunStepLabel = "clusterops.mmlt.nl/step"
+)
+
+// EnvironmentRunSpec is the record of a step execution.
+// It's written once when the step reaches its final state.
+type EnvironmentRunSpec struct {
+	// Environment is the name of the Environment the step belongs to.
+	Environment string `json:"environment"`
+	// Step is the name of the step.
+	Step string `json:"step"`
+
+	/* Inputs */
+
+	// Hash is the opaque value representing the config/parameters applied by the step.
+	// +optional
+	Hash string `json:"hash,omitempty"`
+	// Generation is the metadata.generation of the Environment that is applied by the step.
+	// +optional
+	Generation int64 `json:"generation,omitempty"`
+	// Source is the source revision applied by the step.
+	// +optional
+	Source *StepSource `json:"source,omitempty"`
+	// Values are the values applied by an Addons step (vault references are not resolved).
+	// +optional
+	Values map[string]string `json:"values,omitempty"`
+
+	/* Outputs */
+
+	// State is the outcome of the run, Ready or Error.
+	State StepState `json:"state"`
+	// Message is the last message of the run.
+	// +optional
+	Message string `json:"message,omitempty"`
+	// Added, Changed and Deleted are the number of objects affected by the run.
+	// +optional
+	Added int `json:"added,omitempty"`
+	// +optional
+	Changed int `json:"changed,omitempty"`
+	// +optional
+	Deleted int `json:"deleted,omitempty"`
+	// LogID identifies the logs of the run in the log sink, see 'envop logs'.
+	// +optional
+	LogID string `json:"logID,omitempty"`
+
+	// Start is the time the step started running.
+	Start metav1.Time `json:"start"`
+	// End is the time the step reached its final state.
+	End metav1.Time `json:"end"`
+	// Duration is the time between Start and End.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=envrun
+// +kubebuilder:printcolumn:name="Environment",type="string",JSONPath=".spec.environment"
+// +kubebuilder:printcolumn:name="Step",type="string",JSONPath=".spec.step"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".spec.state"
+// +kubebuilder:printcolumn:name="Duration",type="string",JSONPath=".spec.duration"
+// +kubebuilder:printcolumn:name="Message",type="string",JSONPath=".spec.message"
+
+// EnvironmentRun is an (immutable) audit record of a step execution.
+// EnvironmentRuns are owned by their Environment and pruned according to its spec.runRetention.
+type EnvironmentRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec EnvironmentRunSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// EnvironmentRunList contains a list of EnvironmentRuns.
+type EnvironmentRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []EnvironmentRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&EnvironmentRun{}, &EnvironmentRunList{})
+}
@@ -0,0 +1,67 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// FreezeOverrideAnnotation is the Environment annotation that lists the names of the Freezes that do not apply to
+// that Environment (comma separated).
+const FreezeOverrideAnnotation = "clusterops.mmlt.nl/freeze-override"
+
+// FreezeSpec defines which Environments are frozen.
+type FreezeSpec struct {
+	// Selector selects the Environments that are frozen by their labels.
+	// An omitted selector freezes all Environments.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Reason explains why changes are frozen, for example "incident 1234".
+	Reason string `json:"reason"`
+
+	// Expires is the time the freeze ends.
+	// An omitted expiry freezes until the Freeze is deleted.
+	// +optional
+	Expires *metav1.Time `json:"expires,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:printcolumn:name="Reason",type="string",JSONPath=".spec.reason"
+// +kubebuilder:printcolumn:name="Expires",type="string",JSONPath=".spec.expires"
+
+// Freeze stops all envop activity on the selected Environments.
+// An Environment can override a Freeze by naming it in the clusterops.mmlt.nl/freeze-override annotation.
+type Freeze struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec FreezeSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// FreezeList contains a list of Freezes.
+type FreezeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Freeze `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Freeze{}, &FreezeList{})
+}
@@ -139,7 +139,7 @@ func waitReady(ctx context.Context, client xclientset.Interface, namespace, name
 		UpdateFunc: func(oldObj, newObj interface{}) {
 			if x, ok := newObj.(*v1.Environment); ok {
 				if x.Namespace == namespace && x.Name == name {
-					if c, ok := statusCondition(x, "Ready"); ok {
+					if c, ok := statusCondition(x, v1.ConditionReady); ok {
 						if c.LastTransitionTime.Time.After(t) {
 							ch <- *c
 						}
@@ -161,7 +161,7 @@ func waitReady(ctx context.Context, client xclientset.Interface, namespace, name
 				return nil
 			case v1.ReasonFailed:
 				return fmt.Errorf("an envop step failed") //TODO we can give a better message; check Steps and print message
-			case "", v1.ReasonRunning:
+			case "", v1.ReasonPending, v1.ReasonRunning:
 				// NOP
 			default:
 				return fmt.Errorf("unexpected Reason while waiting for condition Ready: %v", c.Reason)
@@ -4,7 +4,12 @@ import (
 	"context"
 	"fmt"
 	v1 "github.com/mmlt/environment-operator/api/clusterops/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/client-go/kubernetes"
 	"os"
+	"time"
 )
 
 const (
@@ -32,7 +37,7 @@ func exitOnError(err error) {
 }
 
 // StatusCondition returns the named condition from environment.status.conditions.
-func statusCondition(environment *v1.Environment, condition string) (*v1.EnvironmentCondition, bool) {
+func statusCondition(environment *v1.Environment, condition v1.EnvironmentConditionType) (*v1.EnvironmentCondition, bool) {
 	if environment == nil {
 		return nil, false
 	}
@@ -45,3 +50,43 @@ func statusCondition(environment *v1.Environment, condition string) (*v1.Environ
 
 	return nil, false
 }
+
+// Convert copies in to out by JSON encoding/decoding.
+// It's used to convert between api/clusterops/v1 types (used by the generated clientset) and the identical
+// api/v1 types (used by the controller).
+func convert(in, out interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+// RecordEvent creates an Event regarding environment.
+// Typ is Normal or Warning, reason is a CamelCase reason for the event.
+func recordEvent(ctx context.Context, client kubernetes.Interface, environment *v1.Environment, typ, reason, msg string) error {
+	t := metav1.Time{Time: time.Now()}
+	e := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: environment.Name + ".",
+			Namespace:    environment.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      v1.GroupVersion.String(),
+			Kind:            "Environment",
+			Namespace:       environment.Namespace,
+			Name:            environment.Name,
+			UID:             environment.UID,
+			ResourceVersion: environment.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        msg,
+		Type:           typ,
+		Source:         corev1.EventSource{Component: CLIName},
+		FirstTimestamp: t,
+		LastTimestamp:  t,
+		Count:          1,
+	}
+	_, err := client.CoreV1().Events(environment.Namespace).Create(ctx, e, metav1.CreateOptions{})
+	return err
+}
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"github.com/go-logr/logr"
 	clusteropsv1 "github.com/mmlt/environment-operator/api/v1"
 	"github.com/mmlt/environment-operator/controllers"
 	"github.com/mmlt/environment-operator/pkg/client/addon"
@@ -11,18 +13,29 @@ import (
 	"github.com/mmlt/environment-operator/pkg/client/terraform"
 	"github.com/mmlt/environment-operator/pkg/cloud"
 	"github.com/mmlt/environment-operator/pkg/cluster"
+	"github.com/mmlt/environment-operator/pkg/events"
+	"github.com/mmlt/environment-operator/pkg/logstream"
+	"github.com/mmlt/environment-operator/pkg/notify"
 	"github.com/mmlt/environment-operator/pkg/plan"
 	"github.com/mmlt/environment-operator/pkg/source"
 	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/mmlt/environment-operator/pkg/tracing"
 	"github.com/mmlt/environment-operator/pkg/util"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
+	"github.com/nats-io/nats.go"
 	"github.com/spf13/cobra"
+	"io/ioutil"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/klog"
 	"k8s.io/klog/klogr"
 	"os"
+	"path/filepath"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/yaml"
 	"time"
 )
 
@@ -31,6 +44,7 @@ func NewCmdController() *cobra.Command {
 	// flags
 	var (
 		credentialsFile      string
+		azureIdentity        string
 		vault                string
 		workDir              string
 		selector             string
@@ -38,6 +52,16 @@ func NewCmdController() *cobra.Command {
 		allowedSteps         string
 		enableLeaderElection bool
 		metricsAddr          string
+		logsAddr             string
+		sinkFlags            logSinkFlags
+		notificationsFile    string
+		eventsURL            string
+		eventsNATSURL        string
+		eventsNATSSubject    string
+		otlpEndpoint         string
+		otlpInsecure         bool
+		recordFile           string
+		azureClient          string
 	)
 
 	command := cobra.Command{
@@ -59,6 +83,11 @@ func NewCmdController() *cobra.Command {
 				return fmt.Errorf("flag --allowed-steps: %w", err)
 			}
 
+			identity, err := identityFlag(azureIdentity, credentialsFile)
+			if err != nil {
+				return err
+			}
+
 			// Setup manager.
 			scheme := runtime.NewScheme()
 			_ = clientgoscheme.AddToScheme(scheme)
@@ -80,41 +109,155 @@ func NewCmdController() *cobra.Command {
 
 			// Create environment reconciler and all it's dependencies.
 
+			// rn (optional) executes the commands of the clients.
+			var rn exe.Starter
+			if recordFile != "" {
+				rn = &exe.Recorder{Runner: &exe.Executor{Log: l}, Path: recordFile}
+			}
+
+			if azureClient != "cli" && azureClient != "sdk" {
+				return fmt.Errorf("flag --azure-client: unknown value %q", azureClient)
+			}
+			// newAZ returns an azure client, configDir (optional) isolates the az cli login.
+			newAZ := func(configDir string) azure.AZer {
+				if azureClient == "sdk" {
+					return &azure.SDK{Log: l}
+				}
+				return &azure.AZ{
+					Runner:    rn,
+					ConfigDir: configDir,
+					Log:       l,
+				}
+			}
+			// az is shared by cloud and planner because the SDK client keeps the login tokens in memory.
+			az := newAZ("")
+
 			cl := &cloud.Azure{
+				Identity:        identity,
 				CredentialsFile: credentialsFile,
 				Vault:           vault,
-				Client: &azure.AZ{
-					Log: l,
+				Client:          az,
+				Log:             l,
+			}
+			if azureClient == "sdk" {
+				// steps that use the SDK client don't login themselves.
+				_, err = cl.Login()
+				if err != nil {
+					return fmt.Errorf("azure login: %w", err)
+				}
+			}
+			// Environments with a credentials spec get their own cloud and az client (with its own login).
+			accounts := &cloud.Accounts{
+				Default: cloud.Account{Cloud: cl, Azure: cl.AZ()},
+				New: func(nsn types.NamespacedName, spec clusteropsv1.CredentialsSpec, credentialsFn func() ([]byte, error)) cloud.Account {
+					a := newAZ(azConfigDir(workDir, nsn))
+					c := &cloud.Azure{
+						Identity:        identity,
+						CredentialsFile: credentialsFile,
+						Account:         nsn.String(),
+						Vault:           vault,
+						Client:          a,
+						Log:             l.WithValues("account", nsn.String()),
+					}
+					// the vault is only overridden together with the credentials that are used to read it,
+					// the operator wide identity must not read vaults that are chosen by Environment owners.
+					if spec.SecretName != "" {
+						c.Identity = cloud.IdentitySP
+						c.CredentialsFn = credentialsFn
+						if spec.Vault != "" {
+							c.Vault = spec.Vault
+						}
+					}
+					return cloud.Account{Cloud: c, Azure: c.AZ()}
+				},
+				Cleanup: func(nsn types.NamespacedName) {
+					err := os.RemoveAll(azConfigDir(workDir, nsn))
+					if err != nil {
+						l.Error(err, "remove az config dir", "account", nsn.String())
+					}
 				},
-				Log: l,
 			}
+
+			// re-login when the credentials file is updated (secret rotation).
+			err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+				cl.Watch(ctx, time.Minute)
+				return nil
+			}))
+			if err != nil {
+				return fmt.Errorf("unable to add credentials watch: %w", err)
+			}
+
+			// not the manager client; its cache would watch all ConfigMaps in the cluster.
+			sink, err := sinkFlags.restSink(mgr.GetConfig())
+			if err != nil {
+				return err
+			}
+
+			notifications, err := readNotifications(notificationsFile)
+			if err != nil {
+				return fmt.Errorf("flag --notifications-file: %w", err)
+			}
+
+			em, err := newEmitter(eventsURL, eventsNATSURL, eventsNATSSubject, l)
+			if err != nil {
+				return err
+			}
+
+			if otlpEndpoint != "" {
+				shutdown, err := tracing.Setup(context.Background(), otlpEndpoint, otlpInsecure, "envop")
+				if err != nil {
+					return fmt.Errorf("flag --otlp-endpoint: %w", err)
+				}
+				defer func() {
+					if err := shutdown(context.Background()); err != nil {
+						l.Error(err, "tracing shutdown")
+					}
+				}()
+			}
+
 			r := &controllers.EnvironmentReconciler{
-				Client:   mgr.GetClient(),
-				Scheme:   mgr.GetScheme(),
-				Recorder: mgr.GetEventRecorderFor("envop"),
-				LabelSet: labelSet,
-				Environ:  util.KVSliceToMap(os.Environ()),
-				Cloud:    cl,
+				Client:        mgr.GetClient(),
+				Scheme:        mgr.GetScheme(),
+				Recorder:      mgr.GetEventRecorderFor("envop"),
+				LabelSet:      labelSet,
+				Environ:       util.KVSliceToMap(os.Environ()),
+				Cloud:         cl,
+				Accounts:      accounts,
+				LogSink:       sink,
+				Notifier:      &notify.Notifier{},
+				Notifications: notifications,
+				Events:        em,
+			}
+			if logsAddr != "" {
+				r.LogStream = &logstream.Hub{}
+				err = mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+					return r.LogStream.ListenAndServe(ctx, logsAddr)
+				}))
+				if err != nil {
+					return fmt.Errorf("unable to add logs endpoint: %w", err)
+				}
 			}
 			r.Sources = &source.Sources{
 				RootPath: workDir,
+				Runner:   rn,
 				Log:      l,
 			}
 			r.Planner = &plan.Planner{
 				AllowedStepTypes: steps,
 				Log:              l,
 				Cloud:            cl,
-				Terraform:        &terraform.Terraform{},
+				Accounts:         accounts,
+				Terraform:        &terraform.Terraform{Runner: rn},
 				Kubectl: &kubectl.Kubectl{
-					Log: l,
-				},
-				Azure: &azure.AZ{
-					Log: l,
+					Runner: rn,
+					Log:    l,
 				},
-				Addon: &addon.Addon{},
+				Azure: cl.AZ(),
+				Addon: &addon.Addon{Runner: rn},
 				Client: cluster.Client{
 					Client: r.Client,
 					Labels: labelSet,
+					Events: em,
 				},
 			}
 
@@ -123,6 +266,16 @@ func NewCmdController() *cobra.Command {
 				return fmt.Errorf("unable to create controller: %w", err)
 			}
 
+			pr := &controllers.PromotionReconciler{
+				Client:   mgr.GetClient(),
+				Recorder: mgr.GetEventRecorderFor("envop"),
+				LabelSet: labelSet,
+			}
+			err = pr.SetupWithManager(mgr)
+			if err != nil {
+				return fmt.Errorf("unable to create promotion controller: %w", err)
+			}
+
 			err = mgr.Start(ctrl.SetupSignalHandler())
 			if err != nil {
 				return fmt.Errorf("problem running manager: %w", err)
@@ -138,8 +291,11 @@ func NewCmdController() *cobra.Command {
 	command.Flags().AddGoFlagSet(fs)
 
 	command.Flags().StringVar(&credentialsFile, "credentials-file", "",
-		"file with JSON fields client_id, client_secret and tenant of a ServicePrincipal that is allowed to access the MasterKeyVault and AzureRM.")
-	must(command.MarkFlagRequired("credentials-file"))
+		"file with JSON fields client_id, client_secret and tenant of a ServicePrincipal that is allowed to access the MasterKeyVault and AzureRM.\n"+
+			"required when --azure-identity=sp")
+	command.Flags().StringVar(&azureIdentity, "azure-identity", string(cloud.IdentitySP),
+		"the Azure identity to login with; 'sp' uses --credentials-file, 'msi' the managed identity of the host and\n"+
+			"'workload' the AKS workload identity (AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE).")
 	command.Flags().StringVar(&vault, "vault", "",
 		"name of the KeyVault that contains secrets referenced from environment yaml.")
 	must(command.MarkFlagRequired("vault"))
@@ -161,6 +317,87 @@ func NewCmdController() *cobra.Command {
 		"enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	command.Flags().StringVar(&metricsAddr, "metrics-addr", ":8080",
 		"address the metric endpoint binds to.")
+	command.Flags().StringVar(&logsAddr, "logs-addr", "",
+		"address the endpoint that serves live step output binds to, for example 127.0.0.1:8081. Empty disables the endpoint.\n"+
+			"the output is served without authentication and might contain secrets, bind to localhost and use port-forwarding.")
+	sinkFlags.addFlags(command.Flags())
+	command.Flags().StringVar(&eventsURL, "events-url", "",
+		"URL of the HTTP endpoint that receives lifecycle events as CloudEvents, empty doesn't send events.")
+	command.Flags().StringVar(&eventsNATSURL, "events-nats-url", "",
+		"URL of the NATS server that receives lifecycle events as CloudEvents, empty doesn't send events.")
+	command.Flags().StringVar(&eventsNATSSubject, "events-nats-subject", "envop",
+		"prefix of the NATS subjects lifecycle events are published to, the event type is appended.")
+	command.Flags().StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"host:port of the OTLP/HTTP collector that receives traces, empty doesn't trace.")
+	command.Flags().BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"use http instead of https to connect to the OTLP collector.")
+	command.Flags().StringVar(&azureClient, "azure-client", "cli",
+		"the Azure client to use; 'cli' runs the az cli, 'sdk' uses the Azure REST API's.")
+	command.Flags().StringVar(&recordFile, "record-file", "",
+		"path of a file to record the az, git, kubectl, kubectl-tmplt and terraform invocations in (for replay by the dryruncontroller).")
+	command.Flags().StringVar(&notificationsFile, "notifications-file", "",
+		"YAML file with a list of notifications (same fields as Environment spec.notifications) that are sent for all environments.")
 
 	return &command
 }
+
+// ReadNotifications returns the notifications in the YAML file at path.
+// An empty path returns no notifications.
+func readNotifications(path string) ([]clusteropsv1.NotificationSpec, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []clusteropsv1.NotificationSpec
+	err = yaml.UnmarshalStrict(b, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NewEmitter returns an emitter of lifecycle events to the HTTP endpoint at url and/or the NATS server at natsURL.
+// Returns nil when both urls are empty.
+func newEmitter(url, natsURL, natsSubject string, log logr.Logger) (*events.Emitter, error) {
+	var ps events.Publishers
+	if url != "" {
+		ps = append(ps, &events.HTTP{URL: url})
+	}
+	if natsURL != "" {
+		nc, err := nats.Connect(natsURL, nats.Name(ControllerName))
+		if err != nil {
+			return nil, fmt.Errorf("flag --events-nats-url: %w", err)
+		}
+		ps = append(ps, &events.NATS{Conn: nc, Subject: natsSubject})
+	}
+	if len(ps) == 0 {
+		return nil, nil
+	}
+
+	return &events.Emitter{Publisher: ps, Log: log.WithName("events")}, nil
+}
+
+// IdentityFlag returns the cloud.Identity for the --azure-identity flag value.
+func identityFlag(value, credentialsFile string) (cloud.Identity, error) {
+	for _, id := range cloud.Identities {
+		if string(id) != value {
+			continue
+		}
+		if id == cloud.IdentitySP && credentialsFile == "" {
+			return "", fmt.Errorf("flag --credentials-file: required when --azure-identity=sp")
+		}
+		return id, nil
+	}
+	return "", fmt.Errorf("flag --azure-identity: unknown value %q, valid values: %v", value, cloud.Identities)
+}
+
+// AzConfigDir returns the az cli configuration directory of the environment nsn.
+func azConfigDir(workDir string, nsn types.NamespacedName) string {
+	return filepath.Join(workDir, ".azure", nsn.Namespace, nsn.Name)
+}
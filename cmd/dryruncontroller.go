@@ -12,8 +12,10 @@ import (
 	"github.com/mmlt/environment-operator/pkg/cloud"
 	"github.com/mmlt/environment-operator/pkg/cluster"
 	"github.com/mmlt/environment-operator/pkg/plan"
+	"github.com/mmlt/environment-operator/pkg/scenario"
 	"github.com/mmlt/environment-operator/pkg/source"
 	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"github.com/spf13/cobra"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -35,15 +37,25 @@ func NewDryrunControllerCmd() *cobra.Command {
 		syncPeriodInMin      int
 		enableLeaderElection bool
 		metricsAddr          string
+		scenarioFile         string
+		replayFile           string
+		replaySpeed          float64
 	)
 
 	command := cobra.Command{
 		Use:   "dryruncontroller",
 		Short: "Run an envop controller in dryrun mode",
 		Long: `In dryrun mode the controller doesn't write to external systems.
-Because the dryruncontroller works with fake data the processed environment(yaml) must:
+Without --scenario-file or --replay-file the dryruncontroller works with built-in fake data and
+the processed environment(yaml) must:
 - have a budget of add=1, delete=1, update=2 or more
 - have a single cluster called "mycluster"
+
+With --scenario-file the terraform plan, apply and output results, the az node pools, the kubectl pod states
+and the kubectl-tmplt results per cluster are read from a scenario file. A scenario can inject failures.
+
+Alternatively the az, kubectl, kubectl-tmplt and terraform invocations recorded by 'envop controller --record-file'
+are replayed with --replay-file.
 `,
 		Example: `
 `,
@@ -97,15 +109,49 @@ Because the dryruncontroller works with fake data the processed environment(yaml
 				Log:      l,
 			}
 
-			az := &azure.AZFake{}
-			az.SetupFakeResults()
-			tf := &terraform.TerraformFake{
-				Log: l,
+			var (
+				az azure.AZer
+				tf terraform.Terraformer
+				kc kubectl.Kubectrler
+				ao addon.Addonr
+			)
+			if replayFile != "" && scenarioFile != "" {
+				return fmt.Errorf("flags --replay-file and --scenario-file are mutually exclusive")
+			}
+			switch {
+			case scenarioFile != "":
+				sc, err := scenario.Load(scenarioFile)
+				if err != nil {
+					return fmt.Errorf("flag --scenario-file: %w", err)
+				}
+				az = &scenario.AzureClient{Scenario: sc}
+				tf = &scenario.TerraformClient{Scenario: sc}
+				kc = &scenario.KubectlClient{Scenario: sc}
+				ao = &scenario.AddonClient{Scenario: sc}
+			case replayFile != "":
+				t, err := exe.ReadTranscript(replayFile)
+				if err != nil {
+					return fmt.Errorf("flag --replay-file: %w", err)
+				}
+				rp := &exe.Replayer{Transcript: t, Speed: replaySpeed}
+				az = &azure.AZ{Runner: rp, Log: l}
+				tf = &terraform.Terraform{Runner: rp}
+				kc = &kubectl.Kubectl{Runner: rp, Log: l}
+				ao = &addon.Addon{Runner: rp}
+			default:
+				azf := &azure.AZFake{}
+				azf.SetupFakeResults()
+				az = azf
+				tff := &terraform.TerraformFake{
+					Log: l,
+				}
+				tff.SetupFakeResultsForCreate(nil)
+				tf = tff
+				kc = &kubectl.KubectlFake{}
+				aof := &addon.AddonFake{}
+				aof.SetupFakeResult()
+				ao = aof
 			}
-			tf.SetupFakeResultsForCreate(nil)
-			kc := &kubectl.KubectlFake{}
-			ao := &addon.AddonFake{}
-			ao.SetupFakeResult()
 			clc := cluster.Client{
 				Client: r.Client,
 				Labels: labelSet,
@@ -154,6 +200,12 @@ Because the dryruncontroller works with fake data the processed environment(yaml
 		"enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	command.Flags().StringVar(&metricsAddr, "metrics-addr", ":8080",
 		"address the metric endpoint binds to.")
+	command.Flags().StringVar(&scenarioFile, "scenario-file", "",
+		"path of a scenario file with the fake results to use instead of the built-in fake data.")
+	command.Flags().StringVar(&replayFile, "replay-file", "",
+		"path of a file with recorded invocations to replay instead of using the built-in fake data.")
+	command.Flags().Float64Var(&replaySpeed, "replay-speed", 0,
+		"replay invocations at their recorded duration divided by speed, 0 replays without delay.")
 
 	return &command
 }
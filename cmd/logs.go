@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/clusterops/v1"
+	xclientset "github.com/mmlt/environment-operator/pkg/generated/clientset/versioned"
+	"github.com/mmlt/environment-operator/pkg/logsink"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"io/ioutil"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+// NewCmdLogs returns a command to show the logs of a step run.
+func NewCmdLogs() *cobra.Command {
+	// flags
+	var (
+		runID         string
+		stepName      string
+		follow        bool
+		controllerURL string
+		sinkFlags     logSinkFlags
+	)
+	kubeConfigFlags := genericclioptions.NewConfigFlags(true)
+
+	cmd := cobra.Command{
+		Use:   "logs environment-name [step-name | --step step-name] [--run id] [-f]",
+		Short: "Show the logs of a step",
+		Long: `Show the terraform output of the latest run of a step.
+The logs are read from the log sink the controller writes to, specify the same --log-sink flags as the controller.
+With --run the logs of an earlier run are shown, the run IDs are in the Environment status.history.
+With -f the output of the running (or last) run of a step is read from the controller and shown as it arrives,
+the controller must be started with --logs-addr, use for example 'kubectl port-forward <envop-pod> 8081' to make it
+reachable at --controller-url.`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(c *cobra.Command, args []string) {
+			if len(args) > 1 {
+				stepName = args[1]
+			}
+			if stepName == "" {
+				exitOnError(fmt.Errorf("step-name is required"))
+			}
+
+			namespace := "default"
+			if *kubeConfigFlags.Namespace != "" {
+				namespace = *kubeConfigFlags.Namespace
+			}
+
+			if follow {
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				err := followLogs(ctx, os.Stdout, controllerURL, namespace, args[0], stepName)
+				exitOnError(err)
+				return
+			}
+
+			cfg, err := kubeConfigFlags.ToRESTConfig()
+			exitOnError(err)
+
+			xClient, err := xclientset.NewForConfig(cfg)
+			exitOnError(err)
+
+			ctx := context.Background()
+
+			environment, err := get(ctx, xClient, namespace, args[0])
+			exitOnError(err)
+
+			if runID == "" {
+				runID, err = lastLogID(environment, stepName)
+				exitOnError(err)
+			}
+
+			sink, err := sinkFlags.restSink(cfg)
+			exitOnError(err)
+			if sink == nil {
+				exitOnError(fmt.Errorf("flag --log-sink is required"))
+			}
+
+			logs, err := sink.Get(ctx, logsink.Run{Namespace: namespace, Environment: environment.Name, Step: stepName, ID: runID})
+			exitOnError(err)
+			if len(logs) == 0 {
+				exitOnError(fmt.Errorf("no logs found for run %s of step %s", runID, stepName))
+			}
+
+			printLogs(os.Stdout, logs)
+		},
+	}
+
+	// Add klog flags to cobra command.
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.Flags().AddGoFlagSet(fs)
+
+	cmd.Flags().StringVar(&stepName, "step", "", "Name of the step to show the logs of, for example Infra.")
+	cmd.Flags().StringVar(&runID, "run", "", "ID of the run to show the logs of, default is the latest run.")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Show the output of the running step as it arrives.")
+	cmd.Flags().StringVar(&controllerURL, "controller-url", "http://localhost:8081",
+		"URL of the controller endpoint (--logs-addr) that serves live step output (-f).")
+	sinkFlags.addFlags(cmd.Flags())
+
+	kubeConfigFlags.AddFlags(cmd.Flags())
+
+	return &cmd
+}
+
+// FollowLogs writes the output of step of environment namespace/name to w as it arrives until the step ends or
+// ctx is done.
+// ControllerURL is the URL of the controller that runs the step.
+func followLogs(ctx context.Context, w io.Writer, controllerURL, namespace, name, step string) error {
+	u := fmt.Sprintf("%s/logs/%s/%s/%s?follow=true", strings.TrimRight(controllerURL, "/"),
+		url.PathEscape(namespace), url.PathEscape(name), url.PathEscape(step))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// LastLogID returns the log ID of the latest run of step in environment.
+func lastLogID(environment *v1.Environment, step string) (string, error) {
+	runs := environment.Status.History[step]
+	for i := len(runs) - 1; i >= 0; i-- {
+		if runs[i].LogID != "" {
+			return runs[i].LogID, nil
+		}
+	}
+	return "", fmt.Errorf("no logs recorded for step %s of environment %s", step, environment.Name)
+}
+
+// PrintLogs writes logs to w in the order they are written by a step.
+func printLogs(w io.Writer, logs map[string][]byte) {
+	order := map[string]int{"init.txt": 1, "plan.txt": 2, "apply.txt": 3, "destroy.txt": 3}
+	var names []string
+	for n := range logs {
+		names = append(names, n)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, oj := order[names[i]], order[names[j]]
+		if oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+
+	for _, n := range names {
+		fmt.Fprintf(w, "==> %s <==\n%s\n", n, logs[n])
+	}
+}
+
+// LogSinkFlags are the flags that select the log sink that stores the output of steps.
+type logSinkFlags struct {
+	kind       string
+	dir        string
+	s3Endpoint string
+	s3Bucket   string
+	s3Prefix   string
+	s3Region   string
+	s3Insecure bool
+}
+
+func (f *logSinkFlags) addFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&f.kind, "log-sink", "",
+		"where to store the output of steps, one of: dir, configmap, s3. Empty doesn't store output.")
+	fs.StringVar(&f.dir, "log-dir", "/var/log/envop",
+		"directory to store step output in (--log-sink=dir), typically a persistent volume.")
+	fs.StringVar(&f.s3Endpoint, "log-s3-endpoint", "s3.amazonaws.com",
+		"host[:port] of the S3 compatible service to store step output in (--log-sink=s3).\n"+
+			"credentials are read from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY.")
+	fs.StringVar(&f.s3Bucket, "log-s3-bucket", "",
+		"name of the bucket to store step output in (--log-sink=s3).")
+	fs.StringVar(&f.s3Prefix, "log-s3-prefix", "",
+		"prefix of the object names (--log-sink=s3).")
+	fs.StringVar(&f.s3Region, "log-s3-region", "",
+		"region of the bucket (--log-sink=s3).")
+	fs.BoolVar(&f.s3Insecure, "log-s3-insecure", false,
+		"use http instead of https to access the S3 service (--log-sink=s3).")
+}
+
+// Sink returns the log sink selected by the flags or nil if no log sink is selected.
+// Client is used by the configmap log sink.
+func (f *logSinkFlags) sink(c client.Client) (logsink.Sink, error) {
+	switch f.kind {
+	case "":
+		return nil, nil
+	case "dir":
+		return &logsink.Dir{Path: f.dir}, nil
+	case "configmap":
+		return &logsink.ConfigMaps{Client: c}, nil
+	case "s3":
+		if f.s3Bucket == "" {
+			return nil, fmt.Errorf("flag --log-s3-bucket is required")
+		}
+		return &logsink.S3{
+			Endpoint: f.s3Endpoint,
+			Bucket:   f.s3Bucket,
+			Prefix:   f.s3Prefix,
+			Region:   f.s3Region,
+			Insecure: f.s3Insecure,
+		}, nil
+	default:
+		return nil, fmt.Errorf("flag --log-sink: unknown value: %s", f.kind)
+	}
+}
+
+// RestSink returns the log sink selected by the flags, the configmap log sink uses cfg to access the cluster.
+// The client isn't cached so ConfigMaps are only read when logs are requested.
+func (f *logSinkFlags) restSink(cfg *rest.Config) (logsink.Sink, error) {
+	var c client.Client
+	if f.kind == "configmap" {
+		var err error
+		c, err = client.New(cfg, client.Options{})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return f.sink(c)
+}
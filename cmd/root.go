@@ -15,6 +15,15 @@ For normal operations run envop as Kubernetes controller with:
 and then apply environment resources to the controller:
     envop apply
 
+To show the step status of environments:
+    envop status
+
+To show the output of the latest run of a step:
+    envop logs
+
+To inspect or repair the terraform state of an environment (from within the envop pod):
+    envop state
+
 For testing purposes the controller can be run without making modifications:
     envop dryruncontroller
 `,
@@ -28,6 +37,9 @@ For testing purposes the controller can be run without making modifications:
 	command.AddCommand(NewDryrunControllerCmd())
 	command.AddCommand(NewCmdApply())
 	command.AddCommand(NewCmdReset())
+	command.AddCommand(NewCmdStatus())
+	command.AddCommand(NewCmdLogs())
+	command.AddCommand(NewCmdState())
 
 	return command
 }
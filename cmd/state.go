@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	apiv1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/controllers"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"github.com/mmlt/environment-operator/pkg/cloud"
+	xclientset "github.com/mmlt/environment-operator/pkg/generated/clientset/versioned"
+	"github.com/mmlt/environment-operator/pkg/source"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/mmlt/environment-operator/pkg/util"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/klog"
+	"k8s.io/klog/klogr"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NewCmdState returns a command to inspect and repair the terraform state of an environment.
+func NewCmdState() *cobra.Command {
+	// flags
+	var (
+		credentialsFile string
+		azureIdentity   string
+		vault           string
+		workDir         string
+		yes             bool
+	)
+	kubeConfigFlags := genericclioptions.NewConfigFlags(true)
+
+	// run runs the terraform state or import subcommand sub with args in the infra workspace of the environment named name.
+	// Commands that modify the state ask for confirmation and are recorded as an Event.
+	run := func(name, sub string, args []string) {
+		cmdArgs, mutate, fn := stateCommand(sub, args)
+		ctx := context.Background()
+
+		cfg, err := kubeConfigFlags.ToRESTConfig()
+		exitOnError(err)
+
+		xClient, err := xclientset.NewForConfig(cfg)
+		exitOnError(err)
+		kubeClient, err := kubernetes.NewForConfig(cfg)
+		exitOnError(err)
+
+		nsn := types.NamespacedName{Namespace: "default", Name: name}
+		if *kubeConfigFlags.Namespace != "" {
+			nsn.Namespace = *kubeConfigFlags.Namespace
+		}
+
+		environment, err := get(ctx, xClient, nsn.Namespace, nsn.Name)
+		exitOnError(err)
+
+		// Infra workspace as maintained by the controller.
+		sources := &source.Sources{RootPath: workDir}
+		dir := filepath.Join(sources.WorkspacePath(nsn, ""), environment.Spec.Infra.Main)
+		_, err = os.Stat(dir)
+		if err != nil {
+			exitOnError(fmt.Errorf("infra workspace (is this the pod envop is running in?): %w", err))
+		}
+
+		// Same environment as the Infra step presents to terraform.
+		var infra apiv1.InfraSpec
+		err = convert(environment.Spec.Infra, &infra)
+		exitOnError(err)
+		l := klogr.New()
+		cl := &cloud.Azure{
+			Vault: vault,
+			Client: &azure.AZ{
+				Log: l,
+			},
+			Log: l,
+		}
+		if creds := infra.Credentials; creds.SecretName != "" {
+			// environment specific credentials, login in the same az config dir as the controller.
+			cl.CredentialsFn = func() ([]byte, error) {
+				return secretData(ctx, kubeClient, nsn.Namespace, creds.SecretName, apiv1.CredentialsSecretKey)
+			}
+			cl.Client = &azure.AZ{
+				ConfigDir: azConfigDir(workDir, nsn),
+				Log:       l,
+			}
+		} else {
+			cl.Identity, err = identityFlag(azureIdentity, credentialsFile)
+			exitOnError(err)
+			cl.CredentialsFile = credentialsFile
+		}
+		if infra.Credentials.Vault != "" {
+			cl.Vault = infra.Credentials.Vault
+		}
+		ispec, err := controllers.VaultInfraValues(infra, cl)
+		exitOnError(err)
+		sp, err := cl.Login()
+		exitOnError(err)
+		env := util.KVSliceMergeMap(os.Environ(), step.TerraformEnviron(sp, ispec.State.Access))
+
+		cmdline := "terraform " + strings.Join(cmdArgs, " ")
+		if mutate {
+			fmt.Printf("About to run in %s:\n  %s\n", dir, cmdline)
+			if !yes && !confirm(os.Stdin) {
+				exitOnError(fmt.Errorf("not confirmed"))
+			}
+		}
+
+		o, err := fn(ctx, &terraform.Terraform{}, env, dir)
+		fmt.Print(o)
+
+		if mutate {
+			msg := cmdline
+			typ := "Normal"
+			if err != nil {
+				msg = msg + ": " + err.Error()
+				typ = "Warning"
+			}
+			e := recordEvent(ctx, kubeClient, environment, typ, "TerraformState", msg)
+			if e != nil {
+				fmt.Fprintf(os.Stderr, "record event: %v\n", e)
+			}
+		}
+
+		exitOnError(err)
+	}
+
+	cmd := cobra.Command{
+		Use:   "state",
+		Short: "Inspect or repair the terraform state of an environment",
+		Long: `Inspect or repair the terraform state of an environment.
+The commands run terraform in the infra workspace of the environment so they must be run in the pod envop is running in.
+Commands that modify the state (mv, rm, import) ask for confirmation and are recorded as an Event on the environment.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list environment-name [address...]",
+		Short: "List resources in the terraform state",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			run(args[0], "list", args[1:])
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "show environment-name address",
+		Short: "Show a resource in the terraform state",
+		Args:  cobra.ExactArgs(2),
+		Run: func(c *cobra.Command, args []string) {
+			run(args[0], "show", args[1:])
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "mv environment-name source-address destination-address",
+		Short: "Move an item in the terraform state",
+		Args:  cobra.ExactArgs(3),
+		Run: func(c *cobra.Command, args []string) {
+			run(args[0], "mv", args[1:])
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rm environment-name address...",
+		Short: "Remove items from the terraform state",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(c *cobra.Command, args []string) {
+			run(args[0], "rm", args[1:])
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "import environment-name address id",
+		Short: "Import existing infrastructure into the terraform state",
+		Args:  cobra.ExactArgs(3),
+		Run: func(c *cobra.Command, args []string) {
+			run(args[0], "import", args[1:])
+		},
+	})
+
+	// Add klog flags to cobra command.
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.PersistentFlags().AddGoFlagSet(fs)
+
+	cmd.PersistentFlags().StringVar(&credentialsFile, "credentials-file", "",
+		"file with JSON fields client_id, client_secret and tenant of a ServicePrincipal that is allowed to access the MasterKeyVault and AzureRM.")
+	cmd.PersistentFlags().StringVar(&azureIdentity, "azure-identity", string(cloud.IdentitySP),
+		"the Azure identity to login with; 'sp', 'msi' or 'workload' (see controller --azure-identity).")
+	cmd.PersistentFlags().StringVar(&vault, "vault", "",
+		"name of the KeyVault that contains secrets referenced from environment yaml.")
+	cmd.PersistentFlags().StringVar(&workDir, "workdir", "/var/tmp/envop",
+		"working directory of the envop controller")
+	cmd.PersistentFlags().BoolVar(&yes, "yes", false,
+		"do not ask for confirmation before modifying the state")
+
+	kubeConfigFlags.AddFlags(cmd.PersistentFlags())
+
+	return &cmd
+}
+
+// StateFunc runs a terraform command with env in dir and returns its output.
+type stateFunc func(ctx context.Context, tf *terraform.Terraform, env []string, dir string) (string, error)
+
+// StateCommand returns the terraform arguments (for display) of the state subcommand sub with args, true when the
+// command modifies the state and a func that runs the command.
+// Sub is list, show, mv, rm or import.
+func stateCommand(sub string, args []string) ([]string, bool, stateFunc) {
+	switch sub {
+	case "import":
+		return append([]string{"import"}, args...), true,
+			func(ctx context.Context, tf *terraform.Terraform, env []string, dir string) (string, error) {
+				return tf.Import(ctx, env, dir, args[0], args[1])
+			}
+	case "show":
+		return []string{"state", "show", args[0]}, false,
+			func(ctx context.Context, tf *terraform.Terraform, env []string, dir string) (string, error) {
+				return tf.State(ctx, env, dir, "show", "-no-color", args[0])
+			}
+	default:
+		return append([]string{"state", sub}, args...), sub == "mv" || sub == "rm",
+			func(ctx context.Context, tf *terraform.Terraform, env []string, dir string) (string, error) {
+				return tf.State(ctx, env, dir, sub, args...)
+			}
+	}
+}
+
+// Confirm asks the user to type 'yes' and returns true when that is what was typed.
+func confirm(in *os.File) bool {
+	fmt.Print("Only 'yes' will be accepted to confirm: ")
+	s, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(s) == "yes"
+}
+
+// SecretData returns the value of key in Secret namespace/name.
+func secretData(ctx context.Context, client kubernetes.Interface, namespace, name, key string) ([]byte, error) {
+	s, err := client.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("secret %s: %w", name, err)
+	}
+	b, ok := s.Data[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %s: no key %s", name, key)
+	}
+	return b, nil
+}
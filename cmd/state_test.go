@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_stateCommand(t *testing.T) {
+	tests := []struct {
+		it         string
+		sub        string
+		args       []string
+		wantArgs   []string
+		wantMutate bool
+		// wantTF are the args terraform is invoked with.
+		wantTF []string
+	}{
+		{
+			it:       "should list all resources",
+			sub:      "list",
+			wantArgs: []string{"state", "list"},
+			wantTF:   []string{"state", "list"},
+		},
+		{
+			it:       "should list resources by address",
+			sub:      "list",
+			args:     []string{"module.aks", "azurerm_resource_group.rg"},
+			wantArgs: []string{"state", "list", "module.aks", "azurerm_resource_group.rg"},
+			wantTF:   []string{"state", "list", "module.aks", "azurerm_resource_group.rg"},
+		},
+		{
+			it:       "should show a resource without color",
+			sub:      "show",
+			args:     []string{"azurerm_resource_group.rg"},
+			wantArgs: []string{"state", "show", "azurerm_resource_group.rg"},
+			wantTF:   []string{"state", "show", "-no-color", "azurerm_resource_group.rg"},
+		},
+		{
+			it:         "should move a resource",
+			sub:        "mv",
+			args:       []string{"module.a.x", "module.b.x"},
+			wantArgs:   []string{"state", "mv", "module.a.x", "module.b.x"},
+			wantMutate: true,
+			wantTF:     []string{"state", "mv", "module.a.x", "module.b.x"},
+		},
+		{
+			it:         "should remove resources",
+			sub:        "rm",
+			args:       []string{"module.a.x", "module.a.y"},
+			wantArgs:   []string{"state", "rm", "module.a.x", "module.a.y"},
+			wantMutate: true,
+			wantTF:     []string{"state", "rm", "module.a.x", "module.a.y"},
+		},
+		{
+			it:         "should import a resource non-interactively",
+			sub:        "import",
+			args:       []string{"azurerm_resource_group.rg", "/subscriptions/s/resourceGroups/rg"},
+			wantArgs:   []string{"import", "azurerm_resource_group.rg", "/subscriptions/s/resourceGroups/rg"},
+			wantMutate: true,
+			wantTF:     []string{"import", "-input=false", "-no-color", "azurerm_resource_group.rg", "/subscriptions/s/resourceGroups/rg"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			args, mutate, fn := stateCommand(tst.sub, tst.args)
+			assert.Equal(t, tst.wantArgs, args)
+			assert.Equal(t, tst.wantMutate, mutate)
+
+			rp := &exe.Replayer{Transcript: &exe.Transcript{Invocations: []exe.Invocation{
+				{Name: "terraform", Args: tst.wantTF, Stdout: "out"},
+			}}}
+			ctx := logr.NewContext(context.Background(), logr.Discard())
+			o, err := fn(ctx, &terraform.Terraform{Runner: rp}, nil, "/tmp/tf")
+			assert.NoError(t, err)
+			assert.Equal(t, "out", o)
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/ghodss/yaml"
+	v1 "github.com/mmlt/environment-operator/api/clusterops/v1"
+	xclientset "github.com/mmlt/environment-operator/pkg/generated/clientset/versioned"
+	xinformers "github.com/mmlt/environment-operator/pkg/generated/informers/externalversions"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/spf13/cobra"
+	"io"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// NewCmdStatus returns a command to show the step status of environments.
+func NewCmdStatus() *cobra.Command {
+	// flags
+	var (
+		output        string
+		selector      string
+		allNamespaces bool
+		watch         bool
+	)
+	kubeConfigFlags := genericclioptions.NewConfigFlags(true)
+
+	cmd := cobra.Command{
+		Use:     "status [environment-name] [-l selector] [-o wide|json|yaml] [--watch]",
+		Aliases: []string{"get"},
+		Short:   "Show the step status of environments",
+		Long: `Show the steps of environments with their state, age, message and hash.
+Without environment-name all environments in the namespace are shown.
+With --watch the steps of environments are shown again each time an environment changes.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(c *cobra.Command, args []string) {
+			switch output {
+			case "", "wide", "json", "yaml":
+			default:
+				exitOnError(fmt.Errorf("unknown output format: %s", output))
+			}
+
+			cfg, err := kubeConfigFlags.ToRESTConfig()
+			exitOnError(err)
+
+			xClient, err := xclientset.NewForConfig(cfg)
+			exitOnError(err)
+
+			namespace := "default"
+			if *kubeConfigFlags.Namespace != "" {
+				namespace = *kubeConfigFlags.Namespace
+			}
+			if allNamespaces {
+				namespace = metav1.NamespaceAll
+			}
+
+			var name string
+			if len(args) > 0 {
+				name = args[0]
+			}
+
+			if watch {
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				err = watchStatus(ctx, xClient, os.Stdout, namespace, name, selector, output)
+				exitOnError(err)
+				return
+			}
+
+			environments, err := list(context.Background(), xClient, namespace, name, selector)
+			exitOnError(err)
+
+			err = printStatus(os.Stdout, environments, output, true)
+			exitOnError(err)
+		},
+	}
+
+	// Add klog flags to cobra command.
+	fs := flag.NewFlagSet("", flag.PanicOnError)
+	klog.InitFlags(fs)
+	cmd.Flags().AddGoFlagSet(fs)
+
+	cmd.Flags().StringVarP(&output, "output", "o", "", "Output format, one of: wide, json, yaml.")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter environments on, for example -l clusterops.mmlt.nl/operator=prod")
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Show environments in all namespaces.")
+	cmd.Flags().BoolVarP(&watch, "watch", "w", false, "Show environments again when they change.")
+
+	kubeConfigFlags.AddFlags(cmd.Flags())
+
+	return &cmd
+}
+
+// List returns the environments in namespace that match selector.
+// When name is not empty only the environment with that name is returned.
+func list(ctx context.Context, client xclientset.Interface, namespace, name, selector string) ([]v1.Environment, error) {
+	if name != "" {
+		environment, err := get(ctx, client, namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		return []v1.Environment{*environment}, nil
+	}
+
+	l, err := client.
+		ClusteropsV1().
+		Environments(namespace).
+		List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(l.Items, func(i, j int) bool {
+		if l.Items[i].Namespace != l.Items[j].Namespace {
+			return l.Items[i].Namespace < l.Items[j].Namespace
+		}
+		return l.Items[i].Name < l.Items[j].Name
+	})
+
+	return l.Items, nil
+}
+
+// WatchStatus prints the status of an environment each time it's added or updated until ctx is done.
+func watchStatus(ctx context.Context, client xclientset.Interface, w io.Writer, namespace, name, selector, output string) error {
+	ch := make(chan *v1.Environment)
+
+	xInformerFactory := xinformers.NewSharedInformerFactoryWithOptions(client, 10*time.Minute,
+		xinformers.WithNamespace(namespace),
+		xinformers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.LabelSelector = selector
+		}))
+	environmentInformer := xInformerFactory.Clusterops().V1().Environments().Informer()
+	fn := sendEnvironment(ctx, ch, name)
+	environmentInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    fn,
+		UpdateFunc: func(_, obj interface{}) { fn(obj) },
+	})
+
+	xInformerFactory.Start(ctx.Done())
+
+	header := true
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case x := <-ch:
+			err := printStatus(w, []v1.Environment{*x}, output, header)
+			if err != nil {
+				return err
+			}
+			header = false
+		}
+	}
+}
+
+// SendEnvironment returns an informer event handler that sends Environment objects named name (or all when name is
+// empty) to ch.
+// The handler returns without sending when ctx is done so it doesn't block the informer after the receiver has returned.
+func sendEnvironment(ctx context.Context, ch chan<- *v1.Environment, name string) func(obj interface{}) {
+	return func(obj interface{}) {
+		x, ok := obj.(*v1.Environment)
+		if !ok || (name != "" && x.Name != name) {
+			return
+		}
+		select {
+		case ch <- x:
+		case <-ctx.Done():
+		}
+	}
+}
+
+// PrintStatus writes environments to w in output format "" (table), "wide" (table), "json" or "yaml".
+// Header is true to include the table header.
+func printStatus(w io.Writer, environments []v1.Environment, output string, header bool) error {
+	switch output {
+	case "json":
+		for _, e := range environments {
+			b, err := json.MarshalIndent(e, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, string(b))
+		}
+		return nil
+	case "yaml":
+		for _, e := range environments {
+			b, err := yaml.Marshal(e)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "---\n%s", b)
+		}
+		return nil
+	}
+
+	wide := output == "wide"
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if header {
+		h := "NAMESPACE\tENVIRONMENT\tCLUSTER\tSTEP\tSTATE\tAGE\tMESSAGE"
+		if wide {
+			h += "\tHASH\tCOMMIT\tGENERATION"
+		}
+		fmt.Fprintln(tw, h)
+	}
+
+	now := time.Now()
+	for _, e := range environments {
+		for _, n := range sortedStepNames(e.Status.Steps) {
+			st := e.Status.Steps[n]
+			typ, cluster := splitStepName(n)
+			msg := st.Message
+			if !wide {
+				msg = truncate(msg, 60)
+			}
+			row := []string{e.Namespace, e.Name, cluster, typ, string(st.State),
+				age(now, st.LastTransitionTime), msg}
+			if wide {
+				var commit string
+				if st.Source != nil {
+					commit = st.Source.Commit
+				}
+				row = append(row, st.Hash, commit, fmt.Sprint(st.Generation))
+			}
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+	}
+
+	return tw.Flush()
+}
+
+// SortedStepNames returns the names of steps in the order of step.Types followed by cluster name.
+func sortedStepNames(steps map[string]v1.StepStatus) []string {
+	order := make(map[string]int, len(step.Types))
+	for i, t := range step.Types {
+		order[string(t)] = i
+	}
+
+	var r []string
+	for n := range steps {
+		r = append(r, n)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		ti, ci := splitStepName(r[i])
+		tj, cj := splitStepName(r[j])
+		if ti != tj {
+			return order[ti] < order[tj]
+		}
+		return ci < cj
+	})
+
+	return r
+}
+
+// SplitStepName splits a step short name into the step type and the cluster name.
+// For RolloutGate steps the cluster name is the batch number.
+func splitStepName(name string) (string, string) {
+	var typ string
+	for _, t := range step.Types {
+		if strings.HasPrefix(name, string(t)) && len(t) > len(typ) {
+			typ = string(t)
+		}
+	}
+	return typ, name[len(typ):]
+}
+
+// Age returns the human readable time between t and now.
+func age(now time.Time, t metav1.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(now.Sub(t.Time))
+}
+
+// Truncate shortens s to at most max runes.
+func truncate(s string, max int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	r := []rune(s)
+	if len(r) <= max {
+		return s
+	}
+	return string(r[:max-3]) + "..."
+}
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	v1 "github.com/mmlt/environment-operator/api/clusterops/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_sortedStepNames(t *testing.T) {
+	steps := map[string]v1.StepStatus{
+		"RolloutGate1":      {},
+		"Addonsxyz":         {},
+		"AKSPoolxyz":        {},
+		"AKSAddonPreflight": {},
+		"AKSPoolabc":        {},
+		"Infra":             {},
+	}
+
+	got := sortedStepNames(steps)
+
+	assert.Equal(t, []string{"Infra", "AKSPoolabc", "AKSPoolxyz", "AKSAddonPreflight", "Addonsxyz", "RolloutGate1"}, got)
+}
+
+func Test_splitStepName(t *testing.T) {
+	tests := []struct {
+		it          string
+		in          string
+		wantType    string
+		wantCluster string
+	}{
+		{
+			it:       "should split a step without cluster",
+			in:       "Infra",
+			wantType: "Infra",
+		},
+		{
+			it:          "should split a cluster step",
+			in:          "AKSPoolxyz",
+			wantType:    "AKSPool",
+			wantCluster: "xyz",
+		},
+		{
+			it:          "should use the longest matching type",
+			in:          "AKSAddonPreflightxyz",
+			wantType:    "AKSAddonPreflight",
+			wantCluster: "xyz",
+		},
+		{
+			it:          "should return the batch of a rollout gate",
+			in:          "RolloutGate2",
+			wantType:    "RolloutGate",
+			wantCluster: "2",
+		},
+		{
+			it:          "should return an empty type for an unknown step",
+			in:          "Unknown",
+			wantCluster: "Unknown",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			typ, cluster := splitStepName(tst.in)
+			assert.Equal(t, tst.wantType, typ)
+			assert.Equal(t, tst.wantCluster, cluster)
+		})
+	}
+}
+
+func Test_age(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		it   string
+		in   metav1.Time
+		want string
+	}{
+		{
+			it:   "should return unknown for a zero time",
+			want: "<unknown>",
+		},
+		{
+			it:   "should return seconds",
+			in:   metav1.NewTime(now.Add(-30 * time.Second)),
+			want: "30s",
+		},
+		{
+			it:   "should return hours",
+			in:   metav1.NewTime(now.Add(-3 * time.Hour)),
+			want: "3h",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			assert.Equal(t, tst.want, age(now, tst.in))
+		})
+	}
+}
+
+func Test_truncate(t *testing.T) {
+	tests := []struct {
+		it   string
+		in   string
+		max  int
+		want string
+	}{
+		{
+			it:   "should keep a short string",
+			in:   "ok",
+			max:  10,
+			want: "ok",
+		},
+		{
+			it:   "should replace newlines",
+			in:   "line1\nline2",
+			max:  20,
+			want: "line1 line2",
+		},
+		{
+			it:   "should shorten a long string",
+			in:   "0123456789abc",
+			max:  10,
+			want: "0123456...",
+		},
+		{
+			it:   "should count runes instead of bytes",
+			in:   "ééééééééééé",
+			max:  10,
+			want: "ééééééé...",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			assert.Equal(t, tst.want, truncate(tst.in, tst.max))
+		})
+	}
+}
+
+func Test_printStatus(t *testing.T) {
+	long := "upgrading node pool " + strings.Repeat("x", 50)
+	environments := []v1.Environment{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "dev"},
+			Status: v1.EnvironmentStatus{
+				Steps: map[string]v1.StepStatus{
+					"AKSPoolxyz": {State: v1.StateRunning, Message: long, Hash: "h2", Generation: 2},
+					"Infra":      {State: v1.StateReady, Hash: "h1", Source: &v1.StepSource{Commit: "c1"}, Generation: 1},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		it     string
+		output string
+		header bool
+		want   string
+	}{
+		{
+			it:     "should print a table with header and truncated messages",
+			header: true,
+			want: "NAMESPACE  ENVIRONMENT  CLUSTER  STEP     STATE    AGE        MESSAGE\n" +
+				"default    dev                   Infra    Ready    <unknown>  \n" +
+				"default    dev          xyz      AKSPool  Running  <unknown>  " + long[:57] + "...\n",
+		},
+		{
+			it:     "should print a wide table without header",
+			output: "wide",
+			want: "default  dev       Infra    Ready    <unknown>  " + strings.Repeat(" ", len(long)) + "  h1  c1  1\n" +
+				"default  dev  xyz  AKSPool  Running  <unknown>  " + long + "  h2      2\n",
+		},
+		{
+			it:     "should print yaml",
+			output: "yaml",
+			want:   "---\nmetadata:\n",
+		},
+		{
+			it:     "should print json",
+			output: "json",
+			want:   "{\n  \"metadata\": {\n",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			var b bytes.Buffer
+
+			err := printStatus(&b, environments, tst.output, tst.header)
+
+			assert.NoError(t, err)
+			if tst.output == "yaml" || tst.output == "json" {
+				assert.True(t, strings.HasPrefix(b.String(), tst.want), b.String())
+				return
+			}
+			assert.Equal(t, tst.want, b.String())
+		})
+	}
+}
+
+func Test_sendEnvironment(t *testing.T) {
+	dev := &v1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "dev"}}
+	test := &v1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"}}
+
+	t.Run("should send the named environment", func(t *testing.T) {
+		ch := make(chan *v1.Environment, 2)
+		fn := sendEnvironment(context.Background(), ch, "dev")
+		fn(test)
+		fn(dev)
+		fn("not an environment")
+		assert.Len(t, ch, 1)
+		assert.Same(t, dev, <-ch)
+	})
+
+	t.Run("should send all environments when no name is given", func(t *testing.T) {
+		ch := make(chan *v1.Environment, 2)
+		fn := sendEnvironment(context.Background(), ch, "")
+		fn(test)
+		fn(dev)
+		assert.Len(t, ch, 2)
+	})
+
+	t.Run("should not block when ctx is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		ch := make(chan *v1.Environment)
+		done := make(chan struct{})
+		go func() {
+			sendEnvironment(ctx, ch, "")(dev)
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("handler blocked after ctx is done")
+		}
+	})
+}
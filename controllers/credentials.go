@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/cloud"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+)
+
+// CredentialsExpiringWithin is the time before expiry the credentials are reported as Expiring.
+var credentialsExpiringWithin = 7 * 24 * time.Hour
+
+// CredentialsCondition returns the CredentialsExpiring condition given the result of a cloud login at time now.
+func credentialsCondition(sp *cloud.ServicePrincipal, loginErr error, now time.Time) v1.EnvironmentCondition {
+	c := v1.EnvironmentCondition{
+		Type:   v1.ConditionCredentialsExpiring,
+		Status: metav1.ConditionFalse,
+		Reason: v1.ReasonNotExpiring,
+	}
+	switch {
+	case loginErr != nil:
+		c.Status = metav1.ConditionUnknown
+		c.Reason = v1.ReasonLoginFailed
+		c.Message = loginErr.Error()
+	case sp == nil || sp.ExpiresOn == nil:
+		// expiry unknown
+	case !now.Before(*sp.ExpiresOn):
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ReasonExpired
+		c.Message = fmt.Sprintf("credentials of %s expired on %s", sp.ClientID, sp.ExpiresOn.Format(time.RFC3339))
+	case sp.ExpiresOn.Sub(now) < credentialsExpiringWithin:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ReasonExpiring
+		c.Message = fmt.Sprintf("credentials of %s expire on %s", sp.ClientID, sp.ExpiresOn.Format(time.RFC3339))
+	}
+
+	return c
+}
+
+// CloudFor returns the Cloud of cr.
+// An Environment with a credentials spec gets its own Cloud that reads the credentials Secret on login.
+func (r *EnvironmentReconciler) cloudFor(cr *v1.Environment) cloud.Cloud {
+	if r.Accounts == nil {
+		return r.Cloud
+	}
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}
+	spec := cr.Spec.Infra.Credentials
+	acc := r.Accounts.Set(nsn, spec, func() ([]byte, error) {
+		return r.credentials(nsn.Namespace, spec.SecretName)
+	})
+	return acc.Cloud
+}
+
+// Credentials returns the JSON formatted ServicePrincipal in Secret namespace/name.
+func (r *EnvironmentReconciler) credentials(namespace, name string) ([]byte, error) {
+	s := &corev1.Secret{}
+	err := r.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, s)
+	if err != nil {
+		return nil, fmt.Errorf("credentials secret: %w", err)
+	}
+	b, ok := s.Data[v1.CredentialsSecretKey]
+	if !ok {
+		return nil, fmt.Errorf("credentials secret %s: no key %s", name, v1.CredentialsSecretKey)
+	}
+	return b, nil
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/cloud"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func Test_credentialsCondition(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	sp := func(expires time.Duration) *cloud.ServicePrincipal {
+		r := &cloud.ServicePrincipal{ClientID: "envop"}
+		if expires != 0 {
+			e := now.Add(expires)
+			r.ExpiresOn = &e
+		}
+		return r
+	}
+
+	tests := []struct {
+		it          string
+		sp          *cloud.ServicePrincipal
+		err         error
+		wantStatus  metav1.ConditionStatus
+		wantReason  v1.EnvironmentConditionReason
+		wantMessage string
+	}{
+		{
+			it:         "should not be expiring when expiry is unknown",
+			sp:         sp(0),
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1.ReasonNotExpiring,
+		},
+		{
+			it:         "should not be expiring when expiry is far away",
+			sp:         sp(30 * 24 * time.Hour),
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1.ReasonNotExpiring,
+		},
+		{
+			it:          "should be expiring within a week",
+			sp:          sp(48 * time.Hour),
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  v1.ReasonExpiring,
+			wantMessage: "credentials of envop expire on 2006-01-04T15:04:05Z",
+		},
+		{
+			it:          "should be expired",
+			sp:          sp(-time.Second),
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  v1.ReasonExpired,
+			wantMessage: "credentials of envop expired on 2006-01-02T15:04:04Z",
+		},
+		{
+			it:          "should be unknown when login fails",
+			err:         fmt.Errorf("AADSTS7000222: The provided client secret keys are expired"),
+			wantStatus:  metav1.ConditionUnknown,
+			wantReason:  v1.ReasonLoginFailed,
+			wantMessage: "AADSTS7000222: The provided client secret keys are expired",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			c := credentialsCondition(tst.sp, tst.err, now)
+			assert.Equal(t, v1.ConditionCredentialsExpiring, c.Type)
+			assert.Equal(t, tst.wantStatus, c.Status)
+			assert.Equal(t, tst.wantReason, c.Reason)
+			assert.Equal(t, tst.wantMessage, c.Message)
+		})
+	}
+}
+
+func Test_validateSpec_credentials(t *testing.T) {
+	tests := []struct {
+		it          string
+		credentials v1.CredentialsSpec
+		wantErr     string
+	}{
+		{
+			it: "should accept no credentials",
+		},
+		{
+			it:          "should accept a vault with credentials",
+			credentials: v1.CredentialsSpec{SecretName: "env1-sp", Vault: "env1-kv"},
+		},
+		{
+			it:          "should reject a vault without credentials",
+			credentials: v1.CredentialsSpec{Vault: "env1-kv"},
+			wantErr:     "spec.infra.credentials.vault: requires spec.infra.credentials.secretName",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			spec := testSpec1()
+			spec.Infra.Credentials = tst.credentials
+			err := validateSpec(spec)
+			if tst.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tst.wantErr)
+		})
+	}
+}
@@ -21,19 +21,29 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/imdario/mergo"
 	"github.com/mmlt/environment-operator/pkg/cloud"
+	"github.com/mmlt/environment-operator/pkg/events"
+	"github.com/mmlt/environment-operator/pkg/logsink"
+	"github.com/mmlt/environment-operator/pkg/logstream"
+	"github.com/mmlt/environment-operator/pkg/notify"
 	"github.com/mmlt/environment-operator/pkg/plan"
 	"github.com/mmlt/environment-operator/pkg/source"
 	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/mmlt/environment-operator/pkg/tracing"
 	"github.com/mmlt/environment-operator/pkg/util"
-	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/attribute"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	ctrlsource "sigs.k8s.io/controller-runtime/pkg/source"
 	"time"
 
 	v1 "github.com/mmlt/environment-operator/api/v1"
@@ -52,6 +62,9 @@ type EnvironmentReconciler struct {
 
 	// Cloud provides generic cloud access functions.
 	Cloud cloud.Cloud
+	// Accounts (optional) provides the Cloud of Environments with their own credentials.
+	// When nil Cloud is used for all Environments.
+	Accounts *cloud.Accounts
 
 	// Sources fetches tf or yaml source code.
 	Sources *source.Sources
@@ -62,6 +75,20 @@ type EnvironmentReconciler struct {
 	// Environ are the environment variables presented to the steps.
 	Environ map[string]string
 
+	// LogSink (optional) stores the output of steps.
+	LogSink logsink.Sink
+
+	// LogStream (optional) makes the output of running steps available.
+	LogStream *logstream.Hub
+
+	// Notifier (optional) sends step transitions to the endpoints in Environment spec.notifications and Notifications.
+	Notifier *notify.Notifier
+	// Notifications are the endpoints that are notified of the step transitions of all environments.
+	Notifications []v1.NotificationSpec
+
+	// Events (optional) emits lifecycle events.
+	Events *events.Emitter
+
 	// Invocation counters
 	reconTally int
 }
@@ -71,10 +98,23 @@ var timeNow = time.Now
 
 // +kubebuilder:rbac:groups=clusterops.mmlt.nl,resources=environments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=clusterops.mmlt.nl,resources=environments/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=clusterops.mmlt.nl,resources=freezes,verbs=get;list;watch
+// +kubebuilder:rbac:groups=clusterops.mmlt.nl,resources=environmentruns,verbs=get;list;watch;create;delete
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;delete
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // Reconcile takes an Environment custom resource and attempts to converge the target environment to the desired state.
 // The status of the k8s resource is updated to match the observed state of the Envirnoment.
 func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, "Reconcile", attribute.String("environment", req.String()))
+	res, err := r.reconcile(ctx, req)
+	tracing.End(span, err)
+
+	return res, err
+}
+
+// Reconcile implements Reconcile.
+func (r *EnvironmentReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var (
 		noRequeue   = ctrl.Result{}
 		requeueNow  = ctrl.Result{Requeue: true}
@@ -91,19 +131,51 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 	// Get Environment Custom Resource (deep copy).
 	cr := &v1.Environment{}
 	if err := r.Get(ctx, req.NamespacedName, cr); err != nil {
+		if apierrors.IsNotFound(err) && r.Accounts != nil {
+			// Environment is deleted.
+			r.Accounts.Delete(req.NamespacedName)
+		}
 		log.V(2).Info("unable to get kind Environment (retried)", "error", err)
 		return requeueSoon, ignoreNotFound(err)
 	}
 
-	// Ignore when not within time schedule.
-	ok, err := inSchedule(cr.Spec.Infra.Schedule, timeNow())
+	// Report credentials that (are about to) expire.
+	sp, lerr := r.cloudFor(cr).Login()
+	cc := credentialsCondition(sp, lerr, timeNow())
+	if prev := getCondition(cr.Status.Conditions, v1.ConditionCredentialsExpiring); prev == nil || prev.Reason != cc.Reason || prev.Message != cc.Message {
+		cc.LastTransitionTime = metav1.Time{Time: timeNow()}
+		if cc.Status != metav1.ConditionFalse {
+			r.Recorder.Event(cr, "Warning", "Credentials"+string(cc.Reason), cc.Message)
+		}
+	}
+	setCondition(&cr.Status.Conditions, cc)
+
+	// Ignore when frozen.
+	freezes := &v1.FreezeList{}
+	err := r.List(ctx, freezes)
 	if err != nil {
-		// Schedule contains error (needs user to fix it first so do noy retry).
-		r.Recorder.Event(cr, "Warning", "Config", "infra.schedule:"+err.Error())
-		return noRequeue, fmt.Errorf("spec.infra.schedule: %w", err)
+		return requeueSoon, fmt.Errorf("list freezes: %w", err)
 	}
-	if !ok {
-		log.V(2).Info("outside schedule", "schedule", cr.Spec.Infra.Schedule)
+	fc, expires, err := freezeCondition(freezes.Items, cr, timeNow())
+	if err != nil {
+		return requeueSoon, err
+	}
+	if prev := getCondition(cr.Status.Conditions, v1.ConditionFrozen); prev == nil || prev.Reason != fc.Reason || prev.Message != fc.Message {
+		fc.LastTransitionTime = metav1.Time{Time: timeNow()}
+		if fc.Reason != v1.ReasonNotFrozen {
+			r.Recorder.Event(cr, "Normal", string(fc.Reason), fc.Message)
+		}
+	}
+	setCondition(&cr.Status.Conditions, fc)
+	if fc.Status == metav1.ConditionTrue {
+		log.V(2).Info("frozen", "msg", fc.Message)
+		err = r.saveStatus2(ctx, cr)
+		if err != nil {
+			return requeueNow, fmt.Errorf("save status: %w", err)
+		}
+		if expires > 0 {
+			return ctrl.Result{RequeueAfter: expires}, nil
+		}
 		return noRequeue, nil
 	}
 
@@ -112,8 +184,53 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		return noRequeue, nil
 	}
 
+	// Postpone planning when no step is within a maintenance window.
+	cr.Status.NextWindow = nil
+	ok, next, err := anyInWindow(cr.Spec, timeNow())
+	if err != nil {
+		// Schedule contains error (needs user to fix it first so do noy retry).
+		r.Recorder.Event(cr, "Warning", "Config", err.Error())
+		return noRequeue, fmt.Errorf("spec: %w", err)
+	}
+	if !ok {
+		log.V(2).Info("outside schedule", "next", next)
+		cr.Status.NextWindow = next
+		err = r.saveStatus2(ctx, cr)
+		if err != nil {
+			return requeueNow, fmt.Errorf("save status: %w", err)
+		}
+		if next != nil {
+			return ctrl.Result{RequeueAfter: next.Sub(timeNow())}, nil
+		}
+		return noRequeue, nil
+	}
+
 	// Plan work.
-	stp, err := r.nextStep(cr, req, log)
+	planned := plannedSteps(cr.Status)
+	stp, err := r.nextStep(ctx, cr, req)
+	if err == nil {
+		cr.Status.ObservedGeneration = cr.Generation
+		r.emitPlan(ctx, cr, planned)
+	}
+
+	// Postpone work when the next step is not within a maintenance window.
+	var wait time.Duration
+	if stp != nil {
+		ok, next, err := inWindow(cr.Spec, stp.GetID(), timeNow())
+		if err != nil {
+			// Schedule contains error (needs user to fix it first so do noy retry).
+			r.Recorder.Event(cr, "Warning", "Config", err.Error())
+			return noRequeue, fmt.Errorf("spec: %w", err)
+		}
+		if !ok {
+			log.V(2).Info("outside schedule", "step", stp.GetID().ShortName(), "next", next)
+			cr.Status.NextWindow = next
+			if next != nil {
+				wait = next.Sub(timeNow())
+			}
+			stp = nil
+		}
+	}
 
 	// save planned steps (some steps might need to be re-executed)
 	err = r.saveStatus2(ctx, cr)
@@ -123,6 +240,12 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 	// Execute work.
 	if stp != nil {
+		var logID string
+		if r.LogSink != nil {
+			logID = logsink.RunID(timeNow())
+			stp.SetLogSink(r.LogSink, logsink.Run{Namespace: cr.Namespace, Environment: cr.Name,
+				Step: stp.GetID().ShortName(), ID: logID})
+		}
 		stp.SetOnUpdate(func(meta step.Meta) {
 			log1 := logr.FromContext(ctx).WithName("OnUpdate")
 			ctx1 := logr.NewContext(ctx, log)
@@ -134,10 +257,27 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			}
 			s := meta.GetState()
 			log1.Info("callback", "msg", m, "state", s, "id", meta.GetID().ShortName())
-			r.update(ctx1, cr, meta)
+			r.update(ctx1, cr, stp, logID)
 		})
+		prepareGate(cr.Status, stp)
 		env := util.KVSliceFromMap(r.Environ)
-		stp.Execute(ctx, env)
+		ctx2, span := tracing.Start(ctx, "Execute", attribute.String("step", stp.GetID().ShortName()))
+		if r.LogStream != nil {
+			w := r.LogStream.Writer(cr.Namespace, cr.Name, stp.GetID().ShortName())
+			stp.Execute(logstream.NewContext(ctx2, w), env)
+			_ = w.Close()
+		} else {
+			stp.Execute(ctx2, env)
+		}
+		tracing.End(span, stp.GetLastError())
+
+		if d := gateWait(stp); d > 0 {
+			wait = d
+		}
+	}
+
+	if wait > 0 {
+		return ctrl.Result{RequeueAfter: wait}, nil
 	}
 
 	return noRequeue, nil
@@ -145,7 +285,12 @@ func (r *EnvironmentReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 
 // NextStep fetches sources, makes a plan, updates cr and returns the next step.
 // Return nil if there is nothing to do.
-func (r *EnvironmentReconciler) nextStep(cr *v1.Environment, req ctrl.Request, log logr.Logger) (step.Step, error) {
+func (r *EnvironmentReconciler) nextStep(ctx context.Context, cr *v1.Environment, req ctrl.Request) (_ step.Step, err error) {
+	ctx, span := tracing.Start(ctx, "nextStep")
+	defer func() { tracing.End(span, err) }()
+
+	log := logr.FromContext(ctx)
+
 	// Get ClusterSpecs with defaults.
 	cspec, err := flattenedClusterSpec(cr.Spec)
 	if err != nil {
@@ -155,11 +300,13 @@ func (r *EnvironmentReconciler) nextStep(cr *v1.Environment, req ctrl.Request, l
 	}
 
 	// Replace references to secret values with the value from vault.
-	ispec, err := vaultInfraValues(cr.Spec.Infra, r.Cloud)
-	if err != nil {
-		return nil, fmt.Errorf("vault ref: %w", err)
+	_, vspan := tracing.Start(ctx, "vault")
+	cl := r.cloudFor(cr)
+	ispec, err := VaultInfraValues(cr.Spec.Infra, cl)
+	if err == nil {
+		cspec, err = VaultClusterValues(cspec, cl)
 	}
-	cspec, err = vaultClusterValues(cspec, r.Cloud)
+	tracing.End(vspan, err)
 	if err != nil {
 		return nil, fmt.Errorf("vault ref: %w", err)
 	}
@@ -175,7 +322,7 @@ func (r *EnvironmentReconciler) nextStep(cr *v1.Environment, req ctrl.Request, l
 			return nil, fmt.Errorf("source: register cluster: %w", err)
 		}
 	}
-	err = r.Sources.FetchAll()
+	err = r.Sources.FetchAll(ctx)
 	if err != nil {
 		log.Error(err, "source: fetch")
 	}
@@ -192,7 +339,9 @@ func (r *EnvironmentReconciler) nextStep(cr *v1.Environment, req ctrl.Request, l
 	}
 
 	// Make a plan
-	pln, err := r.Planner.Plan(req.NamespacedName, r.Sources, cr.Spec.Destroy, ispec, cspec)
+	_, pspan := tracing.Start(ctx, "plan")
+	pln, err := r.Planner.Plan(req.NamespacedName, r.Sources, cr.Spec.Destroy, ispec, cspec, cr.Spec.Rollout)
+	tracing.End(pspan, err)
 	if err != nil {
 		return nil, fmt.Errorf("plan: %w", err)
 	}
@@ -200,41 +349,73 @@ func (r *EnvironmentReconciler) nextStep(cr *v1.Environment, req ctrl.Request, l
 	if err != nil {
 		return nil, fmt.Errorf("sync status with plan: %w", err)
 	}
+	refreshStepSources(&cr.Status, pln, func(id step.ID) *v1.StepSource {
+		return r.stepSource(req.NamespacedName, id)
+	})
 	return stp, nil
 }
 
-// InSchedule returns true when time now is in CRON schedule or the schedule is empty.
-//
-//  Field name   | Mandatory? | Allowed values  | Allowed special characters
-//  ----------   | ---------- | --------------  | --------------------------
-//  Minutes      | Yes        | 0-59            | * / , -
-//  Hours        | Yes        | 0-23            | * / , -
-//  Day of month | Yes        | 1-31            | * / , - ?
-//  Month        | Yes        | 1-12 or JAN-DEC | * / , -
-//  Day of week  | Yes        | 0-6 or SUN-SAT  | * / , - ?
-//
-// Special characters:
-//   * always
-//   / interval, for example */5 is every 5m
-//   , list, for example MON,FRI in DayOfWeek field
-//   - range, for example 20-04 in hour field
-// See https://godoc.org/github.com/robfig/cron#Parser
-func inSchedule(schedule string, now time.Time) (bool, error) {
-	if schedule == "" {
-		return true, nil
-	}
-
-	p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
-	sc, err := p.Parse(schedule)
+// InWindow returns true when the step with id is allowed to run at time now.
+// When the step is not allowed to run the start of the next window is returned (nil if there is none within a year).
+func inWindow(spec v1.EnvironmentSpec, id step.ID, now time.Time) (bool, *metav1.Time, error) {
+	cspec, err := flattenedClusterSpec(spec)
+	if err != nil {
+		return false, nil, err
+	}
+
+	tt, err := timetableForStep(id, spec.Infra, cspec)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if tt.allowed(now) {
+		return true, nil, nil
+	}
+
+	next, ok := tt.next(now)
+	if !ok {
+		return false, nil, nil
+	}
+
+	return false, &metav1.Time{Time: next}, nil
+}
+
+// AnyInWindow returns true when a step of spec is allowed to run at time now.
+// It doesn't need a plan so it can be evaluated before sources are fetched and a plan is made.
+// When no step is allowed to run the earliest start of the next window is returned (nil if there is none within a year).
+func anyInWindow(spec v1.EnvironmentSpec, now time.Time) (bool, *metav1.Time, error) {
+	cspec, err := flattenedClusterSpec(spec)
 	if err != nil {
-		return false, err
+		return false, nil, err
 	}
 
-	next := sc.Next(now)
+	ids := []step.ID{{Type: step.TypeInfra}}
+	for _, c := range cspec {
+		ids = append(ids, step.ID{Type: step.TypeAddons, ClusterName: c.Name})
+	}
+	if len(plan.Batches(spec.Rollout, cspec)) > 1 {
+		// gates are only limited by blackouts.
+		ids = append(ids, step.ID{Type: step.TypeRolloutGate})
+	}
 
-	ok := next.Sub(now).Minutes() < 10.0
+	var first time.Time
+	for _, id := range ids {
+		tt, err := timetableForStep(id, spec.Infra, cspec)
+		if err != nil {
+			return false, nil, err
+		}
+		if tt.allowed(now) {
+			return true, nil, nil
+		}
+		if next, ok := tt.next(now); ok && (first.IsZero() || next.Before(first)) {
+			first = next
+		}
+	}
 
-	return ok, nil
+	if first.IsZero() {
+		return false, nil, nil
+	}
+	return false, &metav1.Time{Time: first}, nil
 }
 
 // getStepAndSyncStatusWithPlan update status.steps with plan and returns the next step to execute.
@@ -303,13 +484,17 @@ func getStepAndSyncStatusWithPlan(status *v1.EnvironmentStatus, plan []step.Step
 // SaveStatus writes the status to the API server.
 func (r *EnvironmentReconciler) saveStatus2(ctx context.Context, cr *v1.Environment) error {
 	// clean-up steps (consider moving to getStepAndSyncStatusWithPlan)
-	p := r.Planner.PossibleSteps(cr.Spec.Clusters)
+	p := r.Planner.PossibleSteps(cr.Spec.Clusters, cr.Spec.Rollout)
 	for n := range cr.Status.Steps {
 		if _, ok := p[n]; ok {
 			continue
 		}
 		delete(cr.Status.Steps, n)
 	}
+	pruneHistory(&cr.Status, p)
+
+	cr.Status.Rollout = rolloutStatus(cr.Status, cr.Spec.Clusters, cr.Spec.Rollout)
+	cr.Status.Clusters = clusterStatuses(cr.Status, cr.Spec.Clusters)
 
 	updateStatusConditions(&cr.Status)
 
@@ -322,98 +507,256 @@ func (r *EnvironmentReconciler) saveStatus2(ctx context.Context, cr *v1.Environm
 }
 
 // UpdateStatusConditions updates Status.Conditions to reflect steps state.
-// Ready = True when all steps are in their final state, Reason is Ready or Failed.
-// Ready = False when a step is running, Reason is Running.
-// Ready = Unknown when no steps are present.
+// Ready, InfraReady and ClustersReady are True when all (infra, cluster) steps are Ready and False otherwise.
+// Their Reason is Ready, Running (a step is running), Failed (a step is in error) or Pending (steps are waiting to run).
+// They are Unknown when no steps are present.
+// Reconciling is True when the Reason is Running or Pending, Stalled is True when the Reason is Failed.
 func updateStatusConditions(status *v1.EnvironmentStatus) {
-	var runningCnt, readyCnt, errorCnt, totalCnt int
-	var latestTime metav1.Time
+	all := tallySteps(status.Steps, func(string) bool { return true })
+	infra := tallySteps(status.Steps, isInfraStep)
+	clusters := tallySteps(status.Steps, func(n string) bool { return !isInfraStep(n) })
 
-	for _, st := range status.Steps {
-		totalCnt++
+	setCondition(&status.Conditions, all.condition(v1.ConditionReady))
+	setCondition(&status.Conditions, infra.condition(v1.ConditionInfraReady))
+	setCondition(&status.Conditions, clusters.condition(v1.ConditionClustersReady))
+
+	c := all.condition(v1.ConditionReconciling)
+	c.Status = metav1.ConditionFalse
+	if c.Reason == v1.ReasonRunning || c.Reason == v1.ReasonPending || all.total == 0 {
+		c.Status = metav1.ConditionTrue
+	}
+	if c.Reason == "" {
+		c.Reason = v1.ReasonPending
+	}
+	setCondition(&status.Conditions, c)
+
+	c = all.condition(v1.ConditionStalled)
+	c.Status = metav1.ConditionFalse
+	if c.Reason == v1.ReasonFailed {
+		c.Status = metav1.ConditionTrue
+	}
+	if c.Reason == "" {
+		c.Reason = v1.ReasonPending
+	}
+	setCondition(&status.Conditions, c)
+}
+
+// ClusterStatuses returns a synopsis of the steps of each cluster in cspec.
+func clusterStatuses(status v1.EnvironmentStatus, cspec []v1.ClusterSpec) []v1.ClusterStatus {
+	var r []v1.ClusterStatus
+	for _, cl := range cspec {
+		names := make(map[string]bool, len(step.ClusterTypes))
+		for _, t := range step.ClusterTypes {
+			names[string(t)+cl.Name] = true
+		}
+		c := tallySteps(status.Steps, func(n string) bool { return names[n] }).condition("")
+		r = append(r, v1.ClusterStatus{
+			Name:    cl.Name,
+			Status:  c.Status,
+			Reason:  c.Reason,
+			Message: c.Message,
+		})
+	}
+	return r
+}
+
+// IsInfraStep returns true if the step with short name n applies to all clusters.
+func isInfraStep(n string) bool {
+	for _, t := range step.InfraTypes {
+		if n == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// StepTally counts steps by state.
+type stepTally struct {
+	running, ready, error, total int
+	// latest is the most recent step transition time.
+	latest metav1.Time
+}
+
+// TallySteps counts the steps for which include returns true.
+func tallySteps(steps map[string]v1.StepStatus, include func(name string) bool) stepTally {
+	var t stepTally
+	for n, st := range steps {
+		if !include(n) {
+			continue
+		}
+		t.total++
 		switch st.State {
 		case v1.StateRunning:
-			runningCnt++
+			t.running++
 		case v1.StateReady:
-			readyCnt++
+			t.ready++
 		case v1.StateError:
-			errorCnt++
+			t.error++
 		}
 
-		if st.LastTransitionTime.After(latestTime.Time) {
-			latestTime = st.LastTransitionTime
+		if st.LastTransitionTime.After(t.latest.Time) {
+			t.latest = st.LastTransitionTime
 		}
 	}
+	return t
+}
 
+// Condition returns a condition of type typ that is True when all steps are Ready.
+func (t stepTally) condition(typ v1.EnvironmentConditionType) v1.EnvironmentCondition {
 	c := v1.EnvironmentCondition{
-		Type: "Ready", //TODO define in API types
+		Type: typ,
 	}
 	switch {
-	case errorCnt > 0:
-		c.Status = metav1.ConditionTrue
+	case t.total == 0:
+		c.Status = metav1.ConditionUnknown
+		c.Reason = ""
+	case t.error > 0:
+		c.Status = metav1.ConditionFalse
 		c.Reason = v1.ReasonFailed
-	case runningCnt > 0:
+	case t.running > 0:
 		c.Status = metav1.ConditionFalse
 		c.Reason = v1.ReasonRunning
-	case readyCnt == totalCnt && totalCnt > 0:
+	case t.ready == t.total:
 		c.Status = metav1.ConditionTrue
 		c.Reason = v1.ReasonReady
 	default:
-		c.Status = metav1.ConditionUnknown
-		c.Reason = ""
+		c.Status = metav1.ConditionFalse
+		c.Reason = v1.ReasonPending
 	}
-	c.Message = fmt.Sprintf("%d/%d ready, %d running, %d error(s)", readyCnt, totalCnt, runningCnt, errorCnt)
-	if latestTime.IsZero() {
-		latestTime = metav1.Time{Time: timeNow()}
+	c.Message = fmt.Sprintf("%d/%d ready, %d running, %d error(s)", t.ready, t.total, t.running, t.error)
+	c.LastTransitionTime = t.latest
+	if c.LastTransitionTime.IsZero() {
+		c.LastTransitionTime = metav1.Time{Time: timeNow()}
 	}
-	c.LastTransitionTime = latestTime
 
-	// update condition
-	ci := -1
-	for i, v := range status.Conditions {
-		if v.Type == c.Type {
-			ci = i
-			break
+	return c
+}
+
+// SetCondition adds or replaces the condition with the same type as c.
+// An existing condition is left untouched when its status, reason and message are equal to c.
+func setCondition(conditions *[]v1.EnvironmentCondition, c v1.EnvironmentCondition) {
+	for i, v := range *conditions {
+		if v.Type != c.Type {
+			continue
 		}
-	}
-	if ci >= 0 {
-		x := status.Conditions[ci]
-		if x.Status == c.Status &&
-			x.Reason == c.Reason &&
-			x.Message == c.Message {
+		if v.Status == c.Status &&
+			v.Reason == c.Reason &&
+			v.Message == c.Message {
 			// no change in condition
 			return
 		}
-		status.Conditions[ci] = c
-	} else {
-		status.Conditions = append(status.Conditions, c)
+		(*conditions)[i] = c
+		return
+	}
+	*conditions = append(*conditions, c)
+}
+
+// GetCondition returns the condition of type t or nil if not found.
+func getCondition(conditions []v1.EnvironmentCondition, t v1.EnvironmentConditionType) *v1.EnvironmentCondition {
+	for i := range conditions {
+		if conditions[i].Type == t {
+			return &conditions[i]
+		}
 	}
+	return nil
 }
 
-// Update updates cr.Status with meta, writes the status to the API Server and records an Event.
-func (r *EnvironmentReconciler) update(ctx context.Context, cr *v1.Environment, meta step.Meta) {
+// Update updates cr.Status with meta, adds the change to the step history, records an Event, emits lifecycle events,
+// writes the status to the API Server and sends notifications.
+// When the step has ended an EnvironmentRun is created.
+// LogID identifies the logs of the step run.
+func (r *EnvironmentReconciler) update(ctx context.Context, cr *v1.Environment, meta step.Meta, logID string) {
 	log := logr.FromContext(ctx)
 
 	shortname := meta.GetID().ShortName()
 
-	r.Recorder.Event(cr, "Normal", shortname+string(meta.GetState()), meta.GetMsg())
+	nsn := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}
 
 	// copy meta to step
 	ss := cr.Status.Steps[shortname]
+	prev := ss.State
+	recordStepEvent(r.Recorder, cr, shortname, prev, meta)
+	recordRun(&cr.Status, shortname, prev, meta, r.stepSource(nsn, meta.GetID()), logID, timeNow())
 	ss.State = meta.GetState()
 	ss.Message = meta.GetMsg()
 	ss.LastTransitionTime = metav1.Time{Time: timeNow()}
 	if ss.State == v1.StateReady {
 		// step has completed.
 		ss.Hash = meta.GetHash()
+		ss.Source = r.stepSource(nsn, meta.GetID())
+		ss.Generation = cr.Generation
 	}
 	cr.Status.Steps[shortname] = ss
+	r.emitStep(ctx, cr, shortname, prev, meta, ss)
 
 	err := r.saveStatus2(ctx, cr)
 	if err != nil {
 		// failing to save a final state will result in re-execution of the step
 		log.Error(err, "saveStatus")
 	}
+
+	// notify after the save so slow endpoints don't delay it.
+	r.notify(ctx, cr, shortname, prev, meta)
+
+	if step.IsStateFinal(ss.State) {
+		err = r.recordEnvironmentRun(ctx, cr, meta.GetID())
+		if err != nil {
+			log.Error(err, "environmentRun")
+		}
+	}
+}
+
+// RecordStepEvent records an Event on cr for the change of step name from prev to the meta state.
+// Progress updates of a Running step are not recorded to prevent an Event every few seconds during an apply.
+func recordStepEvent(recorder record.EventRecorder, cr *v1.Environment, name string, prev v1.StepState, meta step.Meta) {
+	state := meta.GetState()
+	if prev == v1.StateRunning && state == v1.StateRunning {
+		return
+	}
+	recorder.Event(cr, "Normal", name+string(state), meta.GetMsg())
+}
+
+// StepSource returns the source revision that is applied by the step with id.
+// Returns nil if the step doesn't apply a source.
+func (r *EnvironmentReconciler) stepSource(nsn types.NamespacedName, id step.ID) *v1.StepSource {
+	var name string
+	switch id.Type {
+	case step.TypeAddons:
+		name = id.ClusterName
+	case step.TypeRolloutGate:
+		return nil
+	}
+
+	w, ok := r.Sources.Workspace(nsn, name)
+	if !ok {
+		return nil
+	}
+
+	return &v1.StepSource{
+		URL:      w.Spec.URL,
+		Ref:      w.Spec.Ref,
+		Commit:   w.Revision,
+		Area:     w.Spec.Area,
+		AreaHash: w.Hash,
+	}
+}
+
+// RefreshStepSources updates the source of the Ready steps in status that are at the desired state of plan.
+// A step doesn't re-run when a new commit doesn't change its area, its source is at the new commit nevertheless.
+func refreshStepSources(status *v1.EnvironmentStatus, plan []step.Step, source func(step.ID) *v1.StepSource) {
+	for _, stp := range plan {
+		n := stp.GetID().ShortName()
+		ss, ok := status.Steps[n]
+		if !ok || ss.State != v1.StateReady || ss.Hash != stp.GetHash() {
+			continue
+		}
+		s := source(stp.GetID())
+		if s == nil || (ss.Source != nil && ss.Source.AreaHash != s.AreaHash) {
+			continue
+		}
+		ss.Source = s
+		status.Steps[n] = ss
+	}
 }
 
 // SetupWithManager initializes the receiver and adds it to mgr.
@@ -425,9 +768,25 @@ func (r *EnvironmentReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		},
 	)
 
+	// A Freeze change reconciles all Environments.
+	freezeToEnvironments := handler.EnqueueRequestsFromMapFunc(
+		func(_ client.Object) []reconcile.Request {
+			envs := &v1.EnvironmentList{}
+			err := r.List(context.Background(), envs, client.MatchingLabels(r.LabelSet))
+			if err != nil {
+				return nil
+			}
+			var reqs []reconcile.Request
+			for _, e := range envs.Items {
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: e.Namespace, Name: e.Name}})
+			}
+			return reqs
+		},
+	)
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&v1.Environment{}).
-		WithEventFilter(lp).
+		For(&v1.Environment{}, builder.WithPredicates(lp)).
+		Watches(&ctrlsource.Kind{Type: &v1.Freeze{}}, freezeToEnvironments).
 		Complete(r)
 }
 
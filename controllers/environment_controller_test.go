@@ -1,12 +1,12 @@
 package controllers
 
 import (
-	"errors"
 	"github.com/go-logr/stdr"
 	v1 "github.com/mmlt/environment-operator/api/v1"
 	"github.com/mmlt/environment-operator/pkg/step"
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
 	"log"
 	"os"
 	"testing"
@@ -67,65 +67,6 @@ func TestController_flattenedClusterSpec(t *testing.T) {
 	}
 }
 
-func Test_inSchedule(t *testing.T) {
-	type args struct {
-		schedule string
-		now      string
-	}
-	tests := []struct {
-		it   string
-		args args
-		want bool
-		err  error
-	}{
-		{
-			it: "should return true when in schedule",
-			args: args{
-				schedule: "* 15 * * *",
-				now:      "2006-01-02T15:04:05Z",
-			},
-			want: true,
-			err:  nil,
-		},
-		{
-			it: "should return false when outside schedule",
-			args: args{
-				schedule: "* 22-23,0-4 * * *",
-				now:      "2006-01-02T15:04:05Z",
-			},
-			want: false,
-			err:  nil,
-		},
-		{
-			it: "should return true at start of nightly schedule",
-			args: args{
-				schedule: "* 0-4 * * *",
-				now:      "2006-01-02T00:04:05Z",
-			},
-			want: true,
-			err:  nil,
-		},
-		{
-			it: "should return and error on invalid schedule",
-			args: args{
-				schedule: "* 22-04 * * *",
-				now:      "2006-01-03T03:04:05Z",
-			},
-			want: false,
-			err:  errors.New("beginning of range (22) beyond end of range (4): 22-04"),
-		},
-	}
-	for _, tt := range tests {
-		t.Run(tt.it, func(t *testing.T) {
-			now, err := time.Parse(time.RFC3339, tt.args.now)
-			assert.NoError(t, err)
-			got, err := inSchedule(tt.args.schedule, now)
-			assert.Equal(t, tt.want, got)
-			assert.Equal(t, tt.err, err)
-		})
-	}
-}
-
 func Test_syncStatusWithPlan(t *testing.T) {
 	// test helper
 	newStep := func(typ step.Type, clusterName, hash string) step.Step {
@@ -289,9 +230,11 @@ func Test_updateStatusConditions(t *testing.T) {
 		it            string
 		args          args
 		wantCondition v1.EnvironmentCondition
+		// wantStatus is the status of the other conditions.
+		wantStatus map[v1.EnvironmentConditionType]metav1.ConditionStatus
 	}{
 		{
-			it: "should say status: Unknown when all steps states are unknown (empty)",
+			it: "should say status: False reason: Pending when all steps states are unknown (empty)",
 			args: args{
 				status: &v1.EnvironmentStatus{
 					Steps: map[string]v1.StepStatus{
@@ -299,7 +242,10 @@ func Test_updateStatusConditions(t *testing.T) {
 						"Addonsfoo": {State: "", Message: "new", Hash: "456"},
 					}},
 			},
-			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "Unknown", Reason: "", Message: "0/2 ready, 0 running, 0 error(s)", LastTransitionTime: time1},
+			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "False", Reason: "Pending", Message: "0/2 ready, 0 running, 0 error(s)", LastTransitionTime: time1},
+			wantStatus: map[v1.EnvironmentConditionType]metav1.ConditionStatus{
+				v1.ConditionInfraReady: "False", v1.ConditionClustersReady: "False", v1.ConditionReconciling: "True", v1.ConditionStalled: "False",
+			},
 		},
 		{
 			it: "should say status: False reason: Running when some step(s) are running",
@@ -311,6 +257,9 @@ func Test_updateStatusConditions(t *testing.T) {
 					}},
 			},
 			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "False", Reason: "Running", Message: "0/2 ready, 1 running, 0 error(s)", LastTransitionTime: time1},
+			wantStatus: map[v1.EnvironmentConditionType]metav1.ConditionStatus{
+				v1.ConditionInfraReady: "False", v1.ConditionClustersReady: "False", v1.ConditionReconciling: "True", v1.ConditionStalled: "False",
+			},
 		},
 		{
 			it: "should say status: False reason: Running when some step(s) are ready and some are running",
@@ -322,9 +271,12 @@ func Test_updateStatusConditions(t *testing.T) {
 					}},
 			},
 			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "False", Reason: "Running", Message: "1/2 ready, 1 running, 0 error(s)", LastTransitionTime: time1},
+			wantStatus: map[v1.EnvironmentConditionType]metav1.ConditionStatus{
+				v1.ConditionInfraReady: "True", v1.ConditionClustersReady: "False", v1.ConditionReconciling: "True", v1.ConditionStalled: "False",
+			},
 		},
 		{
-			it: "should say status: True reason: Failed when some step(s) are in error state",
+			it: "should say status: False reason: Failed and Stalled when some step(s) are in error state",
 			args: args{
 				status: &v1.EnvironmentStatus{
 					Steps: map[string]v1.StepStatus{
@@ -332,7 +284,10 @@ func Test_updateStatusConditions(t *testing.T) {
 						"Addonsfoo": {State: "", Message: "new", Hash: "456"},
 					}},
 			},
-			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "True", Reason: "Failed", Message: "0/2 ready, 0 running, 1 error(s)", LastTransitionTime: time1},
+			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "False", Reason: "Failed", Message: "0/2 ready, 0 running, 1 error(s)", LastTransitionTime: time1},
+			wantStatus: map[v1.EnvironmentConditionType]metav1.ConditionStatus{
+				v1.ConditionInfraReady: "False", v1.ConditionClustersReady: "False", v1.ConditionReconciling: "False", v1.ConditionStalled: "True",
+			},
 		},
 		{
 			it: "should say status: True, reason: Ready when all steps completed successfully",
@@ -344,6 +299,9 @@ func Test_updateStatusConditions(t *testing.T) {
 					}},
 			},
 			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "True", Reason: "Ready", Message: "2/2 ready, 0 running, 0 error(s)", LastTransitionTime: time1},
+			wantStatus: map[v1.EnvironmentConditionType]metav1.ConditionStatus{
+				v1.ConditionInfraReady: "True", v1.ConditionClustersReady: "True", v1.ConditionReconciling: "False", v1.ConditionStalled: "False",
+			},
 		},
 		{
 			it: "should say status: Unknown, reason: empty when no steps have been defined",
@@ -351,6 +309,9 @@ func Test_updateStatusConditions(t *testing.T) {
 				status: &v1.EnvironmentStatus{},
 			},
 			wantCondition: v1.EnvironmentCondition{Type: "Ready", Status: "Unknown", Reason: "", Message: "0/0 ready, 0 running, 0 error(s)", LastTransitionTime: time1},
+			wantStatus: map[v1.EnvironmentConditionType]metav1.ConditionStatus{
+				v1.ConditionInfraReady: "Unknown", v1.ConditionClustersReady: "Unknown", v1.ConditionReconciling: "True", v1.ConditionStalled: "False",
+			},
 		},
 	}
 
@@ -363,10 +324,79 @@ func Test_updateStatusConditions(t *testing.T) {
 		t.Run(tt.it, func(t *testing.T) {
 			status := tt.args.status.DeepCopy()
 			updateStatusConditions(status)
-			if assert.Equal(t, 1, len(status.Conditions)) {
-				assert.Equal(t, tt.wantCondition, status.Conditions[0])
+			assert.Equal(t, 5, len(status.Conditions))
+			if c := getCondition(status.Conditions, v1.ConditionReady); assert.NotNil(t, c) {
+				assert.Equal(t, tt.wantCondition, *c)
+			}
+			for typ, want := range tt.wantStatus {
+				if c := getCondition(status.Conditions, typ); assert.NotNil(t, c, typ) {
+					assert.Equal(t, want, c.Status, typ)
+				}
 			}
 		})
 	}
 
 }
+
+func Test_clusterStatuses(t *testing.T) {
+	status := v1.EnvironmentStatus{
+		Steps: map[string]v1.StepStatus{
+			"Infra":                {State: "Ready"},
+			"AKSPoolfoo":           {State: "Ready"},
+			"AKSAddonPreflightfoo": {State: "Ready"},
+			"Addonsfoo":            {State: "Ready"},
+			"RolloutGate1":         {State: "Running"},
+			"AKSPoolbar":           {State: "Ready"},
+			"AKSAddonPreflightbar": {State: "Error"},
+		},
+	}
+	cspec := []v1.ClusterSpec{{Name: "foo"}, {Name: "bar"}, {Name: "baz"}}
+
+	got := clusterStatuses(status, cspec)
+
+	assert.Equal(t, []v1.ClusterStatus{
+		{Name: "foo", Status: "True", Reason: "Ready", Message: "3/3 ready, 0 running, 0 error(s)"},
+		{Name: "bar", Status: "False", Reason: "Failed", Message: "1/2 ready, 0 running, 1 error(s)"},
+		{Name: "baz", Status: "Unknown", Reason: "", Message: "0/0 ready, 0 running, 0 error(s)"},
+	}, got)
+}
+
+func Test_recordStepEvent(t *testing.T) {
+	tests := []struct {
+		it    string
+		prev  v1.StepState
+		state v1.StepState
+		want  []string
+	}{
+		{
+			it:    "should record a step start",
+			state: v1.StateRunning,
+			want:  []string{"Normal InfraRunning msg"},
+		},
+		{
+			it:    "should not record a progress update",
+			prev:  v1.StateRunning,
+			state: v1.StateRunning,
+		},
+		{
+			it:    "should record a step end",
+			prev:  v1.StateRunning,
+			state: v1.StateReady,
+			want:  []string{"Normal InfraReady msg"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			rec := record.NewFakeRecorder(10)
+
+			recordStepEvent(rec, &v1.Environment{}, "Infra", tst.prev, &step.Metaa{State: tst.state, Msg: "msg"})
+
+			close(rec.Events)
+			var got []string
+			for e := range rec.Events {
+				got = append(got, e)
+			}
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/events"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"sort"
+)
+
+// PlannedSteps returns the sorted names of the steps in status that are not Ready.
+func plannedSteps(status v1.EnvironmentStatus) []string {
+	var r []string
+	for n, s := range status.Steps {
+		if s.State != v1.StateReady {
+			r = append(r, n)
+		}
+	}
+	sort.Strings(r)
+	return r
+}
+
+// NewlyPlanned returns true if after contains steps that are not in before.
+func newlyPlanned(before, after []string) bool {
+	m := make(map[string]struct{}, len(before))
+	for _, n := range before {
+		m[n] = struct{}{}
+	}
+	for _, n := range after {
+		if _, ok := m[n]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// StepTransition returns the event data of step name changing from prev to meta state.
+func stepTransition(cr *v1.Environment, name string, prev v1.StepState, meta step.Meta) events.StepTransition {
+	r := events.StepTransition{
+		Namespace:     cr.Namespace,
+		Environment:   cr.Name,
+		Step:          name,
+		State:         meta.GetState(),
+		PreviousState: prev,
+		Message:       meta.GetMsg(),
+		Hash:          meta.GetHash(),
+	}
+	if c, ok := meta.(step.Counter); ok {
+		r.Added, r.Changed, r.Deleted = c.GetCounts()
+	}
+	return r
+}
+
+// EmitPlan emits a plan event when steps are planned that weren't planned before.
+func (r *EnvironmentReconciler) emitPlan(ctx context.Context, cr *v1.Environment, before []string) {
+	after := plannedSteps(cr.Status)
+	if !newlyPlanned(before, after) {
+		return
+	}
+	r.Events.Emit(ctx, events.TypePlan, events.EnvironmentSource(cr.Namespace, cr.Name), "", events.PlanSummary{
+		Namespace:   cr.Namespace,
+		Environment: cr.Name,
+		Generation:  cr.Generation,
+		Steps:       after,
+	})
+}
+
+// EmitStep emits the events for step name changing from prev to ss.
+func (r *EnvironmentReconciler) emitStep(ctx context.Context, cr *v1.Environment, name string, prev v1.StepState, meta step.Meta, ss v1.StepStatus) {
+	if prev == ss.State {
+		// progress update
+		return
+	}
+	src := events.EnvironmentSource(cr.Namespace, cr.Name)
+	r.Events.Emit(ctx, events.TypeStep, src, name, stepTransition(cr, name, prev, meta))
+
+	if ss.State == v1.StateReady && ss.Source != nil {
+		r.Events.Emit(ctx, events.TypeSourceApplied, src, name, events.SourceApplied{
+			Namespace:   cr.Namespace,
+			Environment: cr.Name,
+			Step:        name,
+			Generation:  ss.Generation,
+			Source:      *ss.Source,
+		})
+	}
+}
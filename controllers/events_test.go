@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/events"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func Test_newlyPlanned(t *testing.T) {
+	status := v1.EnvironmentStatus{Steps: map[string]v1.StepStatus{
+		"Infra":      {State: v1.StateReady},
+		"Addonsxyz":  {State: ""},
+		"Kubectlxyz": {State: v1.StateRunning},
+	}}
+	planned := plannedSteps(status)
+	assert.Equal(t, []string{"Addonsxyz", "Kubectlxyz"}, planned)
+
+	tests := []struct {
+		it     string
+		before []string
+		want   bool
+	}{
+		{
+			it:     "should return false when no steps are added to the plan",
+			before: []string{"Addonsxyz", "Infra", "Kubectlxyz"},
+			want:   false,
+		},
+		{
+			it:     "should return true when steps are added to the plan",
+			before: []string{"Kubectlxyz"},
+			want:   true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			assert.Equal(t, tst.want, newlyPlanned(tst.before, planned))
+		})
+	}
+}
+
+func Test_stepTransition(t *testing.T) {
+	cr := &v1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "dev"}}
+	meta := &step.InfraStep{Metaa: step.Metaa{State: v1.StateReady, Msg: "done", Hash: "123"}, Added: 1, Changed: 2, Deleted: 3}
+
+	got := stepTransition(cr, "Infra", v1.StateRunning, meta)
+
+	assert.Equal(t, events.StepTransition{
+		Namespace:     "default",
+		Environment:   "dev",
+		Step:          "Infra",
+		State:         v1.StateReady,
+		PreviousState: v1.StateRunning,
+		Message:       "done",
+		Hash:          "123",
+		Added:         1,
+		Changed:       2,
+		Deleted:       3,
+	}, got)
+}
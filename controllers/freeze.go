@@ -0,0 +1,72 @@
+package controllers
+
+import (
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"strings"
+	"time"
+)
+
+// FreezeCondition returns the Frozen condition of env given freezes at time now.
+// When frozen the time until the first freeze expires is returned (0 if the freezes do not expire).
+func freezeCondition(freezes []v1.Freeze, env *v1.Environment, now time.Time) (v1.EnvironmentCondition, time.Duration, error) {
+	overrides := map[string]bool{}
+	for _, n := range strings.Split(env.Annotations[v1.FreezeOverrideAnnotation], ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			overrides[n] = true
+		}
+	}
+
+	var frozen, overridden []string
+	var expires time.Duration
+	for _, f := range freezes {
+		if f.Spec.Expires != nil && !now.Before(f.Spec.Expires.Time) {
+			// expired
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(f.Spec.Selector)
+		if err != nil {
+			return v1.EnvironmentCondition{}, 0, fmt.Errorf("freeze %s: selector: %w", f.Name, err)
+		}
+		if f.Spec.Selector == nil {
+			selector = labels.Everything()
+		}
+		if !selector.Matches(labels.Set(env.Labels)) {
+			continue
+		}
+
+		if overrides[f.Name] {
+			overridden = append(overridden, f.Name)
+			continue
+		}
+
+		frozen = append(frozen, fmt.Sprintf("%s: %s", f.Name, f.Spec.Reason))
+		if f.Spec.Expires != nil {
+			if d := f.Spec.Expires.Sub(now); expires == 0 || d < expires {
+				expires = d
+			}
+		}
+	}
+
+	c := v1.EnvironmentCondition{
+		Type:   v1.ConditionFrozen,
+		Status: metav1.ConditionFalse,
+		Reason: v1.ReasonNotFrozen,
+	}
+	switch {
+	case len(frozen) > 0:
+		c.Status = metav1.ConditionTrue
+		c.Reason = v1.ReasonFrozen
+		c.Message = strings.Join(frozen, ", ")
+	case len(overridden) > 0:
+		c.Reason = v1.ReasonOverridden
+		c.Message = "overridden " + strings.Join(overridden, ", ")
+	default:
+		expires = 0
+	}
+
+	return c, expires, nil
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func Test_freezeCondition(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	freeze := func(name string, selector map[string]string, expires time.Duration) v1.Freeze {
+		f := v1.Freeze{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1.FreezeSpec{Reason: "incident"},
+		}
+		if selector != nil {
+			f.Spec.Selector = &metav1.LabelSelector{MatchLabels: selector}
+		}
+		if expires != 0 {
+			f.Spec.Expires = &metav1.Time{Time: now.Add(expires)}
+		}
+		return f
+	}
+	env := func(labels, annotations map[string]string) *v1.Environment {
+		return &v1.Environment{ObjectMeta: metav1.ObjectMeta{Labels: labels, Annotations: annotations}}
+	}
+
+	tests := []struct {
+		it          string
+		freezes     []v1.Freeze
+		env         *v1.Environment
+		wantStatus  metav1.ConditionStatus
+		wantReason  v1.EnvironmentConditionReason
+		wantMessage string
+		wantExpires time.Duration
+	}{
+		{
+			it:         "should not be frozen without freezes",
+			env:        env(nil, nil),
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1.ReasonNotFrozen,
+		},
+		{
+			it:          "should be frozen by a freeze without selector",
+			freezes:     []v1.Freeze{freeze("all", nil, 0)},
+			env:         env(map[string]string{"env": "prod"}, nil),
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  v1.ReasonFrozen,
+			wantMessage: "all: incident",
+		},
+		{
+			it: "should only be frozen by freezes that select the environment",
+			freezes: []v1.Freeze{
+				freeze("test", map[string]string{"env": "test"}, 0),
+				freeze("prod", map[string]string{"env": "prod"}, time.Hour),
+			},
+			env:         env(map[string]string{"env": "prod"}, nil),
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  v1.ReasonFrozen,
+			wantMessage: "prod: incident",
+			wantExpires: time.Hour,
+		},
+		{
+			it:         "should ignore expired freezes",
+			freezes:    []v1.Freeze{freeze("all", nil, -time.Second)},
+			env:        env(nil, nil),
+			wantStatus: metav1.ConditionFalse,
+			wantReason: v1.ReasonNotFrozen,
+		},
+		{
+			it:          "should not be frozen when the freeze is overridden",
+			freezes:     []v1.Freeze{freeze("all", nil, time.Hour)},
+			env:         env(nil, map[string]string{v1.FreezeOverrideAnnotation: "other, all"}),
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  v1.ReasonOverridden,
+			wantMessage: "overridden all",
+		},
+		{
+			it:          "should be frozen when only some freezes are overridden",
+			freezes:     []v1.Freeze{freeze("all", nil, 0), freeze("release", nil, 0)},
+			env:         env(nil, map[string]string{v1.FreezeOverrideAnnotation: "all"}),
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  v1.ReasonFrozen,
+			wantMessage: "release: incident",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got, expires, err := freezeCondition(tst.freezes, tst.env, now)
+			assert.NoError(t, err)
+			assert.Equal(t, v1.ConditionFrozen, got.Type)
+			assert.Equal(t, tst.wantStatus, got.Status)
+			assert.Equal(t, tst.wantReason, got.Reason)
+			assert.Equal(t, tst.wantMessage, got.Message)
+			assert.Equal(t, tst.wantExpires, expires)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/step"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// MaxStepHistory is the max number of runs kept per step.
+const maxStepHistory = 10
+
+// RecordRun adds the state change of step name from prev to meta state to the history in status.
+// A run starts when a step becomes Running and ends when it reaches a final state.
+// LogID (optional) identifies the logs of a run that starts.
+func recordRun(status *v1.EnvironmentStatus, name string, prev v1.StepState, meta step.Meta, src *v1.StepSource, logID string, now time.Time) {
+	if status.History == nil {
+		status.History = make(map[string]v1.StepRuns)
+	}
+	runs := status.History[name]
+
+	state := meta.GetState()
+	t := metav1.Time{Time: now}
+
+	// start a new run unless the last run is still ongoing.
+	last := len(runs) - 1
+	if last < 0 || prev != v1.StateRunning || runs[last].End != nil {
+		runs = append(runs, v1.StepRun{Start: t, Source: src, LogID: logID})
+		last = len(runs) - 1
+	}
+
+	r := &runs[last]
+	r.State = state
+	r.Message = meta.GetMsg()
+	if step.IsStateFinal(state) {
+		r.End = &t
+		if c, ok := meta.(step.Counter); ok {
+			r.Added, r.Changed, r.Deleted = c.GetCounts()
+		}
+	}
+
+	if len(runs) > maxStepHistory {
+		runs = runs[len(runs)-maxStepHistory:]
+	}
+	status.History[name] = runs
+}
+
+// PruneHistory removes the history of steps that are not in possible, for example the steps of a removed cluster.
+// The history of possible steps is kept, also when the step is removed from status.Steps by a reset.
+func pruneHistory(status *v1.EnvironmentStatus, possible map[string]struct{}) {
+	for n := range status.History {
+		if _, ok := possible[n]; !ok {
+			delete(status.History, n)
+		}
+	}
+}
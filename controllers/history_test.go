@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func Test_recordRun(t *testing.T) {
+	t0 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	src := &v1.StepSource{URL: "repo", Commit: "abc"}
+	const logID = "20060102t150605z"
+	infra := func(state v1.StepState, msg string) step.Meta {
+		return &step.InfraStep{Metaa: step.Metaa{State: state, Msg: msg}, Added: 1, Changed: 2, Deleted: 3}
+	}
+
+	tests := []struct {
+		it      string
+		history []v1.StepRun
+		prev    v1.StepState
+		meta    step.Meta
+		want    []v1.StepRun
+	}{
+		{
+			it:   "should start a run",
+			meta: infra(v1.StateRunning, "terraform plan"),
+			want: []v1.StepRun{
+				{Start: metav1.Time{Time: t1}, State: v1.StateRunning, Message: "terraform plan", Source: src, LogID: logID},
+			},
+		},
+		{
+			it:      "should end a run with counts",
+			history: []v1.StepRun{{Start: metav1.Time{Time: t0}, State: v1.StateRunning, Source: src}},
+			prev:    v1.StateRunning,
+			meta:    infra(v1.StateReady, "done"),
+			want: []v1.StepRun{
+				{Start: metav1.Time{Time: t0}, End: &metav1.Time{Time: t1}, State: v1.StateReady, Message: "done", Source: src, Added: 1, Changed: 2, Deleted: 3},
+			},
+		},
+		{
+			it:      "should start a new run after a run has ended",
+			history: []v1.StepRun{{Start: metav1.Time{Time: t0}, End: &metav1.Time{Time: t0}, State: v1.StateError}},
+			prev:    "",
+			meta:    infra(v1.StateRunning, "again"),
+			want: []v1.StepRun{
+				{Start: metav1.Time{Time: t0}, End: &metav1.Time{Time: t0}, State: v1.StateError},
+				{Start: metav1.Time{Time: t1}, State: v1.StateRunning, Message: "again", Source: src, LogID: logID},
+			},
+		},
+		{
+			it:   "should record a run that doesn't report counts",
+			meta: &step.Metaa{State: v1.StateError, Msg: "failed"},
+			want: []v1.StepRun{
+				{Start: metav1.Time{Time: t1}, End: &metav1.Time{Time: t1}, State: v1.StateError, Message: "failed", Source: src, LogID: logID},
+			},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			status := &v1.EnvironmentStatus{}
+			if tst.history != nil {
+				status.History = map[string]v1.StepRuns{"Infra": tst.history}
+			}
+
+			recordRun(status, "Infra", tst.prev, tst.meta, src, logID, t1)
+
+			assert.Equal(t, v1.StepRuns(tst.want), status.History["Infra"])
+		})
+	}
+}
+
+func Test_recordRun_bounded(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	status := &v1.EnvironmentStatus{}
+	for i := 0; i < maxStepHistory+5; i++ {
+		recordRun(status, "Infra", "", &step.Metaa{State: v1.StateRunning}, nil, "", now.Add(time.Duration(i)*time.Minute))
+		recordRun(status, "Infra", v1.StateRunning, &step.Metaa{State: v1.StateReady}, nil, "", now.Add(time.Duration(i)*time.Minute))
+	}
+
+	runs := status.History["Infra"]
+	if assert.Len(t, runs, maxStepHistory) {
+		assert.Equal(t, now.Add(time.Duration(maxStepHistory+4)*time.Minute), runs[maxStepHistory-1].Start.Time, "newest last")
+	}
+}
+
+func Test_pruneHistory(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	run := v1.StepRuns{{Start: metav1.Time{Time: now}, End: &metav1.Time{Time: now}, State: v1.StateReady}}
+	status := &v1.EnvironmentStatus{
+		Steps: map[string]v1.StepStatus{
+			"AKSPoolxyz": {State: v1.StateReady},
+		},
+		History: map[string]v1.StepRuns{
+			"Infra":      run,
+			"AKSPoolxyz": run,
+			"AKSPoolold": run,
+		},
+	}
+	possible := map[string]struct{}{"Infra": {}, "Destroy": {}, "AKSPoolxyz": {}}
+
+	pruneHistory(status, possible)
+
+	assert.Contains(t, status.History, "Infra", "history of a reset step is kept")
+	assert.Contains(t, status.History, "AKSPoolxyz")
+	assert.NotContains(t, status.History, "AKSPoolold", "history of a step that is no longer possible is pruned")
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/notify"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"strings"
+)
+
+// NotificationEvent returns the event to notify when a step changes from prev to state with msg.
+// Returns false if the change isn't notified.
+func notificationEvent(prev, state v1.StepState, msg string) (v1.NotificationEvent, bool) {
+	switch state {
+	case v1.StateRunning:
+		if prev == v1.StateRunning {
+			// progress update
+			return "", false
+		}
+		return v1.EventStepStarted, true
+	case v1.StateReady:
+		return v1.EventStepSucceeded, true
+	case v1.StateError:
+		if strings.HasPrefix(msg, step.BudgetExceededMsg) {
+			return v1.EventBudgetExceeded, true
+		}
+		return v1.EventStepFailed, true
+	}
+	return "", false
+}
+
+// Notify sends the change of step name from prev to the meta state to the notification endpoints of cr and the global
+// endpoints.
+// Errors are logged.
+func (r *EnvironmentReconciler) notify(ctx context.Context, cr *v1.Environment, name string, prev v1.StepState, meta step.Meta) {
+	if r.Notifier == nil {
+		return
+	}
+	typ, ok := notificationEvent(prev, meta.GetState(), meta.GetMsg())
+	if !ok {
+		return
+	}
+
+	log := logr.FromContext(ctx)
+
+	var err error
+	specs := append([]v1.NotificationSpec{}, r.Notifications...)
+	for _, n := range cr.Spec.Notifications {
+		err = vaultValue(&n.URL, r.cloudFor(cr), "notifications.url", err)
+		specs = append(specs, n)
+	}
+	if err != nil {
+		log.Error(err, "notify")
+		return
+	}
+
+	err = r.Notifier.Notify(ctx, specs, notify.Event{
+		Type:        typ,
+		Namespace:   cr.Namespace,
+		Environment: cr.Name,
+		Step:        name,
+		State:       meta.GetState(),
+		Message:     meta.GetMsg(),
+		Time:        timeNow(),
+	})
+	if err != nil {
+		log.Error(err, "notify")
+	}
+}
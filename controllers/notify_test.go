@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_notificationEvent(t *testing.T) {
+	tests := []struct {
+		it     string
+		prev   v1.StepState
+		state  v1.StepState
+		msg    string
+		want   v1.NotificationEvent
+		wantOK bool
+	}{
+		{
+			it:     "should notify a step start",
+			prev:   "",
+			state:  v1.StateRunning,
+			want:   v1.EventStepStarted,
+			wantOK: true,
+		},
+		{
+			it:    "should not notify progress updates",
+			prev:  v1.StateRunning,
+			state: v1.StateRunning,
+		},
+		{
+			it:     "should notify a step success",
+			prev:   v1.StateRunning,
+			state:  v1.StateReady,
+			want:   v1.EventStepSucceeded,
+			wantOK: true,
+		},
+		{
+			it:     "should notify a step failure",
+			prev:   v1.StateRunning,
+			state:  v1.StateError,
+			msg:    "terraform plan error",
+			want:   v1.EventStepFailed,
+			wantOK: true,
+		},
+		{
+			it:     "should notify an exceeded budget",
+			prev:   v1.StateRunning,
+			state:  v1.StateError,
+			msg:    "plan limits exceeded: delete 1 > 0",
+			want:   v1.EventBudgetExceeded,
+			wantOK: true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got, ok := notificationEvent(tst.prev, tst.state, tst.msg)
+			assert.Equal(t, tst.wantOK, ok)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
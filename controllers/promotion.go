@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxPromotionHistory is the max number of records kept in EnvironmentPromotion status.history.
+const maxPromotionHistory = 10
+
+// Promotion is the outcome of evaluating an EnvironmentPromotion.
+type promotion struct {
+	// Commits maps the source URLs to the commits To must be pinned to.
+	// Commits is empty when there is nothing to promote (yet).
+	commits map[string]string
+	// Pending is the revision that waits for soak time or approval.
+	pending string
+	// Message explains the outcome.
+	message string
+	// Wait is the time until the soak time has passed.
+	wait time.Duration
+}
+
+// EvaluatePromotion decides which source revisions of from are promoted to to at time now.
+func evaluatePromotion(spec v1.EnvironmentPromotionSpec, from, to *v1.Environment, now time.Time) promotion {
+	ready := getCondition(from.Status.Conditions, v1.ConditionReady)
+	if ready == nil || ready.Reason != v1.ReasonReady {
+		return promotion{message: fmt.Sprintf("waiting for %s to be Ready", from.Name)}
+	}
+
+	commits := stepCommits(from.Status.Steps)
+
+	// Only promote what's used and not yet pinned by to.
+	refs := sourceRefs(to.Spec)
+	for u, c := range commits {
+		if ref, ok := refs[u]; !ok || ref == c {
+			delete(commits, u)
+		}
+	}
+	if len(commits) == 0 {
+		return promotion{message: "up to date"}
+	}
+
+	revision := revisionOf(commits)
+
+	if d := ready.LastTransitionTime.Add(spec.SoakTime.Duration).Sub(now); d > 0 {
+		return promotion{
+			pending: revision,
+			message: fmt.Sprintf("soaking until %s", now.Add(d).UTC().Format(time.RFC3339)),
+			wait:    d,
+		}
+	}
+
+	if spec.RequireApproval && spec.ApprovedRevision != revision {
+		return promotion{
+			pending: revision,
+			message: fmt.Sprintf("waiting for approval of revision %s", revision),
+		}
+	}
+
+	return promotion{
+		commits: commits,
+		message: fmt.Sprintf("promoted revision %s", revision),
+	}
+}
+
+// StepCommits returns the git commits applied by steps, keyed by source URL.
+// When steps have applied different commits of the same URL, for example during a partial rollout, the commit of the
+// step that became Ready most recently is used; steps that are Ready take precedence over steps that are not.
+func stepCommits(steps map[string]v1.StepStatus) map[string]string {
+	type candidate struct {
+		commit string
+		ready  bool
+		time   time.Time
+	}
+	// newer returns true when a is preferred over b.
+	newer := func(a, b candidate) bool {
+		if a.ready != b.ready {
+			return a.ready
+		}
+		if !a.time.Equal(b.time) {
+			return a.time.After(b.time)
+		}
+		// make the choice deterministic.
+		return a.commit > b.commit
+	}
+
+	cs := make(map[string]candidate)
+	for _, st := range steps {
+		if st.Source == nil || st.Source.Commit == "" {
+			continue
+		}
+		c := candidate{commit: st.Source.Commit, ready: st.State == v1.StateReady, time: st.LastTransitionTime.Time}
+		if prev, ok := cs[st.Source.URL]; !ok || newer(c, prev) {
+			cs[st.Source.URL] = c
+		}
+	}
+
+	r := make(map[string]string, len(cs))
+	for u, c := range cs {
+		r[u] = c.commit
+	}
+	return r
+}
+
+// SourceRefs returns the refs of the git sources in spec, keyed by URL.
+func sourceRefs(spec v1.EnvironmentSpec) map[string]string {
+	r := make(map[string]string)
+	for _, s := range gitSources(&spec) {
+		r[s.URL] = s.Ref
+	}
+	return r
+}
+
+// PinRefs sets the ref of the git sources in spec to the commit for their URL.
+// Returns true if spec is changed.
+func pinRefs(spec *v1.EnvironmentSpec, commits map[string]string) bool {
+	var changed bool
+	for _, s := range gitSources(spec) {
+		if c, ok := commits[s.URL]; ok && s.Ref != c {
+			s.Ref = c
+			changed = true
+		}
+	}
+	return changed
+}
+
+// GitSources returns pointers to the git SourceSpecs in spec that have an URL.
+func gitSources(spec *v1.EnvironmentSpec) []*v1.SourceSpec {
+	all := []*v1.SourceSpec{&spec.Infra.Source, &spec.Defaults.Addons.Source}
+	for i := range spec.Clusters {
+		all = append(all, &spec.Clusters[i].Addons.Source)
+	}
+
+	var r []*v1.SourceSpec
+	for _, s := range all {
+		if s.URL == "" || (s.Type != "" && s.Type != v1.SourceTypeGIT) {
+			continue
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+// RevisionOf returns the commits as a single revision string.
+func revisionOf(commits map[string]string) string {
+	var cs []string
+	for _, c := range commits {
+		cs = append(cs, c)
+	}
+	sort.Strings(cs)
+	return strings.Join(cs, ",")
+}
+
+// AddPromotionHistory prepends records for commits to history and limits its length.
+func addPromotionHistory(history []v1.PromotionRecord, commits map[string]string, now time.Time) []v1.PromotionRecord {
+	var urls []string
+	for u := range commits {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+
+	var r []v1.PromotionRecord
+	for _, u := range urls {
+		r = append(r, v1.PromotionRecord{URL: u, Commit: commits[u], Time: metav1.Time{Time: now}})
+	}
+	r = append(r, history...)
+	if len(r) > maxPromotionHistory {
+		r = r[:maxPromotionHistory]
+	}
+	return r
+}
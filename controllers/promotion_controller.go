@@ -0,0 +1,129 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	ctrlsource "sigs.k8s.io/controller-runtime/pkg/source"
+	"time"
+
+	v1 "github.com/mmlt/environment-operator/api/v1"
+)
+
+// PromotionReconciler reconciles an EnvironmentPromotion object.
+type PromotionReconciler struct {
+	client.Client
+	Recorder record.EventRecorder
+
+	// LabelSet are the labels that resources must have to be handled by this reconciler.
+	// An empty set matches all resources.
+	LabelSet labels.Set
+}
+
+// +kubebuilder:rbac:groups=clusterops.mmlt.nl,resources=environmentpromotions,verbs=get;list;watch
+// +kubebuilder:rbac:groups=clusterops.mmlt.nl,resources=environmentpromotions/status,verbs=get;update;patch
+
+// Reconcile takes an EnvironmentPromotion and pins the source refs of the To Environment to the commits the From
+// Environment is running.
+func (r *PromotionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	requeueSoon := ctrl.Result{RequeueAfter: 10 * time.Second}
+
+	log := logr.FromContext(ctx).WithName("Promotion")
+
+	cr := &v1.EnvironmentPromotion{}
+	if err := r.Get(ctx, req.NamespacedName, cr); err != nil {
+		log.V(2).Info("unable to get kind EnvironmentPromotion (retried)", "error", err)
+		return requeueSoon, ignoreNotFound(err)
+	}
+
+	from := &v1.Environment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Spec.From}, from); err != nil {
+		return requeueSoon, fmt.Errorf("get from: %w", err)
+	}
+	to := &v1.Environment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: cr.Namespace, Name: cr.Spec.To}, to); err != nil {
+		return requeueSoon, fmt.Errorf("get to: %w", err)
+	}
+
+	p := evaluatePromotion(cr.Spec, from, to, timeNow())
+
+	if len(p.commits) > 0 && pinRefs(&to.Spec, p.commits) {
+		if err := r.Update(ctx, to); err != nil {
+			return requeueSoon, fmt.Errorf("update to: %w", err)
+		}
+		cr.Status.History = addPromotionHistory(cr.Status.History, p.commits, timeNow())
+		r.Recorder.Event(cr, "Normal", "Promoted", p.message)
+		r.Recorder.Event(to, "Normal", "Promoted", fmt.Sprintf("%s from %s", p.message, from.Name))
+		log.Info("promoted", "from", from.Name, "to", to.Name, "commits", p.commits)
+	}
+
+	cr.Status.Pending = p.pending
+	cr.Status.Message = p.message
+	if err := r.Status().Update(ctx, cr); err != nil {
+		return ctrl.Result{Requeue: true}, fmt.Errorf("save status: %w", err)
+	}
+
+	if p.wait > 0 {
+		return ctrl.Result{RequeueAfter: p.wait}, nil
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager initializes the receiver and adds it to mgr.
+func (r *PromotionReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	selector := r.LabelSet.AsSelector()
+	lp := predicate.NewPredicateFuncs(
+		func(o client.Object) bool {
+			return selector.Matches(labels.Set(o.GetLabels()))
+		},
+	)
+
+	// An Environment change reconciles the promotions from that Environment.
+	environmentToPromotions := handler.EnqueueRequestsFromMapFunc(
+		func(o client.Object) []reconcile.Request {
+			promos := &v1.EnvironmentPromotionList{}
+			err := r.List(context.Background(), promos, client.InNamespace(o.GetNamespace()), client.MatchingLabels(r.LabelSet))
+			if err != nil {
+				return nil
+			}
+			var reqs []reconcile.Request
+			for _, p := range promos.Items {
+				if p.Spec.From != o.GetName() {
+					continue
+				}
+				reqs = append(reqs, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: p.Namespace, Name: p.Name}})
+			}
+			return reqs
+		},
+	)
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1.EnvironmentPromotion{}, builder.WithPredicates(lp)).
+		Watches(&ctrlsource.Kind{Type: &v1.Environment{}}, environmentToPromotions).
+		Complete(r)
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func Test_evaluatePromotion(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	const (
+		repo = "https://example.com/infra.git"
+		sha1 = "1111111111111111111111111111111111111111"
+		sha2 = "2222222222222222222222222222222222222222"
+	)
+	from := func(reason v1.EnvironmentConditionReason, readyAgo time.Duration, commits ...string) *v1.Environment {
+		e := &v1.Environment{ObjectMeta: metav1.ObjectMeta{Name: "dev"}}
+		e.Status.Conditions = []v1.EnvironmentCondition{
+			{Type: v1.ConditionReady, Reason: reason, LastTransitionTime: metav1.Time{Time: now.Add(-readyAgo)}},
+		}
+		e.Status.Steps = map[string]v1.StepStatus{}
+		for i, c := range commits {
+			e.Status.Steps[string(rune('a'+i))] = v1.StepStatus{State: v1.StateReady, Source: &v1.StepSource{URL: repo, Commit: c}}
+		}
+		return e
+	}
+	to := func(ref string) *v1.Environment {
+		e := &v1.Environment{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+		e.Spec.Infra.Source = v1.SourceSpec{URL: repo, Ref: ref}
+		return e
+	}
+
+	tests := []struct {
+		it       string
+		spec     v1.EnvironmentPromotionSpec
+		from, to *v1.Environment
+		want     promotion
+	}{
+		{
+			it:   "should wait when from is not Ready",
+			from: from(v1.ReasonRunning, time.Hour, sha1),
+			to:   to("master"),
+			want: promotion{message: "waiting for dev to be Ready"},
+		},
+		{
+			it:   "should promote the commit from is running",
+			from: from(v1.ReasonReady, time.Hour, sha1, sha1),
+			to:   to("master"),
+			want: promotion{commits: map[string]string{repo: sha1}, message: "promoted revision " + sha1},
+		},
+		{
+			it:   "should do nothing when to is already pinned",
+			from: from(v1.ReasonReady, time.Hour, sha1),
+			to:   to(sha1),
+			want: promotion{message: "up to date"},
+		},
+		{
+			it:   "should soak",
+			spec: v1.EnvironmentPromotionSpec{SoakTime: metav1.Duration{Duration: 2 * time.Hour}},
+			from: from(v1.ReasonReady, time.Hour, sha1),
+			to:   to("master"),
+			want: promotion{pending: sha1, message: "soaking until 2006-01-02T16:04:05Z", wait: time.Hour},
+		},
+		{
+			it:   "should wait for approval",
+			spec: v1.EnvironmentPromotionSpec{RequireApproval: true, ApprovedRevision: sha2},
+			from: from(v1.ReasonReady, time.Hour, sha1),
+			to:   to("master"),
+			want: promotion{pending: sha1, message: "waiting for approval of revision " + sha1},
+		},
+		{
+			it:   "should promote an approved revision",
+			spec: v1.EnvironmentPromotionSpec{RequireApproval: true, ApprovedRevision: sha1},
+			from: from(v1.ReasonReady, time.Hour, sha1),
+			to:   to("master"),
+			want: promotion{commits: map[string]string{repo: sha1}, message: "promoted revision " + sha1},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := evaluatePromotion(tst.spec, tst.from, tst.to, now)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
+
+func Test_stepCommits(t *testing.T) {
+	t0 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	const (
+		repo  = "https://example.com/envs.git"
+		infra = "https://example.com/infra.git"
+		sha1  = "1111111111111111111111111111111111111111"
+		sha2  = "2222222222222222222222222222222222222222"
+	)
+	stp := func(state v1.StepState, url, commit string, t time.Time) v1.StepStatus {
+		return v1.StepStatus{State: state, LastTransitionTime: metav1.Time{Time: t}, Source: &v1.StepSource{URL: url, Commit: commit}}
+	}
+
+	tests := []struct {
+		it    string
+		steps map[string]v1.StepStatus
+		want  map[string]string
+	}{
+		{
+			it: "should return the commit per URL",
+			steps: map[string]v1.StepStatus{
+				"Infra":   stp(v1.StateReady, infra, sha1, t0),
+				"Addonsa": stp(v1.StateReady, repo, sha2, t0),
+				"Addonsb": stp(v1.StateReady, repo, sha2, t0),
+			},
+			want: map[string]string{infra: sha1, repo: sha2},
+		},
+		{
+			it: "should ignore steps without source",
+			steps: map[string]v1.StepStatus{
+				"Infra":        stp(v1.StateReady, infra, sha1, t0),
+				"RolloutGate1": {State: v1.StateReady},
+			},
+			want: map[string]string{infra: sha1},
+		},
+		{
+			it: "should use the most recent Ready step during a partial rollout",
+			steps: map[string]v1.StepStatus{
+				"Addonsa": stp(v1.StateReady, repo, sha2, t1),
+				"Addonsb": stp(v1.StateReady, repo, sha1, t0),
+			},
+			want: map[string]string{repo: sha2},
+		},
+		{
+			it: "should prefer Ready steps over failed steps",
+			steps: map[string]v1.StepStatus{
+				"Addonsa": stp(v1.StateReady, repo, sha1, t0),
+				"Addonsb": stp(v1.StateError, repo, sha2, t1),
+			},
+			want: map[string]string{repo: sha1},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			assert.Equal(t, tst.want, stepCommits(tst.steps))
+		})
+	}
+}
+
+func Test_refreshStepSources(t *testing.T) {
+	const (
+		repo = "https://example.com/envs.git"
+		sha1 = "1111111111111111111111111111111111111111"
+		sha2 = "2222222222222222222222222222222222222222"
+	)
+	newStep := func(typ step.Type, clusterName, hash string) step.Step {
+		return &step.AddonStep{Metaa: step.Metaa{ID: step.ID{Type: typ, ClusterName: clusterName}, Hash: hash}}
+	}
+	src := func(area, commit string) *v1.StepSource {
+		return &v1.StepSource{URL: repo, Commit: commit, Area: area, AreaHash: area + "-hash"}
+	}
+	// infra and addons share a repo, a new commit only changed the infra area and Infra has re-run.
+	status := &v1.EnvironmentStatus{Steps: map[string]v1.StepStatus{
+		"Infra":   {State: v1.StateReady, Hash: "i2", Source: src("infra", sha2)},
+		"Addonsa": {State: v1.StateReady, Hash: "a1", Source: src("addons", sha1)},
+		"Addonsb": {State: v1.StateRunning, Hash: "b1", Source: src("addons", sha1)},
+	}}
+	plan := []step.Step{
+		newStep(step.TypeInfra, "", "i2"),
+		newStep(step.TypeAddons, "a", "a1"),
+		newStep(step.TypeAddons, "b", "b2"),
+	}
+
+	refreshStepSources(status, plan, func(id step.ID) *v1.StepSource {
+		if id.Type == step.TypeAddons {
+			return src("addons", sha2)
+		}
+		return src("infra", sha2)
+	})
+
+	assert.Equal(t, sha2, status.Steps["Addonsa"].Source.Commit, "step with unchanged area is at the new commit")
+	assert.Equal(t, sha1, status.Steps["Addonsb"].Source.Commit, "step that needs to run keeps the applied commit")
+	delete(status.Steps, "Addonsb")
+	assert.Equal(t, map[string]string{repo: sha2}, stepCommits(status.Steps))
+}
+
+func Test_pinRefs(t *testing.T) {
+	const sha = "1111111111111111111111111111111111111111"
+	spec := v1.EnvironmentSpec{
+		Infra:    v1.InfraSpec{Source: v1.SourceSpec{URL: "infra", Ref: "master"}},
+		Defaults: v1.ClusterSpec{Addons: v1.ClusterAddonSpec{Source: v1.SourceSpec{URL: "addons", Ref: "master"}}},
+		Clusters: []v1.ClusterSpec{
+			{Name: "one"},
+			{Name: "two", Addons: v1.ClusterAddonSpec{Source: v1.SourceSpec{Type: v1.SourceTypeLocal, URL: "addons"}}},
+		},
+	}
+
+	changed := pinRefs(&spec, map[string]string{"addons": sha})
+
+	assert.True(t, changed)
+	assert.Equal(t, "master", spec.Infra.Source.Ref, "other URL")
+	assert.Equal(t, sha, spec.Defaults.Addons.Source.Ref, "defaults")
+	assert.Equal(t, "", spec.Clusters[0].Addons.Source.Ref, "inherits from defaults")
+	assert.Equal(t, "", spec.Clusters[1].Addons.Source.Ref, "local source")
+	assert.False(t, pinRefs(&spec, map[string]string{"addons": sha}), "already pinned")
+}
+
+func Test_addPromotionHistory(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	var h []v1.PromotionRecord
+	for i := 0; i < maxPromotionHistory+2; i++ {
+		h = addPromotionHistory(h, map[string]string{"repo": string(rune('a' + i))}, now)
+	}
+	assert.Len(t, h, maxPromotionHistory)
+	assert.Equal(t, string(rune('a'+maxPromotionHistory+1)), h[0].Commit, "newest first")
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/plan"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"strconv"
+	"time"
+)
+
+// PrepareGate sets the time the batch of a RolloutGate step has completed and the previous status of the gate.
+// Steps other than RolloutGate are left as-is.
+func prepareGate(status v1.EnvironmentStatus, stp step.Step) {
+	gate, ok := stp.(*step.RolloutGateStep)
+	if !ok {
+		return
+	}
+
+	var since time.Time
+	for _, n := range gate.Steps {
+		if t := status.Steps[n].LastTransitionTime.Time; t.After(since) {
+			since = t
+		}
+	}
+	if since.IsZero() {
+		since = timeNow()
+	}
+	gate.Since = since
+	gate.Previous = status.Steps[gate.GetID().ShortName()]
+}
+
+// GateWait returns the time a RolloutGate step needs to soak or wait for healthy clusters before it should be
+// executed again.
+// Zero is returned for other steps or when no waiting is needed.
+func gateWait(stp step.Step) time.Duration {
+	gate, ok := stp.(*step.RolloutGateStep)
+	if !ok {
+		return 0
+	}
+	return gate.Remaining
+}
+
+// RolloutStatus returns the progress of a batched rollout or nil if all clusters are rolled out in one batch.
+// The current batch is the first batch that has a cluster step or gate that isn't Ready.
+func rolloutStatus(status v1.EnvironmentStatus, cspec []v1.ClusterSpec, rollout v1.RolloutSpec) *v1.RolloutStatus {
+	batches := plan.Batches(rollout, cspec)
+	if len(batches) < 2 {
+		return nil
+	}
+
+	ready := func(shortName string) bool {
+		s, ok := status.Steps[shortName]
+		// steps that are not allowed to run are not in status.
+		return !ok || s.State == v1.StateReady
+	}
+
+	r := &v1.RolloutStatus{
+		Batches: int32(len(batches)),
+	}
+	for bi, batch := range batches {
+		done := true
+		for _, cl := range batch {
+			for _, t := range step.ClusterTypes {
+				if !ready(string(t) + cl) {
+					done = false
+				}
+			}
+		}
+		if bi < len(batches)-1 && !ready(string(step.TypeRolloutGate)+strconv.Itoa(bi+1)) {
+			done = false
+		}
+		if !done {
+			r.Batch = int32(bi + 1)
+			r.Clusters = batch
+			break
+		}
+	}
+
+	return r
+}
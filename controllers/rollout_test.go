@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_rolloutStatus(t *testing.T) {
+	cspec := []v1.ClusterSpec{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	rollout := v1.RolloutSpec{Canaries: []string{"a"}}
+	ready := v1.StepStatus{State: v1.StateReady}
+	running := v1.StepStatus{State: v1.StateRunning}
+
+	tests := []struct {
+		it      string
+		rollout v1.RolloutSpec
+		steps   map[string]v1.StepStatus
+		want    *v1.RolloutStatus
+	}{
+		{
+			it:   "should return nil when all clusters are in one batch",
+			want: nil,
+		},
+		{
+			it:      "should return the canary batch when a canary step is running",
+			rollout: rollout,
+			steps: map[string]v1.StepStatus{
+				"AKSPoola": ready, "AKSAddonPreflighta": running, "Addonsa": {},
+				"RolloutGate1": {},
+			},
+			want: &v1.RolloutStatus{Batch: 1, Batches: 2, Clusters: []string{"a"}},
+		},
+		{
+			it:      "should return the next batch when the gate is Ready",
+			rollout: rollout,
+			steps: map[string]v1.StepStatus{
+				"AKSPoola": ready, "AKSAddonPreflighta": ready, "Addonsa": ready,
+				"RolloutGate1": ready,
+				"AKSPoolb":     ready, "AKSPoolc": {},
+			},
+			want: &v1.RolloutStatus{Batch: 2, Batches: 2, Clusters: []string{"b", "c"}},
+		},
+		{
+			it:      "should return batch 0 when all batches are rolled out",
+			rollout: rollout,
+			steps: map[string]v1.StepStatus{
+				"AKSPoola": ready, "AKSAddonPreflighta": ready, "Addonsa": ready,
+				"RolloutGate1": ready,
+			},
+			want: &v1.RolloutStatus{Batch: 0, Batches: 2},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := rolloutStatus(v1.EnvironmentStatus{Steps: tst.steps}, cspec, tst.rollout)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
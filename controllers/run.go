@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/logsink"
+	"github.com/mmlt/environment-operator/pkg/step"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxRuns is the number of EnvironmentRuns kept per step when runRetention.maxRuns isn't set.
+const defaultMaxRuns = 10
+
+// NewEnvironmentRun returns the audit record of run of step name.
+func newEnvironmentRun(cr *v1.Environment, name string, run v1.StepRun, ss v1.StepStatus, values map[string]string) *v1.EnvironmentRun {
+	var end metav1.Time
+	if run.End != nil {
+		end = *run.End
+	}
+
+	return &v1.EnvironmentRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:    cr.Namespace,
+			GenerateName: strings.ToLower(cr.Name + "-" + name + "-"),
+			Labels: map[string]string{
+				v1.RunEnvironmentLabel: cr.Name,
+				v1.RunStepLabel:        name,
+			},
+		},
+		Spec: v1.EnvironmentRunSpec{
+			Environment: cr.Name,
+			Step:        name,
+			Hash:        ss.Hash,
+			Generation:  cr.Generation,
+			Source:      run.Source,
+			Values:      values,
+			State:       run.State,
+			Message:     run.Message,
+			Added:       run.Added,
+			Changed:     run.Changed,
+			Deleted:     run.Deleted,
+			LogID:       run.LogID,
+			Start:       run.Start,
+			End:         end,
+			Duration:    metav1.Duration{Duration: end.Sub(run.Start.Time)},
+		},
+	}
+}
+
+// RunValues returns the values applied by the step with id, vault references are not resolved.
+func runValues(spec v1.EnvironmentSpec, id step.ID) map[string]string {
+	if id.Type != step.TypeAddons {
+		return nil
+	}
+	cspec, err := flattenedClusterSpec(spec)
+	if err != nil {
+		return nil
+	}
+	for _, c := range cspec {
+		if c.Name == id.ClusterName {
+			return c.Addons.X
+		}
+	}
+	return nil
+}
+
+// RunsToPrune returns the runs that are not retained at time now.
+// Per step the newest maxRuns runs that are younger than maxAge are retained.
+func runsToPrune(runs []v1.EnvironmentRun, retention v1.RunRetentionSpec, now time.Time) []v1.EnvironmentRun {
+	max := int(retention.MaxRuns)
+	if max == 0 {
+		max = defaultMaxRuns
+	}
+
+	perStep := make(map[string][]v1.EnvironmentRun)
+	for _, r := range runs {
+		perStep[r.Spec.Step] = append(perStep[r.Spec.Step], r)
+	}
+
+	var r []v1.EnvironmentRun
+	for _, rs := range perStep {
+		// newest first
+		sort.Slice(rs, func(i, j int) bool {
+			return rs[i].Spec.End.After(rs[j].Spec.End.Time)
+		})
+		for i, run := range rs {
+			expired := retention.MaxAge.Duration > 0 && now.Sub(run.Spec.End.Time) > retention.MaxAge.Duration
+			if i >= max || expired {
+				r = append(r, run)
+			}
+		}
+	}
+
+	return r
+}
+
+// RecordEnvironmentRun creates an EnvironmentRun for the last run of step name and prunes the EnvironmentRuns of cr
+// (including their logs).
+func (r *EnvironmentReconciler) recordEnvironmentRun(ctx context.Context, cr *v1.Environment, id step.ID) error {
+	name := id.ShortName()
+	runs := cr.Status.History[name]
+	if len(runs) == 0 {
+		return nil
+	}
+
+	run := newEnvironmentRun(cr, name, runs[len(runs)-1], cr.Status.Steps[name], runValues(cr.Spec, id))
+	for k, v := range r.LabelSet {
+		run.Labels[k] = v
+	}
+	err := controllerutil.SetOwnerReference(cr, run, r.Scheme)
+	if err != nil {
+		return fmt.Errorf("owner: %w", err)
+	}
+	err = r.Create(ctx, run)
+	if err != nil {
+		return fmt.Errorf("create: %w", err)
+	}
+
+	list := &v1.EnvironmentRunList{}
+	err = r.List(ctx, list, client.InNamespace(cr.Namespace), client.MatchingLabels{v1.RunEnvironmentLabel: cr.Name})
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	for _, old := range runsToPrune(list.Items, cr.Spec.RunRetention, timeNow()) {
+		old := old
+		err = r.Delete(ctx, &old)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("delete: %w", err)
+		}
+		if r.LogSink != nil && old.Spec.LogID != "" {
+			err = r.LogSink.Delete(ctx, logsink.Run{Namespace: cr.Namespace, Environment: cr.Name, Step: old.Spec.Step, ID: old.Spec.LogID})
+			if err != nil {
+				return fmt.Errorf("delete logs: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"testing"
+	"time"
+)
+
+func Test_newEnvironmentRun(t *testing.T) {
+	t0 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	cr := &v1.Environment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "Dev", Generation: 3}}
+	run := v1.StepRun{
+		Start:   metav1.Time{Time: t0},
+		End:     &metav1.Time{Time: t0.Add(time.Minute)},
+		State:   v1.StateReady,
+		Message: "done",
+		Source:  &v1.StepSource{URL: "repo", Commit: "abc"},
+		Added:   1,
+		LogID:   "20060102t150405z",
+	}
+
+	got := newEnvironmentRun(cr, "Addonsxyz", run, v1.StepStatus{Hash: "123"}, map[string]string{"k": "v"})
+
+	assert.Equal(t, "dev-addonsxyz-", got.GenerateName)
+	assert.Equal(t, map[string]string{v1.RunEnvironmentLabel: "Dev", v1.RunStepLabel: "Addonsxyz"}, got.Labels)
+	assert.Equal(t, v1.EnvironmentRunSpec{
+		Environment: "Dev",
+		Step:        "Addonsxyz",
+		Hash:        "123",
+		Generation:  3,
+		Source:      &v1.StepSource{URL: "repo", Commit: "abc"},
+		Values:      map[string]string{"k": "v"},
+		State:       v1.StateReady,
+		Message:     "done",
+		Added:       1,
+		LogID:       "20060102t150405z",
+		Start:       metav1.Time{Time: t0},
+		End:         metav1.Time{Time: t0.Add(time.Minute)},
+		Duration:    metav1.Duration{Duration: time.Minute},
+	}, got.Spec)
+}
+
+func Test_runsToPrune(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	run := func(name, stp string, age time.Duration) v1.EnvironmentRun {
+		return v1.EnvironmentRun{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       v1.EnvironmentRunSpec{Step: stp, End: metav1.Time{Time: now.Add(-age)}},
+		}
+	}
+	runs := []v1.EnvironmentRun{
+		run("infra-1", "Infra", 3*time.Hour),
+		run("infra-2", "Infra", 2*time.Hour),
+		run("infra-3", "Infra", time.Hour),
+		run("addons-1", "Addonsxyz", 3*time.Hour),
+	}
+
+	tests := []struct {
+		it        string
+		retention v1.RunRetentionSpec
+		want      []string
+	}{
+		{
+			it:   "should keep the default number of runs",
+			want: nil,
+		},
+		{
+			it:        "should keep maxRuns per step",
+			retention: v1.RunRetentionSpec{MaxRuns: 2},
+			want:      []string{"infra-1"},
+		},
+		{
+			it:        "should prune runs older than maxAge",
+			retention: v1.RunRetentionSpec{MaxAge: metav1.Duration{Duration: 150 * time.Minute}},
+			want:      []string{"addons-1", "infra-1"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			var got []string
+			for _, r := range runsToPrune(runs, tst.retention, now) {
+				got = append(got, r.Name)
+			}
+			sort.Strings(got)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package controllers
+
+import (
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/robfig/cron/v3"
+	"time"
+	_ "time/tzdata" // time zones for windows, the image might not have them installed.
+)
+
+// Window is a period that starts at each schedule firing and lasts for duration.
+type window struct {
+	schedule cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+// Timetable decides when changes are allowed.
+type timetable struct {
+	// Windows are the periods in which changes are allowed.
+	// No windows means changes are always allowed (unless blacked out).
+	windows []window
+	// Blackouts are the periods in which changes are not allowed.
+	blackouts []window
+}
+
+// ParseWindow returns a window for a CRON schedule.
+//
+//  Field name   | Mandatory? | Allowed values  | Allowed special characters
+//  ----------   | ---------- | --------------  | --------------------------
+//  Minutes      | Yes        | 0-59            | * / , -
+//  Hours        | Yes        | 0-23            | * / , -
+//  Day of month | Yes        | 1-31            | * / , - ?
+//  Month        | Yes        | 1-12 or JAN-DEC | * / , -
+//  Day of week  | Yes        | 0-6 or SUN-SAT  | * / , - ?
+//
+// Special characters:
+//   * always
+//   / interval, for example */5 is every 5m
+//   , list, for example MON,FRI in DayOfWeek field
+//   - range, for example 20-04 in hour field
+// See https://godoc.org/github.com/robfig/cron#Parser
+func parseWindow(schedule string, duration time.Duration, timeZone string) (window, error) {
+	p := cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+	sc, err := p.Parse(schedule)
+	if err != nil {
+		return window{}, err
+	}
+
+	if duration <= 0 {
+		return window{}, fmt.Errorf("duration must be larger than 0")
+	}
+
+	loc, err := time.LoadLocation(timeZone) // "" returns UTC
+	if err != nil {
+		return window{}, err
+	}
+
+	return window{
+		schedule: sc,
+		duration: duration,
+		location: loc,
+	}, nil
+}
+
+// Start returns the start of the window that contains t.
+// Returns false if t is not within a window.
+func (w window) start(t time.Time) (time.Time, bool) {
+	// first firing after t-duration
+	s := w.schedule.Next(t.In(w.location).Add(-w.duration))
+	if s.After(t) {
+		return time.Time{}, false
+	}
+	return s, true
+}
+
+// Next returns the start of the next window after t.
+func (w window) next(t time.Time) time.Time {
+	return w.schedule.Next(t.In(w.location))
+}
+
+// Allowed returns true if changes are allowed at time t.
+func (tt timetable) allowed(t time.Time) bool {
+	for _, b := range tt.blackouts {
+		if _, ok := b.start(t); ok {
+			return false
+		}
+	}
+
+	if len(tt.windows) == 0 {
+		return true
+	}
+	for _, w := range tt.windows {
+		if _, ok := w.start(t); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Next returns the first time at or after t at which changes are allowed.
+// Returns false if no such time is found within a year.
+func (tt timetable) next(t time.Time) (time.Time, bool) {
+	limit := t.AddDate(1, 0, 0)
+	for t.Before(limit) {
+		if tt.allowed(t) {
+			return t, true
+		}
+
+		// Skip to the end of the blackout(s) containing t.
+		var end time.Time
+		for _, b := range tt.blackouts {
+			if s, ok := b.start(t); ok {
+				if e := s.Add(b.duration); e.After(end) {
+					end = e
+				}
+			}
+		}
+		if !end.IsZero() {
+			t = end
+			continue
+		}
+
+		// Skip to the start of the next window.
+		var start time.Time
+		for _, w := range tt.windows {
+			if s := w.next(t); start.IsZero() || s.Before(start) {
+				start = s
+			}
+		}
+		if start.IsZero() {
+			break
+		}
+		t = start
+	}
+
+	return time.Time{}, false
+}
+
+// TimetableForStep returns the timetable that applies to the step with id.
+// Infra, AKSPool and Destroy steps use the infra schedule, other steps use the addons schedule of their cluster.
+// A Schedule string is treated as a window of 1 minute at each firing.
+func timetableForStep(id step.ID, ispec v1.InfraSpec, cspec []v1.ClusterSpec) (timetable, error) {
+	var tt timetable
+
+	for i, b := range ispec.Blackouts {
+		w, err := parseWindow(b.Schedule, b.Duration.Duration, b.TimeZone)
+		if err != nil {
+			return tt, fmt.Errorf("infra.blackouts[%d]: %w", i, err)
+		}
+		tt.blackouts = append(tt.blackouts, w)
+	}
+
+	var (
+		path     string
+		schedule string
+		windows  []v1.MaintenanceWindow
+	)
+	switch id.Type {
+	case step.TypeInfra, step.TypeAKSPool, step.TypeDestroy:
+		path = "infra"
+		schedule = ispec.Schedule
+		windows = ispec.Windows
+	default:
+		for _, c := range cspec {
+			if c.Name == id.ClusterName {
+				path = "clusters." + c.Name + ".addons"
+				schedule = c.Addons.Schedule
+				windows = c.Addons.Windows
+				break
+			}
+		}
+	}
+
+	if schedule != "" {
+		w, err := parseWindow(schedule, time.Minute, "")
+		if err != nil {
+			return tt, fmt.Errorf("%s.schedule: %w", path, err)
+		}
+		tt.windows = append(tt.windows, w)
+	}
+	for i, mw := range windows {
+		w, err := parseWindow(mw.Schedule, mw.Duration.Duration, mw.TimeZone)
+		if err != nil {
+			return tt, fmt.Errorf("%s.windows[%d]: %w", path, i, err)
+		}
+		tt.windows = append(tt.windows, w)
+	}
+
+	return tt, nil
+}
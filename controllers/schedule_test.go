@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"errors"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func Test_timetableForStep(t *testing.T) {
+	hours := func(h int) metav1.Duration { return metav1.Duration{Duration: time.Duration(h) * time.Hour} }
+	infra := step.ID{Type: step.TypeInfra}
+	addons := step.ID{Type: step.TypeAddons, ClusterName: "one"}
+
+	tests := []struct {
+		it       string
+		id       step.ID
+		ispec    v1.InfraSpec
+		cspec    []v1.ClusterSpec
+		now      string
+		want     bool
+		wantNext string
+		err      error
+	}{
+		{
+			it:   "should allow changes when no schedule is specified",
+			id:   infra,
+			now:  "2006-01-02T15:04:05Z",
+			want: true,
+		},
+		{
+			it:    "should return true when in schedule",
+			id:    infra,
+			ispec: v1.InfraSpec{Schedule: "* 15 * * *"},
+			now:   "2006-01-02T15:04:05Z",
+			want:  true,
+		},
+		{
+			it:       "should return false when outside schedule",
+			id:       infra,
+			ispec:    v1.InfraSpec{Schedule: "* 22-23,0-4 * * *"},
+			now:      "2006-01-02T15:04:05Z",
+			want:     false,
+			wantNext: "2006-01-02T22:00:00Z",
+		},
+		{
+			it:    "should return true at start of nightly schedule",
+			id:    infra,
+			ispec: v1.InfraSpec{Schedule: "* 0-4 * * *"},
+			now:   "2006-01-02T00:04:05Z",
+			want:  true,
+		},
+		{
+			it:    "should return and error on invalid schedule",
+			id:    infra,
+			ispec: v1.InfraSpec{Schedule: "* 22-04 * * *"},
+			now:   "2006-01-03T03:04:05Z",
+			err:   errors.New("infra.schedule: beginning of range (22) beyond end of range (4): 22-04"),
+		},
+		{
+			it: "should return true when in one of the windows",
+			id: infra,
+			ispec: v1.InfraSpec{Windows: []v1.MaintenanceWindow{
+				{Schedule: "0 6 * * *", Duration: hours(1)},
+				{Schedule: "0 22 * * *", Duration: hours(4)},
+			}},
+			now:  "2006-01-03T01:04:05Z",
+			want: true,
+		},
+		{
+			it: "should evaluate windows in their time zone",
+			id: infra,
+			ispec: v1.InfraSpec{Windows: []v1.MaintenanceWindow{
+				{Schedule: "0 22 * * *", Duration: hours(4), TimeZone: "Europe/Amsterdam"},
+			}},
+			now:      "2006-01-03T01:04:05Z", // 02:04 in Amsterdam
+			want:     false,
+			wantNext: "2006-01-03T21:00:00Z",
+		},
+		{
+			it: "should return false during a blackout",
+			id: infra,
+			ispec: v1.InfraSpec{
+				Blackouts: []v1.MaintenanceWindow{
+					{Name: "christmas", Schedule: "0 0 24 12 *", Duration: hours(72)},
+				},
+			},
+			now:      "2006-12-25T15:04:05Z",
+			want:     false,
+			wantNext: "2006-12-27T00:00:00Z",
+		},
+		{
+			it: "should return the first window after a blackout",
+			id: infra,
+			ispec: v1.InfraSpec{
+				Windows: []v1.MaintenanceWindow{
+					{Schedule: "0 22 * * *", Duration: hours(4)},
+				},
+				Blackouts: []v1.MaintenanceWindow{
+					{Name: "christmas", Schedule: "0 0 24 12 *", Duration: hours(72)},
+				},
+			},
+			now:      "2006-12-25T23:04:05Z",
+			want:     false,
+			wantNext: "2006-12-27T00:00:00Z",
+		},
+		{
+			it:    "should use the addons schedule for addons steps",
+			id:    addons,
+			ispec: v1.InfraSpec{Schedule: "* 15 * * *"},
+			cspec: []v1.ClusterSpec{
+				{Name: "one", Addons: v1.ClusterAddonSpec{Windows: []v1.MaintenanceWindow{
+					{Schedule: "0 22 * * *", Duration: hours(4)},
+				}}},
+			},
+			now:      "2006-01-02T15:04:05Z",
+			want:     false,
+			wantNext: "2006-01-02T22:00:00Z",
+		},
+		{
+			it: "should return an error on a window without duration",
+			id: addons,
+			cspec: []v1.ClusterSpec{
+				{Name: "one", Addons: v1.ClusterAddonSpec{Windows: []v1.MaintenanceWindow{
+					{Schedule: "0 22 * * *"},
+				}}},
+			},
+			now: "2006-01-02T15:04:05Z",
+			err: errors.New("clusters.one.addons.windows[0]: duration must be larger than 0"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.it, func(t *testing.T) {
+			now, err := time.Parse(time.RFC3339, tt.now)
+			assert.NoError(t, err)
+
+			table, err := timetableForStep(tt.id, tt.ispec, tt.cspec)
+			if tt.err != nil {
+				assert.EqualError(t, err, tt.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+
+			got := table.allowed(now)
+			assert.Equal(t, tt.want, got)
+
+			if tt.wantNext != "" {
+				next, ok := table.next(now)
+				assert.True(t, ok)
+				assert.Equal(t, tt.wantNext, next.UTC().Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func Test_anyInWindow(t *testing.T) {
+	now := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	night := v1.InfraSpec{Schedule: "* 22-23 * * *", AZ: v1.AZSpec{Subscription: []v1.AZSubscription{{Name: "s", ID: "1"}}}}
+	cluster := func(name, schedule string) v1.ClusterSpec {
+		return v1.ClusterSpec{Name: name, Addons: v1.ClusterAddonSpec{Schedule: schedule}}
+	}
+
+	tests := []struct {
+		it       string
+		spec     v1.EnvironmentSpec
+		want     bool
+		wantNext string
+	}{
+		{
+			it:   "should return true when a cluster is in its window",
+			spec: v1.EnvironmentSpec{Infra: night, Clusters: []v1.ClusterSpec{cluster("one", "* 22-23 * * *"), cluster("two", "")}},
+			want: true,
+		},
+		{
+			it:       "should return the earliest next window when no step is in its window",
+			spec:     v1.EnvironmentSpec{Infra: night, Clusters: []v1.ClusterSpec{cluster("one", "* 18 * * *")}},
+			want:     false,
+			wantNext: "2006-01-02T18:00:00Z",
+		},
+		{
+			it: "should return true for a rollout gate outside blackouts",
+			spec: v1.EnvironmentSpec{Infra: night, Clusters: []v1.ClusterSpec{cluster("one", "* 18 * * *"), cluster("two", "* 18 * * *")},
+				Rollout: v1.RolloutSpec{Canaries: []string{"one"}}},
+			want: true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got, next, err := anyInWindow(tst.spec, now)
+			assert.NoError(t, err)
+			assert.Equal(t, tst.want, got)
+			if tst.wantNext == "" {
+				assert.Nil(t, next)
+				return
+			}
+			if assert.NotNil(t, next) {
+				assert.Equal(t, tst.wantNext, next.UTC().Format(time.RFC3339))
+			}
+		})
+	}
+}
@@ -11,6 +11,10 @@ func validateSpec(es *v1.EnvironmentSpec) error {
 		return fmt.Errorf("spec.infra.az.subscription: at least 1 subscription expected")
 	}
 
+	if es.Infra.Credentials.Vault != "" && es.Infra.Credentials.SecretName == "" {
+		return fmt.Errorf("spec.infra.credentials.vault: requires spec.infra.credentials.secretName")
+	}
+
 	//TODO Add validation logAnalyticsWorkspace.subscriptionName must be in spec.infra.subscription[]
 
 	//TODO Add validation of 'x' values k8sCluster (must equal cluster name), k8sEnvironment, k8sDomain, k8sProvider
@@ -10,7 +10,7 @@ import (
 
 // VaultInfraValues replaces references to a vault value with the actual value.
 // A value is considered a reference when it uses the form "vault secretname secretfield"
-func vaultInfraValues(infra v1.InfraSpec, c cloud.Cloud) (v1.InfraSpec, error) {
+func VaultInfraValues(infra v1.InfraSpec, c cloud.Cloud) (v1.InfraSpec, error) {
 	var err error
 
 	err = vaultValue(&infra.Source.Token, c, "infra.source.token", err)
@@ -26,7 +26,7 @@ func vaultInfraValues(infra v1.InfraSpec, c cloud.Cloud) (v1.InfraSpec, error) {
 
 // VaultClusterValues replaces references to a vault value with the actual value.
 // A value is considered a reference when it uses the form "vault secretname secretfield"
-func vaultClusterValues(clusters []v1.ClusterSpec, c cloud.Cloud) ([]v1.ClusterSpec, error) {
+func VaultClusterValues(clusters []v1.ClusterSpec, c cloud.Cloud) ([]v1.ClusterSpec, error) {
 	var err error
 
 	for i := range clusters {
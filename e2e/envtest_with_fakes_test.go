@@ -47,8 +47,7 @@ func TestGoodRun(t *testing.T) {
 		assert.EqualValues(t, float64(0), cnt, "number of failed executed steps")
 		*/
 		// Condition
-		assert.Equal(t, 1, len(got.Status.Conditions), "number of Status.Conditions")
-		assert.Equal(t, v1.ReasonReady, got.Status.Conditions[0].Reason)
+		assert.Equal(t, v1.ReasonReady, testCondition(t, got, v1.ConditionReady).Reason)
 
 		// Steps
 		assert.Equal(t, 4, len(got.Status.Steps), "number of Status.Steps")
@@ -78,8 +77,7 @@ func TestGoodRun(t *testing.T) {
 		got := testGetCRWhenConditionReady(t, testNSN)
 
 		// Condition
-		assert.Equal(t, 1, len(got.Status.Conditions), "number of Status.Conditions")
-		assert.Equal(t, v1.ReasonReady, got.Status.Conditions[0].Reason)
+		assert.Equal(t, v1.ReasonReady, testCondition(t, got, v1.ConditionReady).Reason)
 
 		assert.Equal(t, 1, kc.WipeClusterTally)
 	})
@@ -91,8 +89,7 @@ func TestGoodRun(t *testing.T) {
 		got := testGetCRWhenConditionReady(t, testNSN)
 
 		// Condition
-		assert.Equal(t, 1, len(got.Status.Conditions), "number of Status.Conditions")
-		assert.Equal(t, v1.ReasonReady, got.Status.Conditions[0].Reason)
+		assert.Equal(t, v1.ReasonReady, testCondition(t, got, v1.ConditionReady).Reason)
 
 		assert.Equal(t, 4, len(got.Status.Steps), "expected 4 Status.Steps of state Ready")
 	})
@@ -117,10 +114,11 @@ func TestErrorRun(t *testing.T) {
 
 		got := testGetCRWhenConditionReady(t, testNSN)
 
-		assert.Equal(t, 1, len(got.Status.Conditions), "number of Status.Conditions")
-		assert.Equal(t, metav1.ConditionTrue, got.Status.Conditions[0].Status)
-		assert.Equal(t, v1.ReasonFailed, got.Status.Conditions[0].Reason)
-		assert.Equal(t, "0/1 ready, 0 running, 1 error(s)", got.Status.Conditions[0].Message)
+		c := testCondition(t, got, v1.ConditionReady)
+		assert.Equal(t, metav1.ConditionFalse, c.Status)
+		assert.Equal(t, v1.ReasonFailed, c.Reason)
+		assert.Equal(t, "0/1 ready, 0 running, 1 error(s)", c.Message)
+		assert.Equal(t, metav1.ConditionTrue, testCondition(t, got, v1.ConditionStalled).Status)
 
 		assert.Equal(t, 1, len(got.Status.Steps), "number of Status.Steps")
 		assert.Equal(t, v1.StateError, got.Status.Steps["Destroy"].State, "Status.Steps[Destroy].State")
@@ -133,10 +131,10 @@ func TestErrorRun(t *testing.T) {
 
 		got = testGetCRWhenConditionReady(t, testNSN)
 
-		assert.Equal(t, 1, len(got.Status.Conditions), "number of Status.Conditions")
-		assert.Equal(t, metav1.ConditionTrue, got.Status.Conditions[0].Status)
-		assert.Equal(t, v1.ReasonReady, got.Status.Conditions[0].Reason)
-		assert.Equal(t, "1/1 ready, 0 running, 0 error(s)", got.Status.Conditions[0].Message)
+		c = testCondition(t, got, v1.ConditionReady)
+		assert.Equal(t, metav1.ConditionTrue, c.Status)
+		assert.Equal(t, v1.ReasonReady, c.Reason)
+		assert.Equal(t, "1/1 ready, 0 running, 0 error(s)", c.Message)
 
 		assert.Equal(t, 1, len(got.Status.Steps), "number of Status.Steps")
 		assert.Equal(t, v1.StateReady, got.Status.Steps["Destroy"].State, "Status.Steps[Destroy].State")
@@ -48,6 +48,7 @@ func testGetCR(t *testing.T, nsn types.NamespacedName) *v1.Environment {
 	return obj
 }
 
+// testGetCRWhenConditionReady waits until the Environment is Ready or Stalled.
 func testGetCRWhenConditionReady(t *testing.T, nsn types.NamespacedName) *v1.Environment {
 	t.Helper()
 
@@ -58,8 +59,8 @@ func testGetCRWhenConditionReady(t *testing.T, nsn types.NamespacedName) *v1.Env
 			return false, err
 		}
 		for _, c := range obj.Status.Conditions {
-			if c.Type == "Ready" {
-				return c.Status == metav1.ConditionTrue, nil
+			if (c.Type == v1.ConditionReady || c.Type == v1.ConditionStalled) && c.Status == metav1.ConditionTrue {
+				return true, nil
 			}
 		}
 		return false, nil
@@ -68,6 +69,19 @@ func testGetCRWhenConditionReady(t *testing.T, nsn types.NamespacedName) *v1.Env
 	return obj
 }
 
+// testCondition returns the condition of type typ of environment.
+func testCondition(t *testing.T, environment *v1.Environment, typ v1.EnvironmentConditionType) v1.EnvironmentCondition {
+	t.Helper()
+
+	for _, c := range environment.Status.Conditions {
+		if c.Type == typ {
+			return c
+		}
+	}
+	assert.Failf(t, "condition not found", "%s", typ)
+	return v1.EnvironmentCondition{}
+}
+
 func testResetStep(t *testing.T, nsn types.NamespacedName, step string) {
 	t.Helper()
 
@@ -3,23 +3,37 @@ module github.com/mmlt/environment-operator
 go 1.16
 
 require (
+	github.com/Azure/azure-sdk-for-go v55.0.0+incompatible
+	github.com/Azure/go-autorest/autorest v0.11.18
+	github.com/Azure/go-autorest/autorest/adal v0.9.15
+	github.com/Azure/go-autorest/autorest/to v0.4.0 // indirect
+	github.com/Azure/go-autorest/autorest/validation v0.3.1 // indirect
 	github.com/Jeffail/gabs/v2 v2.6.0
 	github.com/Masterminds/sprig/v3 v3.1.0
 	github.com/ghodss/yaml v1.0.0
 	github.com/go-logr/logr v0.4.0
 	github.com/go-logr/stdr v0.3.0
+	github.com/golang-jwt/jwt/v4 v4.3.0 // indirect
 	github.com/hashicorp/go-multierror v1.1.0
 	github.com/huandu/xstrings v1.3.2 // indirect
 	github.com/imdario/mergo v0.3.12
+	github.com/minio/minio-go/v7 v7.0.10
 	github.com/mitchellh/hashstructure v1.0.0
 	github.com/mmlt/testr v0.0.0-20200331071714-d38912dd7e5a
+	github.com/nats-io/nats.go v1.11.0
 	github.com/otiai10/copy v1.1.1
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/prometheus/client_golang v1.11.0
 	github.com/robfig/cron/v3 v3.0.0
 	github.com/rodaine/hclencoder v0.0.0-20190213202847-fb9757bb536e
 	github.com/securego/gosec/v2 v2.8.1
 	github.com/spf13/cobra v1.1.3
+	github.com/spf13/pflag v1.0.5
 	github.com/stretchr/testify v1.7.0
+	go.opentelemetry.io/otel v1.0.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.0.0
+	go.opentelemetry.io/otel/sdk v1.0.0
+	go.opentelemetry.io/otel/trace v1.0.0
 	golang.org/x/tools v0.1.3
 	k8s.io/api v0.21.1
 	k8s.io/apimachinery v0.21.1
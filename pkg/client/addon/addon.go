@@ -7,21 +7,19 @@ import (
 	"github.com/go-logr/logr"
 	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"io"
-	"os/exec"
 	"strings"
 	"unicode"
 )
 
 // Addonr is able to provision Kubernetes resources.
 type Addonr interface {
-	// Start runs kubectl-tmplt concurrently in dir and returns a cmd and a channel of KTResults.
+	// Start runs kubectl-tmplt concurrently in dir and returns a channel of KTResults.
 	// The jobPath refers to a yaml file with resources to apply.
 	// The valuesPath refers to yaml file with values that parameterize the job resources.
 	// The kubeconfigPath refers to a kube config file with current-context referring to the target cluster.
 	// The masterVaultPath refers to a directory containing the config of the Vault to use for {{ vault }}.
 	// The channel will be closed when kubectl-tmplt exits.
-	// cmd.Wait() must be called to clean-up.
-	Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (*exec.Cmd, chan KTResult, error)
+	Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (chan KTResult, error)
 }
 
 // KTResult
@@ -43,38 +41,42 @@ type KTResult struct {
 
 // Addon provisions Kubernetes resources using kubectl-tmplt cli.
 type Addon struct {
+	// Runner (optional) executes the kubectl-tmplt commands, nil means an exe.Executor is used.
+	Runner exe.Starter
 }
 
 // Start implements Addonr.
-func (a *Addon) Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (*exec.Cmd, chan KTResult, error) {
+func (a *Addon) Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (chan KTResult, error) {
 	log := logr.FromContext(ctx).WithName("Addon")
 	ctx = logr.NewContext(ctx, log)
 
-	cmd := exe.RunAsync(ctx, log, &exe.Opt{Dir: dir, Env: env}, "", "kubectl-tmplt",
-		"-m", "apply-with-actions",
-		"--job-file", jobPath,
-		"--set-file", valuesPath,
-		"--kubeconfig", kubeconfigPath,
-		"--master-vault-path", masterVaultPath)
-
-	o, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, err
+	var rn exe.Starter = &exe.Executor{Log: log}
+	if a.Runner != nil {
+		rn = a.Runner
 	}
-
-	cmd.Stderr = cmd.Stdout // combine
-
-	err = cmd.Start()
+	o, err := rn.Start(ctx, exe.Command{
+		Name: "kubectl-tmplt",
+		Args: []string{
+			"-m", "apply-with-actions",
+			"--job-file", jobPath,
+			"--set-file", valuesPath,
+			"--kubeconfig", kubeconfigPath,
+			"--master-vault-path", masterVaultPath,
+		},
+		Dir: dir,
+		Env: env,
+	})
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
 	ch := a.parseAsyncAddonResponse(log, o)
 
-	return cmd, ch, nil
+	return ch, nil
 }
 
 // ParseAsyncAddonResponse parses in and returns results when interesting input is encountered.
+// A read error (like a non-zero exit) is returned as an error result.
 // Close in to release the go func.
 func (a *Addon) parseAsyncAddonResponse(log logr.Logger, in io.ReadCloser) chan KTResult {
 	out := make(chan KTResult)
@@ -96,7 +98,9 @@ func (a *Addon) parseAsyncAddonResponse(log logr.Logger, in io.ReadCloser) chan
 			}
 		}
 		if err := sc.Err(); err != nil {
+			// kubectl-tmplt failed (non-zero exit) or its output couldn't be read.
 			log.Error(err, "parseAsyncAddonResponse")
+			out <- KTResult{Changed: changed, Errors: []string{err.Error()}}
 		}
 
 		close(out)
@@ -2,7 +2,6 @@ package addon
 
 import (
 	"context"
-	"os/exec"
 	"time"
 )
 
@@ -15,7 +14,7 @@ type AddonFake struct {
 	StartResult []KTResult
 }
 
-func (a *AddonFake) Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (*exec.Cmd, chan KTResult, error) {
+func (a *AddonFake) Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (chan KTResult, error) {
 	a.StartTally++
 
 	out := make(chan KTResult)
@@ -33,7 +32,7 @@ func (a *AddonFake) Start(ctx context.Context, env []string, dir, jobPath, value
 		close(out)
 	}()
 
-	return nil, out, nil
+	return out, nil
 }
 
 // SetupFakeResultsForCreate sets-up the receiver with data that is returned during testing.
@@ -1,16 +1,18 @@
 package azure
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-logr/logr"
 	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"strconv"
 	"strings"
 )
 
 // Autoscaler enables or disables a Node autoscaler for a cluster/pool.
 // https://docs.microsoft.com/en-us/azure/aks/cluster-autoscaler#use-the-cluster-autoscaler-with-multiple-node-pools-enabled
-func (c *AZ) Autoscaler(enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
+func (c *AZ) Autoscaler(ctx context.Context, enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
 	var a string
 
 	args := []string{"aks", "nodepool", "update", "--resource-group", resourceGroup, "--cluster-name", cluster,
@@ -28,15 +30,15 @@ func (c *AZ) Autoscaler(enable bool, resourceGroup string, cluster string, pool
 
 	c.Log.Info(a+" autoscaler", "resourceGroup", resourceGroup, "cluster", cluster, "pool", pool)
 
-	_, err := runAZ(c.Log, nil, "", args...)
+	_, err := c.runAZ(ctx, exe.Command{Args: args})
 
 	return err
 }
 
 // AllAutoscalers enables or disables Node autoscaling of multiple clusters/pools.
-func (c *AZ) AllAutoscalers(enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
+func (c *AZ) AllAutoscalers(ctx context.Context, enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
 	for _, cl := range clusters {
-		pls, err := c.AKSNodepoolList(resourceGroup, cl.Name)
+		pls, err := c.AKSNodepoolList(ctx, resourceGroup, cl.Name)
 		if err != nil {
 			s := fmt.Sprintf("ERROR: The Resource 'Microsoft.ContainerService/managedClusters/%s' under resource group '%s' was not found", cl.Name, resourceGroup)
 			if strings.Contains(err.Error(), s) {
@@ -47,7 +49,7 @@ func (c *AZ) AllAutoscalers(enable bool, clusters []v1.ClusterSpec, resourceGrou
 		}
 		for _, pl := range pls {
 			if pl.EnableAutoScaling {
-				err = c.Autoscaler(enable, pl.ResourceGroup, cl.Name, pl.Name, pl.MinCount, pl.MaxCount)
+				err = c.Autoscaler(ctx, enable, pl.ResourceGroup, cl.Name, pl.Name, pl.MinCount, pl.MaxCount)
 				log.Error(err, "disable autoscaler", "cluster", cl.Name, "pool", pl.Name)
 			}
 		}
@@ -2,10 +2,12 @@
 package azure
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-logr/logr"
 	v1 "github.com/mmlt/environment-operator/api/v1"
 	"github.com/mmlt/environment-operator/pkg/util/exe"
+	"os"
 	"strings"
 )
 
@@ -14,22 +16,26 @@ type AZer interface {
 	SetSubscription(sub string)
 
 	LoginSP(user, password, tenant string) error
+	// LoginMSI logs in with the managed identity of the host, clientID (optional) selects a user assigned identity.
+	LoginMSI(clientID string) error
+	// LoginFederated logs in with a federated token (workload identity) that is read from tokenFile.
+	LoginFederated(clientID, tenant, tokenFile string) error
 	Logout() error
 
 	// KeyvaultSecret reads name secret from vaultName KeyVault.
 	KeyvaultSecret(name, vaultName string) (string, error)
 
 	// AKSNodepoolList returns all the node pools of an AKS cluster.
-	AKSNodepoolList(resourceGroup, cluster string) ([]AKSNodepool, error)
+	AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) ([]AKSNodepool, error)
 	// AKSNodepool returns the details about an AKS cluster nodepool.
-	AKSNodepool(resourceGroup, cluster, nodepool string) (*AKSNodepool, error)
+	AKSNodepool(ctx context.Context, resourceGroup, cluster, nodepool string) (*AKSNodepool, error)
 	// AKSNodepoolUpgrade upgrades the node pool in a managed Kubernetes cluster to Kubernetes version.
 	// Expect this call to block for VM count * 10m.
-	AKSNodepoolUpgrade(resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error)
+	AKSNodepoolUpgrade(ctx context.Context, resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error)
 	// Autoscaling enables or disables a Node autoscaler.
-	Autoscaler(enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error
+	Autoscaler(ctx context.Context, enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error
 	// AllAutoscalers enables or disables the Node autoscalers of multiple clusters.
-	AllAutoscalers(enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error
+	AllAutoscalers(ctx context.Context, enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error
 }
 
 // AZ is able to perform az cli commands.
@@ -37,6 +43,13 @@ type AZ struct {
 	// Subscription is the Name or ID of the Azure subscription.
 	Subscription string
 
+	// Runner (optional) executes the az commands, nil means an exe.Executor is used.
+	Runner exe.Runner
+
+	// ConfigDir (optional) is the az configuration directory (AZURE_CONFIG_DIR) that keeps the login.
+	// Empty means the az default (~/.azure) is used.
+	ConfigDir string
+
 	Log logr.Logger
 }
 
@@ -53,15 +66,52 @@ func (c *AZ) extraArgs(arg []string) []string {
 	return arg
 }
 
-// RunAZ runs the az cli.
-func runAZ(log logr.Logger, options *exe.Opt, stdin string, args ...string) (string, error) {
-	stdout, stderr, err := exe.Run(log, options, stdin, "az", args...)
+// Runner returns the runner to execute az commands.
+func (c *AZ) runner() exe.Runner {
+	if c.Runner != nil {
+		return c.Runner
+	}
+	return &exe.Executor{Log: c.Log}
+}
+
+// CLIAuthFailures are substrings of az cli error output that indicate invalid or expired credentials.
+var cliAuthFailures = []string{
+	"AADSTS",
+	"az login",
+	"ExpiredAuthenticationToken",
+	"InvalidAuthenticationToken",
+	"Unauthorized",
+}
+
+// IsCLIAuthFailure returns true when msg is az cli output that indicates invalid or expired credentials.
+func isCLIAuthFailure(msg string) bool {
+	for _, s := range cliAuthFailures {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// RunAZ runs the az cli and returns stdout.
+// Output on stderr is an error.
+func (c *AZ) runAZ(ctx context.Context, cmd exe.Command) (string, error) {
+	r, err := c.run(ctx, cmd)
 	if err != nil {
 		return "", err
 	}
 	// don't rely on az cli exit code for error detection.
-	if stderr != "" {
-		return "", fmt.Errorf("%s: %s", strings.Join(args, " "), stderr)
+	if r.Stderr != "" {
+		return "", fmt.Errorf("%s: %s", strings.Join(exe.RedactArgs(cmd.Args, cmd.Secrets), " "), exe.Redact(r.Stderr, cmd.Secrets))
+	}
+	return r.Stdout, nil
+}
+
+// Run runs the az cli with the az configuration directory of c.
+func (c *AZ) run(ctx context.Context, cmd exe.Command) (exe.Result, error) {
+	cmd.Name = "az"
+	if c.ConfigDir != "" {
+		cmd.Env = append(os.Environ(), "AZURE_CONFIG_DIR="+c.ConfigDir)
 	}
-	return stdout, err
+	return c.runner().Run(ctx, cmd)
 }
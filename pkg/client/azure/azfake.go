@@ -1,6 +1,7 @@
 package azure
 
 import (
+	"context"
 	"github.com/go-logr/logr"
 	v1 "github.com/mmlt/environment-operator/api/v1"
 	"time"
@@ -30,6 +31,14 @@ func (c *AZFake) LoginSP(user, password, tenant string) error {
 	return nil
 }
 
+func (c *AZFake) LoginMSI(clientID string) error {
+	return nil
+}
+
+func (c *AZFake) LoginFederated(clientID, tenant, tokenFile string) error {
+	return nil
+}
+
 func (c *AZFake) Logout() error {
 	return nil
 }
@@ -42,13 +51,13 @@ func (c *AZFake) KeyvaultSecret(name, vaultName string) (string, error) {
 }
 
 // AKSNodepoolList returns all the node pools of an AKS cluster.
-func (c *AZFake) AKSNodepoolList(resourceGroup, cluster string) ([]AKSNodepool, error) {
+func (c *AZFake) AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) ([]AKSNodepool, error) {
 	c.AKSNodepoolListTally++
 	return c.AKSNodepoolListResult, nil
 }
 
 // AKSNodepool returns the details about an AKS cluster nodepool.
-func (c *AZFake) AKSNodepool(resourceGroup, cluster, nodepool string) (*AKSNodepool, error) {
+func (c *AZFake) AKSNodepool(ctx context.Context, resourceGroup, cluster, nodepool string) (*AKSNodepool, error) {
 	c.AKSNodepoolTally++
 	i := c.AKSNodepoolTally
 	if i > len(c.AKSNodepoolResult) {
@@ -61,7 +70,7 @@ func (c *AZFake) AKSNodepool(resourceGroup, cluster, nodepool string) (*AKSNodep
 
 // AKSNodepoolUpgrade upgrades the node pool in a managed Kubernetes cluster to Kubernetes version.
 // Expect this call to block for 10m per VM.
-func (c *AZFake) AKSNodepoolUpgrade(resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error) {
+func (c *AZFake) AKSNodepoolUpgrade(ctx context.Context, resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error) {
 	c.AKSNodepoolUpgradeTally++
 	time.Sleep(2 * time.Second)
 	return &c.AKSNodepoolUpgradeResult, nil
@@ -109,10 +118,10 @@ func (c *AZFake) SetupFakeResults() {
 	}
 }
 
-func (c *AZFake) Autoscaler(enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
+func (c *AZFake) Autoscaler(ctx context.Context, enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
 	return nil
 }
 
-func (c *AZFake) AllAutoscalers(enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
+func (c *AZFake) AllAutoscalers(ctx context.Context, enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
 	return nil
 }
@@ -1,13 +1,15 @@
 package azure
 
 import (
+	"context"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"strings"
 )
 
 // KeyvaultSecret returns the value of 'name' secret in 'vaultName' KeyVault.
 func (c *AZ) KeyvaultSecret(name, vaultName string) (string, error) {
 	args := []string{"keyvault", "secret", "show", "--name", name, "--vault-name", vaultName, "--query", "value", "-o", "tsv"}
-	o, err := runAZ(c.Log, nil, "", args...)
+	o, err := c.runAZ(context.Background(), exe.Command{Args: args, SecretOutput: true})
 	if err != nil {
 		return "", err
 	}
@@ -1,13 +1,48 @@
 package azure
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
+	"io/ioutil"
+	"strings"
 )
 
 // LoginSP performs an 'az login' by ServicePrincipal.
 func (c *AZ) LoginSP(user, password, tenant string) error {
 	args := []string{"login", "--service-principal", "-u", user, "-p", password, "--tenant", tenant}
-	_, err := runAZ(c.Log, nil, "", args...)
+	_, err := c.runAZ(context.Background(), exe.Command{Args: args, Secrets: []string{password}})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LoginMSI performs an 'az login' by managed identity.
+func (c *AZ) LoginMSI(clientID string) error {
+	args := []string{"login", "--identity"}
+	if clientID != "" {
+		args = append(args, "--username", clientID)
+	}
+	_, err := c.runAZ(context.Background(), exe.Command{Args: args})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LoginFederated performs an 'az login' by ServicePrincipal with a federated token read from tokenFile.
+func (c *AZ) LoginFederated(clientID, tenant, tokenFile string) error {
+	b, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return err
+	}
+	token := strings.TrimSpace(string(b))
+
+	args := []string{"login", "--service-principal", "-u", clientID, "--tenant", tenant, "--federated-token", token}
+	_, err = c.runAZ(context.Background(), exe.Command{Args: args, Secrets: []string{token}})
 	if err != nil {
 		return err
 	}
@@ -18,7 +53,7 @@ func (c *AZ) LoginSP(user, password, tenant string) error {
 // Logout performs an 'az logout'.
 func (c *AZ) Logout() error {
 	args := []string{"logout"}
-	_, err := runAZ(c.Log, nil, "", args...)
+	_, err := c.runAZ(context.Background(), exe.Command{Args: args})
 	if err != nil {
 		return err
 	}
@@ -30,7 +65,7 @@ func (c *AZ) Logout() error {
 // An error is returned when no account is logged in.
 func (c *AZ) AccountStatus() ([]AccountStatus, error) {
 	args := []string{"account", "show"}
-	o, err := runAZ(c.Log, nil, "", args...)
+	o, err := c.runAZ(context.Background(), exe.Command{Args: args})
 	if err != nil {
 		return nil, err
 	}
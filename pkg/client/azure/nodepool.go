@@ -2,6 +2,7 @@
 package azure
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/mmlt/environment-operator/pkg/util/exe"
 )
@@ -9,10 +10,10 @@ import (
 // https://docs.microsoft.com/en-us/cli/azure/ext/aks-preview/aks/nodepool
 
 // AKSNodepoolList returns all the node pools of an AKS cluster.
-func (c *AZ) AKSNodepoolList(resourceGroup, cluster string) ([]AKSNodepool, error) {
+func (c *AZ) AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) ([]AKSNodepool, error) {
 	args := []string{"aks", "nodepool", "list", "--resource-group", resourceGroup, "--cluster-name", cluster}
 	args = c.extraArgs(args)
-	o, err := runAZ(c.Log, nil, "", args...)
+	o, err := c.runAZ(ctx, exe.Command{Args: args})
 	if err != nil {
 		return nil, err
 	}
@@ -27,11 +28,11 @@ func (c *AZ) AKSNodepoolList(resourceGroup, cluster string) ([]AKSNodepool, erro
 }
 
 // AKSNodepool returns the details about an AKS cluster nodepool.
-func (c *AZ) AKSNodepool(resourceGroup, cluster, nodepool string) (*AKSNodepool, error) {
+func (c *AZ) AKSNodepool(ctx context.Context, resourceGroup, cluster, nodepool string) (*AKSNodepool, error) {
 	args := []string{"aks", "nodepool", "show", "--resource-group", resourceGroup, "--cluster-name", cluster,
 		"--name", nodepool}
 	args = c.extraArgs(args)
-	o, err := runAZ(c.Log, nil, "", args...)
+	o, err := c.runAZ(ctx, exe.Command{Args: args})
 	if err != nil {
 		return nil, err
 	}
@@ -47,17 +48,18 @@ func (c *AZ) AKSNodepool(resourceGroup, cluster, nodepool string) (*AKSNodepool,
 
 // AKSNodepoolUpgrade upgrades the node pool in a managed Kubernetes cluster to Kubernetes version.
 // Expect this call to block for 10m per VM.
-func (c *AZ) AKSNodepoolUpgrade(resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error) {
+func (c *AZ) AKSNodepoolUpgrade(ctx context.Context, resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error) {
 	args := []string{"aks", "nodepool", "upgrade", "--resource-group", resourceGroup, "--cluster-name", cluster,
 		"--name", nodepool, "--kubernetes-version", version}
 	args = c.extraArgs(args)
-	o, _, err := exe.Run(c.Log, nil, "", "az", args...)
+	// no timeout as the upgrade takes 10m per VM, stderr isn't checked because az reports progress on it.
+	o, err := c.run(ctx, exe.Command{Args: args, Timeout: -1})
 	if err != nil {
 		return nil, err
 	}
 
 	var r AKSNodepool
-	err = json.Unmarshal([]byte(o), &r)
+	err = json.Unmarshal([]byte(o.Stdout), &r)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,57 @@
+package azure
+
+import (
+	"context"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// RecordingRunner records the commands it runs and returns stdout.
+type recordingRunner struct {
+	stdout string
+	cmds   []exe.Command
+}
+
+func (r *recordingRunner) Run(ctx context.Context, cmd exe.Command) (exe.Result, error) {
+	r.cmds = append(r.cmds, cmd)
+	return exe.Result{Stdout: r.stdout}, nil
+}
+
+func TestAZ_AKSNodepoolUpgrade(t *testing.T) {
+	tests := []struct {
+		it        string
+		configDir string
+		wantEnv   string
+	}{
+		{
+			it: "should use the default az config dir",
+		},
+		{
+			it:        "should use the az config dir of the environment",
+			configDir: "/var/tmp/envop/azure/default/env1",
+			wantEnv:   "AZURE_CONFIG_DIR=/var/tmp/envop/azure/default/env1",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			rn := &recordingRunner{stdout: `{"name":"default","orchestratorVersion":"1.20.7"}`}
+			c := &AZ{Runner: rn, ConfigDir: tst.configDir}
+
+			p, err := c.AKSNodepoolUpgrade(context.Background(), "rg", "cpe", "default", "1.20.7")
+
+			assert.NoError(t, err)
+			assert.Equal(t, "default", p.Name)
+			if assert.Len(t, rn.cmds, 1) {
+				cmd := rn.cmds[0]
+				assert.Equal(t, "az", cmd.Name)
+				assert.Equal(t, -1, int(cmd.Timeout), "no timeout")
+				if tst.wantEnv == "" {
+					assert.Nil(t, cmd.Env)
+				} else {
+					assert.Contains(t, cmd.Env, tst.wantEnv)
+				}
+			}
+		})
+	}
+}
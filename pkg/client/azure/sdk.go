@@ -0,0 +1,481 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/Azure/azure-sdk-for-go/services/containerservice/mgmt/2021-03-01/containerservice"
+	"github.com/Azure/azure-sdk-for-go/services/keyvault/v7.1/keyvault"
+	"github.com/Azure/azure-sdk-for-go/services/resources/mgmt/2021-01-01/subscriptions"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	azenv "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SDK is an AZer that uses the Azure Resource Manager and KeyVault REST API's instead of the az cli.
+type SDK struct {
+	// Environment (optional) is the Azure cloud to use, zero value means the public cloud.
+	Environment *azenv.Environment
+	// VaultURL (optional) is the KeyVault base URL with {vault} being replaced by the vault name.
+	// Zero value means https://{vault}.<Environment.KeyVaultDNSSuffix>
+	VaultURL string
+	// PollingDelay (optional) is the delay between status requests of long running operations, zero means 30s.
+	// A Retry-After header of the response takes precedence.
+	PollingDelay time.Duration
+
+	Log logr.Logger
+
+	mu sync.Mutex
+	// subscription is the Name or ID of the subscription.
+	subscription string
+	// subscriptionIDs are the subscription ID's by name.
+	subscriptionIDs map[string]string
+	// arm and vault authorize ARM respectively KeyVault requests, nil means not logged in.
+	arm, vault autorest.Authorizer
+}
+
+var _ AZer = &SDK{}
+
+// APIError is returned when an Azure API request fails.
+type APIError struct {
+	// Op is the operation that failed, for example "get nodepool".
+	Op string
+	// StatusCode is the HTTP status code of the response, zero when unknown.
+	StatusCode int
+	// Code is the Azure error code, for example ResourceNotFound.
+	Code string
+	// Message is the Azure error message.
+	Message string
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: %d %s: %s", e.Op, e.StatusCode, e.Code, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotFound returns true when err is caused by a resource that doesn't exist.
+func IsNotFound(err error) bool {
+	var e *APIError
+	return errors.As(err, &e) && e.StatusCode == http.StatusNotFound
+}
+
+// IsAuthFailure returns true when err is caused by invalid credentials or insufficient permissions.
+// Errors of the az cli are recognized by their message.
+func IsAuthFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *APIError
+	if !errors.As(err, &e) {
+		return isCLIAuthFailure(err.Error())
+	}
+	var te adal.TokenRefreshError
+	return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden || errors.As(err, &te)
+}
+
+// SetSubscription sets the Name or ID of the Azure subscription to use.
+func (c *SDK) SetSubscription(sub string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.subscription = sub
+}
+
+// LoginSP acquires tokens for the ServicePrincipal user.
+func (c *SDK) LoginSP(user, password, tenant string) error {
+	env := c.env()
+	oc, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.login("login service principal", func(resource string) (*adal.ServicePrincipalToken, error) {
+		return adal.NewServicePrincipalToken(*oc, user, password, resource)
+	})
+}
+
+// LoginMSI acquires tokens for the managed identity of the host.
+// The clientID selects a user assigned identity, empty means the system assigned identity.
+func (c *SDK) LoginMSI(clientID string) error {
+	return c.login("login managed identity", func(resource string) (*adal.ServicePrincipalToken, error) {
+		return adal.NewServicePrincipalTokenFromManagedIdentity(resource, &adal.ManagedIdentityOptions{ClientID: clientID})
+	})
+}
+
+// LoginFederated acquires tokens for the ServicePrincipal clientID with the federated token in tokenFile.
+// The token file is read on each token refresh because it's rotated by the workload identity webhook.
+func (c *SDK) LoginFederated(clientID, tenant, tokenFile string) error {
+	env := c.env()
+	oc, err := adal.NewOAuthConfig(env.ActiveDirectoryEndpoint, tenant)
+	if err != nil {
+		return err
+	}
+
+	return c.login("login federated", func(resource string) (*adal.ServicePrincipalToken, error) {
+		return adal.NewServicePrincipalTokenWithSecret(*oc, clientID, resource, &federatedToken{path: tokenFile})
+	})
+}
+
+// FederatedToken is an adal.ServicePrincipalSecret that authenticates with a client assertion read from path.
+type federatedToken struct {
+	path string
+}
+
+// SetAuthenticationValues implements adal.ServicePrincipalSecret.
+func (f *federatedToken) SetAuthenticationValues(_ *adal.ServicePrincipalToken, v *url.Values) error {
+	b, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	v.Set("client_assertion", strings.TrimSpace(string(b)))
+	v.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+	return nil
+}
+
+// Login creates the ARM and KeyVault authorizers with tokens from newToken.
+// The ARM token is refreshed to verify the credentials.
+func (c *SDK) login(op string, newToken func(resource string) (*adal.ServicePrincipalToken, error)) error {
+	env := c.env()
+
+	arm, err := newToken(env.TokenAudience)
+	if err != nil {
+		return err
+	}
+	err = arm.Refresh()
+	if err != nil {
+		return apiError(op, err)
+	}
+	vault, err := newToken(env.ResourceIdentifiers.KeyVault)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.arm = autorest.NewBearerAuthorizer(arm)
+	c.vault = autorest.NewBearerAuthorizer(vault)
+
+	return nil
+}
+
+// Logout forgets the tokens.
+func (c *SDK) Logout() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.arm, c.vault = nil, nil
+	return nil
+}
+
+// KeyvaultSecret returns the value of 'name' secret in 'vaultName' KeyVault.
+func (c *SDK) KeyvaultSecret(name, vaultName string) (string, error) {
+	c.mu.Lock()
+	auth := c.vault
+	c.mu.Unlock()
+	if auth == nil {
+		return "", errNotLoggedIn
+	}
+
+	cl := keyvault.New()
+	cl.Authorizer = auth
+	b, err := cl.GetSecret(context.Background(), c.vaultURL(vaultName), name, "")
+	if err != nil {
+		return "", apiError("get secret", err)
+	}
+	if b.Value == nil {
+		return "", nil
+	}
+
+	return *b.Value, nil
+}
+
+// AKSNodepoolList returns all the node pools of an AKS cluster.
+func (c *SDK) AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) ([]AKSNodepool, error) {
+	cl, err := c.agentPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var r []AKSNodepool
+	it, err := cl.ListComplete(ctx, resourceGroup, cluster)
+	for ; err == nil && it.NotDone(); err = it.NextWithContext(ctx) {
+		r = append(r, nodepoolFromAgentPool(it.Value(), resourceGroup))
+	}
+	if err != nil {
+		return nil, apiError("list nodepools", err)
+	}
+
+	return r, nil
+}
+
+// AKSNodepool returns the details about an AKS cluster nodepool.
+func (c *SDK) AKSNodepool(ctx context.Context, resourceGroup, cluster, nodepool string) (*AKSNodepool, error) {
+	cl, err := c.agentPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ap, err := cl.Get(ctx, resourceGroup, cluster, nodepool)
+	if err != nil {
+		return nil, apiError("get nodepool", err)
+	}
+
+	r := nodepoolFromAgentPool(ap, resourceGroup)
+	return &r, nil
+}
+
+// AKSNodepoolUpgrade upgrades the node pool in a managed Kubernetes cluster to Kubernetes version.
+// Expect this call to block for 10m per VM.
+func (c *SDK) AKSNodepoolUpgrade(ctx context.Context, resourceGroup, cluster, nodepool, version string) (*AKSNodepool, error) {
+	return c.updateNodepool(ctx, "upgrade nodepool", resourceGroup, cluster, nodepool, func(p *containerservice.ManagedClusterAgentPoolProfileProperties) {
+		p.OrchestratorVersion = &version
+	})
+}
+
+// Autoscaler enables or disables a Node autoscaler for a cluster/pool.
+func (c *SDK) Autoscaler(ctx context.Context, enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
+	a := "disable"
+	if enable {
+		a = "enable"
+	}
+	c.Log.Info(a+" autoscaler", "resourceGroup", resourceGroup, "cluster", cluster, "pool", pool)
+
+	_, err := c.updateNodepool(ctx, a+" autoscaler", resourceGroup, cluster, pool, func(p *containerservice.ManagedClusterAgentPoolProfileProperties) {
+		p.EnableAutoScaling = &enable
+		if enable {
+			min, max := int32(minCount), int32(maxCount)
+			p.MinCount, p.MaxCount = &min, &max
+		} else {
+			p.MinCount, p.MaxCount = nil, nil
+		}
+	})
+
+	return err
+}
+
+// AllAutoscalers enables or disables Node autoscaling of multiple clusters/pools.
+// Clusters that don't exist are ignored.
+func (c *SDK) AllAutoscalers(ctx context.Context, enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
+	for _, cl := range clusters {
+		pls, err := c.AKSNodepoolList(ctx, resourceGroup, cl.Name)
+		if IsNotFound(err) {
+			log.Info("ignore error", "error", err.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for _, pl := range pls {
+			if pl.EnableAutoScaling {
+				err = c.Autoscaler(ctx, enable, pl.ResourceGroup, cl.Name, pl.Name, pl.MinCount, pl.MaxCount)
+				if err != nil {
+					log.Error(err, "autoscaler", "cluster", cl.Name, "pool", pl.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// UpdateNodepool reads a node pool, changes it with fn and waits for the update to complete.
+func (c *SDK) updateNodepool(ctx context.Context, op, resourceGroup, cluster, nodepool string, fn func(*containerservice.ManagedClusterAgentPoolProfileProperties)) (*AKSNodepool, error) {
+	cl, err := c.agentPools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ap, err := cl.Get(ctx, resourceGroup, cluster, nodepool)
+	if err != nil {
+		return nil, apiError(op, err)
+	}
+	if ap.ManagedClusterAgentPoolProfileProperties == nil {
+		ap.ManagedClusterAgentPoolProfileProperties = &containerservice.ManagedClusterAgentPoolProfileProperties{}
+	}
+	fn(ap.ManagedClusterAgentPoolProfileProperties)
+
+	f, err := cl.CreateOrUpdate(ctx, resourceGroup, cluster, nodepool, ap)
+	if err != nil {
+		return nil, apiError(op, err)
+	}
+	err = f.WaitForCompletionRef(ctx, cl.Client)
+	if err != nil {
+		return nil, apiError(op, err)
+	}
+	ap, err = f.Result(cl)
+	if err != nil {
+		return nil, apiError(op, err)
+	}
+
+	r := nodepoolFromAgentPool(ap, resourceGroup)
+	return &r, nil
+}
+
+// AgentPools returns an AgentPoolsClient for the current subscription.
+func (c *SDK) agentPools(ctx context.Context) (containerservice.AgentPoolsClient, error) {
+	id, auth, err := c.subscriptionID(ctx)
+	if err != nil {
+		return containerservice.AgentPoolsClient{}, err
+	}
+
+	cl := containerservice.NewAgentPoolsClientWithBaseURI(c.env().ResourceManagerEndpoint, id)
+	cl.Authorizer = auth
+	// node pool upgrades take 10m per VM.
+	cl.PollingDuration = 0
+	if c.PollingDelay > 0 {
+		cl.PollingDelay = c.PollingDelay
+	}
+
+	return cl, nil
+}
+
+// SubscriptionIDRE matches a subscription ID.
+var subscriptionIDRE = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// SubscriptionID returns the ID of the current subscription and the ARM authorizer.
+// A subscription name is resolved to an ID once.
+func (c *SDK) subscriptionID(ctx context.Context) (string, autorest.Authorizer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.arm == nil {
+		return "", nil, errNotLoggedIn
+	}
+	sub := c.subscription
+	if sub == "" {
+		return "", nil, fmt.Errorf("no subscription set")
+	}
+	if subscriptionIDRE.MatchString(sub) {
+		return sub, c.arm, nil
+	}
+	if id, ok := c.subscriptionIDs[sub]; ok {
+		return id, c.arm, nil
+	}
+
+	cl := subscriptions.NewClientWithBaseURI(c.env().ResourceManagerEndpoint)
+	cl.Authorizer = c.arm
+	it, err := cl.ListComplete(ctx)
+	for ; err == nil && it.NotDone(); err = it.NextWithContext(ctx) {
+		s := it.Value()
+		if s.DisplayName != nil && s.SubscriptionID != nil && *s.DisplayName == sub {
+			if c.subscriptionIDs == nil {
+				c.subscriptionIDs = make(map[string]string)
+			}
+			c.subscriptionIDs[sub] = *s.SubscriptionID
+			return *s.SubscriptionID, c.arm, nil
+		}
+	}
+	if err != nil {
+		return "", nil, apiError("list subscriptions", err)
+	}
+
+	return "", nil, fmt.Errorf("subscription %s not found", sub)
+}
+
+// Env returns the Azure environment.
+func (c *SDK) env() *azenv.Environment {
+	if c.Environment != nil {
+		return c.Environment
+	}
+	return &azenv.PublicCloud
+}
+
+// VaultURL returns the base URL of vault.
+func (c *SDK) vaultURL(vault string) string {
+	u := c.VaultURL
+	if u == "" {
+		u = "https://{vault}." + c.env().KeyVaultDNSSuffix
+	}
+	return strings.ReplaceAll(u, "{vault}", vault)
+}
+
+// ErrNotLoggedIn is returned when a request is made before login.
+var errNotLoggedIn = errors.New("not logged in")
+
+// ApiError returns err as an *APIError.
+func apiError(op string, err error) error {
+	e := &APIError{
+		Op:      op,
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	var de autorest.DetailedError
+	if errors.As(err, &de) {
+		if sc, ok := de.StatusCode.(int); ok {
+			e.StatusCode = sc
+		}
+	}
+	var re *azenv.RequestError
+	if errors.As(err, &re) && re.ServiceError != nil {
+		e.Code, e.Message = re.ServiceError.Code, re.ServiceError.Message
+	}
+	var se *azenv.ServiceError
+	if errors.As(err, &se) {
+		e.Code, e.Message = se.Code, se.Message
+	}
+	var te adal.TokenRefreshError
+	if errors.As(err, &te) {
+		e.Code = "TokenRefreshFailed"
+		if r := te.Response(); r != nil {
+			e.StatusCode = r.StatusCode
+		}
+	}
+
+	return e
+}
+
+// NodepoolFromAgentPool returns an AKSNodepool with the values of ap.
+func nodepoolFromAgentPool(ap containerservice.AgentPool, resourceGroup string) AKSNodepool {
+	r := AKSNodepool{
+		ResourceGroup: resourceGroup,
+	}
+	if ap.Name != nil {
+		r.Name = *ap.Name
+	}
+	p := ap.ManagedClusterAgentPoolProfileProperties
+	if p == nil {
+		return r
+	}
+
+	r.AgentPoolType = string(p.Type)
+	r.Mode = string(p.Mode)
+	r.OSType = string(p.OsType)
+	if p.Count != nil {
+		r.Count = int(*p.Count)
+	}
+	if p.EnableAutoScaling != nil {
+		r.EnableAutoScaling = *p.EnableAutoScaling
+	}
+	if p.MaxCount != nil {
+		r.MaxCount = int(*p.MaxCount)
+	}
+	if p.MinCount != nil {
+		r.MinCount = int(*p.MinCount)
+	}
+	if p.MaxPods != nil {
+		r.MaxPods = int(*p.MaxPods)
+	}
+	if p.OrchestratorVersion != nil {
+		r.OrchestratorVersion = *p.OrchestratorVersion
+	}
+	if p.ProvisioningState != nil {
+		r.ProvisioningState = ProvisioningState(*p.ProvisioningState)
+	}
+	if p.VMSize != nil {
+		r.VMSize = *p.VMSize
+	}
+
+	return r
+}
@@ -0,0 +1,284 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	azenv "github.com/Azure/go-autorest/autorest/azure"
+	"github.com/mmlt/testr"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testSubscriptionID = "00000000-0000-0000-0000-000000000001"
+	testPoolPath       = "/subscriptions/" + testSubscriptionID + "/resourceGroups/rg/providers/Microsoft.ContainerService/managedClusters/cpe/agentPools"
+)
+
+// TestARM is a stand-in for the Azure AD, ARM and KeyVault endpoints.
+type testARM struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// pool is the state of the 'default' node pool of cluster 'cpe'.
+	pool map[string]interface{}
+	// polls is the number of async operation status requests.
+	polls int
+	// puts are the bodies of the node pool PUT requests.
+	puts []map[string]interface{}
+}
+
+func newTestARM(t *testing.T) *testARM {
+	a := &testARM{
+		pool: map[string]interface{}{
+			"count":               2,
+			"enableAutoScaling":   false,
+			"mode":                "System",
+			"orchestratorVersion": "1.20.7",
+			"osType":              "Linux",
+			"provisioningState":   "Succeeded",
+			"type":                "VirtualMachineScaleSets",
+			"vmSize":              "Standard_DS2_v2",
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tenant/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.Form.Get("client_secret") != "s3cret" && r.Form.Get("client_assertion") != "fed-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"AADSTS7000215: Invalid client secret"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"access_token":"tkn","token_type":"Bearer","expires_in":"3600","expires_on":"%d","resource":"%s"}`,
+			time.Now().Add(time.Hour).Unix(), r.Form.Get("resource"))
+	})
+	mux.HandleFunc("/subscriptions", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"value":[{"subscriptionId":"%s","displayName":"sub1"}]}`, testSubscriptionID)
+	})
+	mux.HandleFunc(testPoolPath, func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		fmt.Fprintf(w, `{"value":[%s]}`, a.poolJSON())
+	})
+	mux.HandleFunc(testPoolPath+"/default", func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if r.Method == http.MethodPut {
+			var body struct {
+				Properties map[string]interface{} `json:"properties"`
+			}
+			b, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(b, &body)
+			a.puts = append(a.puts, body.Properties)
+			for k, v := range body.Properties {
+				a.pool[k] = v
+			}
+			a.pool["provisioningState"] = "Upgrading"
+			w.Header().Set("Azure-AsyncOperation", a.URL+"/operations/1")
+			w.WriteHeader(http.StatusOK)
+		}
+		fmt.Fprint(w, a.poolJSON())
+	})
+	mux.HandleFunc("/operations/1", func(w http.ResponseWriter, r *http.Request) {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		a.polls++
+		if a.polls < 3 {
+			fmt.Fprint(w, `{"status":"InProgress"}`)
+			return
+		}
+		a.pool["provisioningState"] = "Succeeded"
+		fmt.Fprint(w, `{"status":"Succeeded"}`)
+	})
+	mux.HandleFunc("/secrets/envop/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tkn" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"value":"vault-value"}`)
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"error":{"code":"ResourceNotFound","message":"The Resource was not found."}}`)
+	})
+
+	a.Server = httptest.NewServer(mux)
+	t.Cleanup(a.Close)
+	return a
+}
+
+// PoolJSON returns the node pool as an ARM resource.
+func (a *testARM) poolJSON() string {
+	b, _ := json.Marshal(map[string]interface{}{
+		"id":         testPoolPath + "/default",
+		"name":       "default",
+		"properties": a.pool,
+	})
+	return string(b)
+}
+
+// Client returns an SDK client that uses the testARM.
+func (a *testARM) client(t *testing.T) *SDK {
+	env := azenv.PublicCloud
+	env.ActiveDirectoryEndpoint = a.URL + "/"
+	env.ResourceManagerEndpoint = a.URL + "/"
+	return &SDK{
+		Environment:  &env,
+		VaultURL:     a.URL,
+		PollingDelay: time.Millisecond,
+		Log:          testr.New(t),
+	}
+}
+
+func TestSDK_LoginSP(t *testing.T) {
+	tests := []struct {
+		it           string
+		secret       string
+		wantErr      bool
+		wantAuthFail bool
+	}{
+		{
+			it:     "should_login_with_valid_credentials",
+			secret: "s3cret",
+		},
+		{
+			it:           "should_return_auth_failure_with_invalid_credentials",
+			secret:       "wrong",
+			wantErr:      true,
+			wantAuthFail: true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			arm := newTestARM(t)
+			c := arm.client(t)
+
+			err := c.LoginSP("user", tst.secret, "tenant")
+			assert.Equal(t, tst.wantErr, err != nil, "error: %v", err)
+			assert.Equal(t, tst.wantAuthFail, IsAuthFailure(err), "IsAuthFailure")
+		})
+	}
+}
+
+func TestIsAuthFailure(t *testing.T) {
+	tests := []struct {
+		it   string
+		in   error
+		want bool
+	}{
+		{
+			it: "should_ignore_nil",
+		},
+		{
+			it:   "should_recognize_unauthorized_api_error",
+			in:   fmt.Errorf("vault: %w", &APIError{Op: "get secret", StatusCode: http.StatusUnauthorized}),
+			want: true,
+		},
+		{
+			it: "should_ignore_other_api_errors",
+			in: &APIError{Op: "get nodepool", StatusCode: http.StatusNotFound},
+		},
+		{
+			it:   "should_recognize_az_cli_invalid_secret",
+			in:   fmt.Errorf("login --service-principal: AADSTS7000215: Invalid client secret provided."),
+			want: true,
+		},
+		{
+			it:   "should_recognize_az_cli_not_logged_in",
+			in:   fmt.Errorf("keyvault secret show: ERROR: Please run 'az login' to setup account."),
+			want: true,
+		},
+		{
+			it: "should_ignore_other_az_cli_errors",
+			in: fmt.Errorf("keyvault secret show: ERROR: (SecretNotFound) A secret with (name/id) x was not found"),
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			assert.Equal(t, tst.want, IsAuthFailure(tst.in))
+		})
+	}
+}
+
+func TestSDK_LoginFederated(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sdk")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+
+	arm := newTestARM(t)
+	c := arm.client(t)
+
+	err = c.LoginFederated("user", "tenant", tokenFile)
+	assert.Error(t, err, "missing token file")
+
+	assert.NoError(t, ioutil.WriteFile(tokenFile, []byte("fed-token\n"), 0600))
+	err = c.LoginFederated("user", "tenant", tokenFile)
+	assert.NoError(t, err)
+}
+
+func TestSDK_KeyvaultSecret(t *testing.T) {
+	arm := newTestARM(t)
+	c := arm.client(t)
+
+	_, err := c.KeyvaultSecret("envop", "vault")
+	assert.EqualError(t, err, "not logged in")
+
+	assert.NoError(t, c.LoginSP("user", "s3cret", "tenant"))
+	got, err := c.KeyvaultSecret("envop", "vault")
+	assert.NoError(t, err)
+	assert.Equal(t, "vault-value", got)
+}
+
+func TestSDK_AKSNodepool(t *testing.T) {
+	arm := newTestARM(t)
+	c := arm.client(t)
+	if !assert.NoError(t, c.LoginSP("user", "s3cret", "tenant")) {
+		return
+	}
+	c.SetSubscription("sub1")
+
+	got, err := c.AKSNodepoolList(context.Background(), "rg", "cpe")
+	if assert.NoError(t, err) {
+		want := []AKSNodepool{{
+			AgentPoolType:       "VirtualMachineScaleSets",
+			Count:               2,
+			Mode:                "System",
+			Name:                "default",
+			OrchestratorVersion: "1.20.7",
+			OSType:              "Linux",
+			ProvisioningState:   Succeeded,
+			ResourceGroup:       "rg",
+			VMSize:              "Standard_DS2_v2",
+		}}
+		assert.Equal(t, want, got)
+	}
+
+	p, err := c.AKSNodepoolUpgrade(context.Background(), "rg", "cpe", "default", "1.21.2")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "1.21.2", p.OrchestratorVersion)
+		assert.Equal(t, Succeeded, p.ProvisioningState)
+	}
+	assert.Equal(t, 3, arm.polls, "number of async operation polls")
+
+	err = c.Autoscaler(context.Background(), true, "rg", "cpe", "default", 1, 5)
+	if assert.NoError(t, err) && assert.Len(t, arm.puts, 2) {
+		assert.Equal(t, true, arm.puts[1]["enableAutoScaling"])
+		assert.EqualValues(t, 1, arm.puts[1]["minCount"])
+		assert.EqualValues(t, 5, arm.puts[1]["maxCount"])
+	}
+
+	_, err = c.AKSNodepool(context.Background(), "rg", "missing", "default")
+	assert.True(t, IsNotFound(err), "IsNotFound")
+	assert.EqualError(t, err, "get nodepool: 404 ResourceNotFound: The Resource was not found.")
+}
@@ -1,6 +1,7 @@
 package kubectl
 
 import (
+	"context"
 	"github.com/ghodss/yaml"
 	"github.com/go-logr/logr"
 	"github.com/mmlt/environment-operator/pkg/util/exe"
@@ -13,31 +14,54 @@ import (
 type Kubectrler interface {
 	// PodState returns the state of a Pod in the cluster addressed by kubeconfigPath.
 	// State is FakePodRunning, FakePodCompleted, FakePodError or empty when no Pod is found.
-	PodState(kubeconfigPath, namespace, name string) (string, error)
+	PodState(ctx context.Context, kubeconfigPath, namespace, name string) (string, error)
 	// PodRun run a Pod
-	PodRun(kubeconfigPath, namespace, name, image, cmd string) error
+	PodRun(ctx context.Context, kubeconfigPath, namespace, name, image, cmd string) error
 	// PodLog returns the log of a Pod.
-	PodLog(kubeconfigPath, namespace, name string) (string, error)
+	PodLog(ctx context.Context, kubeconfigPath, namespace, name string) (string, error)
 	// PodDelete deletes a Pod.
-	PodDelete(kubeconfigPath, namespace, name string) error
+	PodDelete(ctx context.Context, kubeconfigPath, namespace, name string) error
 	// StorageClasses returns all the StorageClasses in the cluster addressed by kubeconfigPath.
-	StorageClasses(kubeconfigPath string) ([]storagev1.StorageClass, error)
+	StorageClasses(ctx context.Context, kubeconfigPath string) ([]storagev1.StorageClass, error)
+	// Nodes returns all the Nodes in the cluster addressed by kubeconfigPath.
+	Nodes(ctx context.Context, kubeconfigPath string) ([]v1.Node, error)
+	// Pods returns the Pods in namespace (all namespaces if empty) of the cluster addressed by kubeconfigPath.
+	Pods(ctx context.Context, kubeconfigPath, namespace string) ([]v1.Pod, error)
 	// WipeCluster removes resources so all cluster nodes can be drained without errors.
-	WipeCluster(kubeconfigPath string) error
+	WipeCluster(ctx context.Context, kubeconfigPath string) error
 }
 
 // Kubectl is able to perform kubectl cli commands.
 type Kubectl struct {
+	// Runner (optional) executes the kubectl commands, nil means an exe.Executor is used.
+	Runner exe.Runner
+
 	Log logr.Logger
 }
 
 var _ Kubectrler = &Kubectl{}
 
+// Run runs kubectl with args and returns stdout.
+func (k Kubectl) run(ctx context.Context, args ...string) (string, error) {
+	return k.runCommand(ctx, exe.Command{Name: "kubectl", Args: args})
+}
+
+// RunCommand runs kubectl cmd and returns stdout.
+func (k Kubectl) runCommand(ctx context.Context, cmd exe.Command) (string, error) {
+	var rn exe.Runner = &exe.Executor{Log: k.Log}
+	if k.Runner != nil {
+		rn = k.Runner
+	}
+	r, err := rn.Run(ctx, cmd)
+
+	return r.Stdout, err
+}
+
 // PodState returns the "Ready" status reason of the Pod in the cluster addressed by kubeconfigPath.
 // State is PodRunning, PodCompleted, ContainersNotReady or empty when no Pod is found.
-func (k Kubectl) PodState(kubeconfigPath, namespace, name string) (string, error) {
+func (k Kubectl) PodState(ctx context.Context, kubeconfigPath, namespace, name string) (string, error) {
 	args := []string{"--kubeconfig", kubeconfigPath, "-n", namespace, "get", "pod", name, "-o", "yaml"}
-	o, _, err := exe.Run(k.Log, nil, "", "kubectl", args...)
+	o, err := k.run(ctx, args...)
 	if err != nil && !strings.Contains(err.Error(), "Error from server (NotFound):") {
 		// It's not an exit status 1 - Error from server (NotFound): pods "preflight" not found
 		return "", err
@@ -62,34 +86,34 @@ func (k Kubectl) PodState(kubeconfigPath, namespace, name string) (string, error
 }
 
 // PodRun runs a Pod.
-func (k Kubectl) PodRun(kubeconfigPath, namespace, name, image, cmd string) error {
+func (k Kubectl) PodRun(ctx context.Context, kubeconfigPath, namespace, name, image, cmd string) error {
 	args := []string{"--kubeconfig", kubeconfigPath, "-n", namespace,
 		"run", "--restart", "OnFailure", "--image", image, name, "--", "sh", "-c", cmd}
-	_, _, err := exe.Run(k.Log, nil, "", "kubectl", args...)
+	_, err := k.run(ctx, args...)
 
 	return err
 }
 
 // PodLog returns the log of a Pod.
-func (k Kubectl) PodLog(kubeconfigPath, namespace, name string) (string, error) {
+func (k Kubectl) PodLog(ctx context.Context, kubeconfigPath, namespace, name string) (string, error) {
 	args := []string{"--kubeconfig", kubeconfigPath, "-n", namespace, "logs", name}
-	o, _, err := exe.Run(k.Log, nil, "", "kubectl", args...)
+	o, err := k.run(ctx, args...)
 
 	return o, err
 }
 
 // PodDelete deletes a Pod.
-func (k Kubectl) PodDelete(kubeconfigPath, namespace, name string) error {
+func (k Kubectl) PodDelete(ctx context.Context, kubeconfigPath, namespace, name string) error {
 	args := []string{"--kubeconfig", kubeconfigPath, "-n", namespace, "delete", "pod", name}
-	_, _, err := exe.Run(k.Log, nil, "", "kubectl", args...)
+	_, err := k.run(ctx, args...)
 
 	return err
 }
 
 // StorageClasses returns all the StorageClasses in the cluster addressed by kubeconfigPath.
-func (k Kubectl) StorageClasses(kubeconfigPath string) ([]storagev1.StorageClass, error) {
+func (k Kubectl) StorageClasses(ctx context.Context, kubeconfigPath string) ([]storagev1.StorageClass, error) {
 	args := []string{"--kubeconfig", kubeconfigPath, "get", "sc", "-o", "yaml"}
-	o, _, err := exe.Run(k.Log, nil, "", "kubectl", args...)
+	o, err := k.run(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -103,10 +127,49 @@ func (k Kubectl) StorageClasses(kubeconfigPath string) ([]storagev1.StorageClass
 	return r.Items, nil
 }
 
+// Nodes returns all the Nodes in the cluster addressed by kubeconfigPath.
+func (k Kubectl) Nodes(ctx context.Context, kubeconfigPath string) ([]v1.Node, error) {
+	args := []string{"--kubeconfig", kubeconfigPath, "get", "nodes", "-o", "yaml"}
+	o, err := k.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var r v1.NodeList
+	err = yaml.Unmarshal([]byte(o), &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Items, nil
+}
+
+// Pods returns the Pods in namespace (all namespaces if empty) of the cluster addressed by kubeconfigPath.
+func (k Kubectl) Pods(ctx context.Context, kubeconfigPath, namespace string) ([]v1.Pod, error) {
+	args := []string{"--kubeconfig", kubeconfigPath, "get", "pods", "-o", "yaml"}
+	if namespace == "" {
+		args = append(args, "--all-namespaces")
+	} else {
+		args = append(args, "-n", namespace)
+	}
+	o, err := k.run(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var r v1.PodList
+	err = yaml.Unmarshal([]byte(o), &r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Items, nil
+}
+
 // Namespaces returns all the Namespaces matching labelSelector in the cluster addressed by kubeconfigPath.
-func (k Kubectl) Namespaces(kubeconfigPath, labelSelector string) ([]v1.Namespace, error) {
+func (k Kubectl) Namespaces(ctx context.Context, kubeconfigPath, labelSelector string) ([]v1.Namespace, error) {
 	args := []string{"--kubeconfig", kubeconfigPath, "get", "ns", "-l", labelSelector, "-o", "yaml"}
-	o, _, err := exe.Run(k.Log, nil, "", "kubectl", args...)
+	o, err := k.run(ctx, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -122,24 +185,24 @@ func (k Kubectl) Namespaces(kubeconfigPath, labelSelector string) ([]v1.Namespac
 
 // WipeCluster removes resources so all cluster nodes can be drained without errors.
 // Arg kubeconfigRaw contains the kubeconfig file contents.
-func (k Kubectl) WipeCluster(kubeconfigPath string) error {
+func (k Kubectl) WipeCluster(ctx context.Context, kubeconfigPath string) error {
 	var err error
 	var args []string
 
 	args = []string{"--kubeconfig", kubeconfigPath, "delete", "validatingwebhookconfiguration", "--all", "--ignore-not-found=true"}
-	_, _, err = exe.Run(k.Log, nil, "", "kubectl", args...)
+	_, err = k.run(ctx, args...)
 	if err != nil {
 		return err
 	}
 
 	args = []string{"--kubeconfig", kubeconfigPath, "delete", "mutatingwebhookconfiguration", "--all", "--ignore-not-found=true"}
-	_, _, err = exe.Run(k.Log, nil, "", "kubectl", args...)
+	_, err = k.run(ctx, args...)
 	if err != nil {
 		return err
 	}
 
 	// delete namespace except those containing control-plane components
-	nss, err := k.Namespaces(kubeconfigPath, "control-plane!=true")
+	nss, err := k.Namespaces(ctx, kubeconfigPath, "control-plane!=true")
 	if err != nil {
 		return err
 	}
@@ -150,7 +213,8 @@ func (k Kubectl) WipeCluster(kubeconfigPath string) error {
 		}
 
 		args = []string{"--kubeconfig", kubeconfigPath, "delete", "namespace", ns.Name}
-		_, _, err = exe.Run(k.Log, nil, "", "kubectl", args...)
+		// no timeout as deleting a namespace waits for all its resources to be finalized.
+		_, err = k.runCommand(ctx, exe.Command{Name: "kubectl", Args: args, Timeout: -1})
 		if err != nil {
 			return err
 		}
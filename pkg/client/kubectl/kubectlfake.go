@@ -1,7 +1,9 @@
 package kubectl
 
 import (
+	"context"
 	"fmt"
+	v1 "k8s.io/api/core/v1"
 	storagev1 "k8s.io/api/storage/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -31,7 +33,7 @@ const (
 	FakePodError
 )
 
-func (k *KubectlFake) PodState(kubeconfigPath, namespace, name string) (string, error) {
+func (k *KubectlFake) PodState(ctx context.Context, kubeconfigPath, namespace, name string) (string, error) {
 	switch k.probePodState {
 	case FakePodRunning:
 		k.probePodState = FakePodCompleted // simulate completed after one call.
@@ -45,7 +47,7 @@ func (k *KubectlFake) PodState(kubeconfigPath, namespace, name string) (string,
 	}
 }
 
-func (k *KubectlFake) PodRun(kubeconfigPath, namespace, name, image, cmd string) error {
+func (k *KubectlFake) PodRun(ctx context.Context, kubeconfigPath, namespace, name, image, cmd string) error {
 	k.PodRunTally++
 
 	switch k.probePodState {
@@ -57,7 +59,7 @@ func (k *KubectlFake) PodRun(kubeconfigPath, namespace, name, image, cmd string)
 	}
 }
 
-func (k *KubectlFake) PodLog(kubeconfigPath, namespace, name string) (string, error) {
+func (k *KubectlFake) PodLog(ctx context.Context, kubeconfigPath, namespace, name string) (string, error) {
 	switch k.probePodState {
 	case FakePodRunning:
 		return "", nil
@@ -74,7 +76,7 @@ Retry 2020-09-08T07:01:06+0000
 	}
 }
 
-func (k *KubectlFake) PodDelete(kubeconfigPath, namespace, name string) error {
+func (k *KubectlFake) PodDelete(ctx context.Context, kubeconfigPath, namespace, name string) error {
 	k.PodDeleteTally++
 
 	switch k.probePodState {
@@ -87,7 +89,7 @@ func (k *KubectlFake) PodDelete(kubeconfigPath, namespace, name string) error {
 }
 
 // StorageClasses returns all the StorageClasses in the cluster addressed by kubeconfigPath.
-func (k KubectlFake) StorageClasses(kubeconfigPath string) ([]storagev1.StorageClass, error) {
+func (k KubectlFake) StorageClasses(ctx context.Context, kubeconfigPath string) ([]storagev1.StorageClass, error) {
 	r := []storagev1.StorageClass{
 		storagev1.StorageClass{
 			ObjectMeta: metav1.ObjectMeta{
@@ -101,8 +103,42 @@ func (k KubectlFake) StorageClasses(kubeconfigPath string) ([]storagev1.StorageC
 	return r, nil
 }
 
+// Nodes returns a single Ready Node.
+func (k KubectlFake) Nodes(ctx context.Context, kubeconfigPath string) ([]v1.Node, error) {
+	r := []v1.Node{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-0"},
+			Status: v1.NodeStatus{
+				Conditions: []v1.NodeCondition{
+					{Type: v1.NodeReady, Status: v1.ConditionTrue},
+				},
+			},
+		},
+	}
+	return r, nil
+}
+
+// Pods returns a single Running and Ready Pod.
+func (k KubectlFake) Pods(ctx context.Context, kubeconfigPath, namespace string) ([]v1.Pod, error) {
+	if namespace == "" {
+		namespace = "kube-system"
+	}
+	r := []v1.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "addon-0"},
+			Status: v1.PodStatus{
+				Phase: v1.PodRunning,
+				Conditions: []v1.PodCondition{
+					{Type: v1.PodReady, Status: v1.ConditionTrue},
+				},
+			},
+		},
+	}
+	return r, nil
+}
+
 // WipeCluster removes resources before cluster delete.
-func (k *KubectlFake) WipeCluster(kubeconfigPath string) error {
+func (k *KubectlFake) WipeCluster(ctx context.Context, kubeconfigPath string) error {
 	k.WipeClusterTally++
 
 	return nil
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/go-logr/logr"
-	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"strings"
 )
 
@@ -13,7 +12,8 @@ import (
 func (t *Terraform) GetPlan(ctx context.Context, env []string, dir string) (*gabs.Container, error) {
 	log := logr.FromContext(ctx).WithName("GetPlan")
 
-	o, _, err := exe.Run(log, &exe.Opt{Dir: dir, Env: env}, "", "terraform", "show",
+	// the plan contains the values of sensitive attributes.
+	o, err := t.runSecretOutput(ctx, log, env, dir, "show",
 		"-json", planName)
 	if err != nil {
 		return nil, err
@@ -84,6 +84,63 @@ const (
 	ActionDelete
 )
 
+// ActionReplace is a delete followed by a create or vice versa.
+const ActionReplace = ActionCreate | ActionDelete
+
+// IsReplace returns true when the action replaces a resource.
+func (a Action) IsReplace() bool {
+	return a&ActionReplace == ActionReplace
+}
+
+// ResourceChangesFromPlan parses a plan and returns all resources that are going to be created, updated or deleted.
+// The plan json conforms to https://www.terraform.io/docs/internals/json-format.html
+func ResourceChangesFromPlan(plan *gabs.Container) ([]ResourceChange, error) {
+	var r []ResourceChange
+	for _, chg := range plan.Path("resource_changes").Children() {
+		act := stringsToAction(chg.Path("change.actions").Children())
+		if act == 0 {
+			// no change
+			continue
+		}
+
+		address, ok := chg.Path("address").Data().(string)
+		if !ok {
+			return nil, fmt.Errorf("resource change without address")
+		}
+		typ, _ := chg.Path("type").Data().(string)
+
+		// tags after the change take precedence over tags before the change.
+		tags := map[string]string{}
+		for _, p := range []string{"change.before.tags", "change.after.tags"} {
+			for k, v := range chg.Path(p).ChildrenMap() {
+				if s, ok := v.Data().(string); ok {
+					tags[k] = s
+				}
+			}
+		}
+
+		r = append(r, ResourceChange{
+			Address: address,
+			Type:    typ,
+			Action:  act,
+			Tags:    tags,
+		})
+	}
+
+	return r, nil
+}
+
+// ResourceChange represents a planned change of a resource.
+type ResourceChange struct {
+	// Address is the absolute resource address, for example module.aks1.azurerm_kubernetes_cluster.this
+	Address string
+	// Type is the resource type, for example azurerm_kubernetes_cluster
+	Type   string
+	Action Action
+	// Tags are the resource tags (if any).
+	Tags map[string]string
+}
+
 // PoolsFromPlan parses a plan and returns AKS Pools that are going to be created, updated or deleted.
 // The plan json conforms to https://www.terraform.io/docs/internals/json-format.html
 func PoolsFromPlan(plan *gabs.Container) ([]AKSPool, error) {
@@ -48,6 +48,42 @@ func Test_PoolsFromPlan(t *testing.T) {
 	}
 }
 
+func Test_ResourceChangesFromPlan(t *testing.T) {
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "plan.json"))
+	assert.NoError(t, err)
+	json, err := gabs.ParseJSON(b)
+	assert.NoError(t, err)
+
+	got, err := ResourceChangesFromPlan(json)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// cat pkg/client/terraform/testdata/plan.json | jq '.resource_changes[] | select(.change.actions != ["no-op"]) | .address'
+	var addresses []string
+	for _, c := range got {
+		addresses = append(addresses, c.Address)
+	}
+	want := []string{
+		"azurerm_management_lock.env-sa",
+		"azurerm_monitor_diagnostic_setting.aks1",
+		"azurerm_role_assignment.sa1",
+		"azurerm_storage_account.sa1",
+		"azurerm_storage_container.velero1",
+		"module.aks1.azurerm_kubernetes_cluster.this",
+		`module.aks1.azurerm_kubernetes_cluster_node_pool.this["extra"]`,
+		`module.aks1.azurerm_kubernetes_cluster_node_pool.this["extra1"]`,
+		`module.aks1.azurerm_kubernetes_cluster_node_pool.this["extra2"]`,
+		`module.aks1.azurerm_kubernetes_cluster_node_pool.this["extra3"]`,
+	}
+	assert.Equal(t, want, addresses)
+
+	aks := got[5]
+	assert.Equal(t, "azurerm_kubernetes_cluster", aks.Type)
+	assert.Equal(t, ActionDelete, aks.Action)
+	assert.Equal(t, "KUBERNETES", aks.Tags["CIName"])
+}
+
 func Test_pathToMap(t *testing.T) {
 	tests := []struct {
 		it      string
@@ -0,0 +1,26 @@
+package terraform
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+)
+
+// State runs a 'terraform state' subcommand like list, show, mv or rm in dir and returns its output.
+func (t *Terraform) State(ctx context.Context, env []string, dir string, subcommand string, args ...string) (string, error) {
+	log := logr.FromContext(ctx).WithName("TFState")
+
+	a := append([]string{"state", subcommand}, args...)
+	o, err := t.run(ctx, log, env, dir, a...)
+
+	return o, err
+}
+
+// Import imports an existing infrastructure object with id into the terraform state at address.
+func (t *Terraform) Import(ctx context.Context, env []string, dir string, address, id string) (string, error) {
+	log := logr.FromContext(ctx).WithName("TFImport")
+
+	o, err := t.run(ctx, log, env, dir, "import",
+		"-input=false", "-no-color", address, id)
+
+	return o, err
+}
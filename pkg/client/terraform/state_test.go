@@ -0,0 +1,81 @@
+package terraform
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
+	"github.com/mmlt/testr"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTerraform_State(t *testing.T) {
+	tests := []struct {
+		it         string
+		subcommand string
+		args       []string
+		want       []string
+	}{
+		{
+			it:         "should run a subcommand without args",
+			subcommand: "list",
+			want:       []string{"state", "list"},
+		},
+		{
+			it:         "should pass args after the subcommand",
+			subcommand: "mv",
+			args:       []string{"module.a.x", "module.b.x"},
+			want:       []string{"state", "mv", "module.a.x", "module.b.x"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			rp := &exe.Replayer{Transcript: &exe.Transcript{Invocations: []exe.Invocation{
+				{Name: "terraform", Args: tst.want, Stdout: "out"},
+			}}}
+			tf := &Terraform{Runner: rp}
+			ctx := logr.NewContext(context.Background(), testr.New(t))
+
+			o, err := tf.State(ctx, nil, "/tmp/tf", tst.subcommand, tst.args...)
+
+			assert.NoError(t, err)
+			assert.Equal(t, "out", o)
+		})
+	}
+}
+
+func TestTerraform_Import(t *testing.T) {
+	tests := []struct {
+		it       string
+		exitCode int
+		wantErr  bool
+	}{
+		{
+			it: "should import non-interactively",
+		},
+		{
+			it:       "should return an error when terraform fails",
+			exitCode: 1,
+			wantErr:  true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			rp := &exe.Replayer{Transcript: &exe.Transcript{Invocations: []exe.Invocation{
+				{
+					Name:     "terraform",
+					Args:     []string{"import", "-input=false", "-no-color", "azurerm_resource_group.rg", "/subscriptions/s/resourceGroups/rg"},
+					Stdout:   "Import successful!",
+					ExitCode: tst.exitCode,
+				},
+			}}}
+			tf := &Terraform{Runner: rp}
+			ctx := logr.NewContext(context.Background(), testr.New(t))
+
+			o, err := tf.Import(ctx, nil, "/tmp/tf", "azurerm_resource_group.rg", "/subscriptions/s/resourceGroups/rg")
+
+			assert.Equal(t, tst.wantErr, err != nil)
+			assert.Equal(t, "Import successful!", o)
+		})
+	}
+}
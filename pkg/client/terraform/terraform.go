@@ -4,12 +4,11 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
-	"errors"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/go-logr/logr"
+	"github.com/mmlt/environment-operator/pkg/logstream"
 	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"io"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
@@ -22,11 +21,11 @@ type Terraformer interface {
 	// Plan creates an execution plan for the terraform configuration files in dir.
 	Plan(ctx context.Context, env []string, dir string) *TFResult
 	// StartApply applies the plan in dir without waiting for completion.
-	// If a Cmd is returned cmd.Wait() should be called wait for completion and clean-up.
-	StartApply(ctx context.Context, env []string, dir string) (*exec.Cmd, chan TFApplyResult, error)
+	// The returned channel reports progress and is closed on completion.
+	StartApply(ctx context.Context, env []string, dir string) (chan TFApplyResult, error)
 	// StartDestroy destroys the resources specified in the plan in dir without waiting for completion.
-	// If a Cmd is returned cmd.Wait() should be called wait for completion and clean-up.
-	StartDestroy(ctx context.Context, env []string, dir string) (*exec.Cmd, chan TFApplyResult, error)
+	// The returned channel reports progress and is closed on completion.
+	StartDestroy(ctx context.Context, env []string, dir string) (chan TFApplyResult, error)
 	// Output gets terraform output values an returns them as a map of types and values.
 	// When outputs.tf contains output "xyz" { value = 7 } the returned map contains ["yxz"]["value"] == 7
 	Output(ctx context.Context, env []string, dir string) (map[string]interface{}, error)
@@ -79,10 +78,41 @@ type TFApplyResult struct {
 }
 
 // Terraform provisions infrastructure using terraform cli.
-type Terraform struct{}
+type Terraform struct {
+	// Runner (optional) executes the terraform commands, nil means an exe.Executor is used.
+	Runner exe.Starter
+}
 
 var _ Terraformer = &Terraform{}
 
+// Runner returns the runner to execute terraform commands.
+func (t *Terraform) runner(log logr.Logger) exe.Starter {
+	if t.Runner != nil {
+		return t.Runner
+	}
+	return &exe.Executor{Log: log}
+}
+
+// Run runs terraform with args in dir and returns stdout.
+func (t *Terraform) run(ctx context.Context, log logr.Logger, env []string, dir string, args ...string) (string, error) {
+	r, err := t.runner(log).Run(ctx, exe.Command{Name: "terraform", Args: args, Dir: dir, Env: env})
+
+	return r.Stdout, err
+}
+
+// RunSecretOutput runs terraform with args in dir and returns stdout that contains secrets.
+// The output is not logged or recorded.
+func (t *Terraform) runSecretOutput(ctx context.Context, log logr.Logger, env []string, dir string, args ...string) (string, error) {
+	r, err := t.runner(log).Run(ctx, exe.Command{Name: "terraform", Args: args, Dir: dir, Env: env, SecretOutput: true})
+
+	return r.Stdout, err
+}
+
+// Start starts terraform with args in dir and returns its combined stdout and stderr.
+func (t *Terraform) start(ctx context.Context, log logr.Logger, env []string, dir string, args ...string) (io.ReadCloser, error) {
+	return t.runner(log).Start(ctx, exe.Command{Name: "terraform", Args: args, Dir: dir, Env: env})
+}
+
 // PlanName is the name of the terraform plan.
 const planName = "newplan"
 
@@ -90,7 +120,8 @@ const planName = "newplan"
 func (t *Terraform) Init(ctx context.Context, env []string, dir string) *TFResult {
 	log := logr.FromContext(ctx).WithName("TFInit")
 
-	o, _, err := exe.Run(log, &exe.Opt{Dir: dir, Env: env}, "", "terraform", "init", "-input=false", "-no-color")
+	o, err := t.run(ctx, log, env, dir, "init", "-input=false", "-no-color")
+	_, _ = io.WriteString(logstream.FromContext(ctx), o)
 
 	return parseInitResponse(o, err)
 }
@@ -119,8 +150,9 @@ func parseInitResponse(text string, err error) *TFResult {
 func (t *Terraform) Plan(ctx context.Context, env []string, dir string) *TFResult {
 	log := logr.FromContext(ctx).WithName("TFPlan")
 
-	o, _, err := exe.Run(log, &exe.Opt{Dir: dir, Env: env}, "", "terraform", "plan",
+	o, err := t.run(ctx, log, env, dir, "plan",
 		"-out="+planName, "-detailed-exitcode", "-input=false", "-no-color")
+	_, _ = io.WriteString(logstream.FromContext(ctx), o)
 	return parsePlanResponse(o, err)
 }
 
@@ -157,8 +189,7 @@ func parsePlanResponse(text string, err error) *TFResult {
 	//	0 = Succeeded with empty diff (no changes)
 	//  1 = Error
 	//  2 = Succeeded with non-empty diff (changes present)
-	var ee *exec.ExitError
-	if errors.As(err, &ee) && ee.ExitCode() == 1 {
+	if exe.ExitCode(err) == 1 {
 		r.Info = 0
 		r.Errors = append(r.Errors, err.Error())
 	}
@@ -167,77 +198,65 @@ func parsePlanResponse(text string, err error) *TFResult {
 }
 
 // StartApply applies the plan in dir without waiting for completion.
-// If a Cmd is returned cmd.Wait() should be called wait for completion and clean-up.
-func (t *Terraform) StartApply(ctx context.Context, env []string, dir string) (*exec.Cmd, chan TFApplyResult, error) {
+// The returned channel reports progress and is closed when terraform has completed.
+func (t *Terraform) StartApply(ctx context.Context, env []string, dir string) (chan TFApplyResult, error) {
 	log := logr.FromContext(ctx).WithName("TFApply")
 	ctx = logr.NewContext(ctx, log)
 
-	cmd := exe.RunAsync(ctx, log, &exe.Opt{Dir: dir, Env: env}, "", "terraform", "apply",
-		"-auto-approve", "-input=false", "-no-color", planName)
-
-	o, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	cmd.Stderr = cmd.Stdout // combine
-
-	err = cmd.Start()
+	o, err := t.start(ctx, log, env, dir, "apply", "-auto-approve", "-input=false", "-no-color", planName)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	ch := t.parseAsyncApplyResponse(log, o)
+	ch := t.parseAsyncApplyResponse(log, o, logstream.FromContext(ctx))
 
-	return cmd, ch, nil
+	return ch, nil
 }
 
 // StartDestroy destroys the resources specified in the plan in dir without waiting for completion.
-// If a Cmd is returned cmd.Wait() should be called wait for completion and clean-up.
-func (t *Terraform) StartDestroy(ctx context.Context, env []string, dir string) (*exec.Cmd, chan TFApplyResult, error) {
+// The returned channel reports progress and is closed when terraform has completed.
+func (t *Terraform) StartDestroy(ctx context.Context, env []string, dir string) (chan TFApplyResult, error) {
 	log := logr.FromContext(ctx).WithName("TFDestroy")
 	ctx = logr.NewContext(ctx, log)
 
-	cmd := exe.RunAsync(ctx, log, &exe.Opt{Dir: dir, Env: env}, "", "terraform", "destroy",
-		"-auto-approve", "-no-color")
-
-	o, err := cmd.StdoutPipe()
-	if err != nil {
-		return nil, nil, err
-	}
-
-	cmd.Stderr = cmd.Stdout // combine
-
-	err = cmd.Start()
+	o, err := t.start(ctx, log, env, dir, "destroy", "-auto-approve", "-no-color")
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	ch := t.parseAsyncApplyResponse(log, o)
+	ch := t.parseAsyncApplyResponse(log, o, logstream.FromContext(ctx))
 
-	return cmd, ch, nil
+	return ch, nil
 }
 
 // ParseAsyncApplyResponse parses in and returns results when interesting input is encountered.
+// All input lines are written to live as they arrive.
+// A read error (like a non-zero exit) is returned as an error of the last result.
 // Close in to release the go func.
-func (t *Terraform) parseAsyncApplyResponse(log logr.Logger, in io.ReadCloser) chan TFApplyResult {
+func (t *Terraform) parseAsyncApplyResponse(log logr.Logger, in io.ReadCloser, live io.Writer) chan TFApplyResult {
 	out := make(chan TFApplyResult)
 
 	// hold running totals.
 	result := &TFApplyResult{}
 
 	go func() {
+		var last TFApplyResult
 		sc := bufio.NewScanner(in)
 		for sc.Scan() {
 			s := sc.Text()
 			log.V(3).Info("RunAsync-result", "text", s)
+			_, _ = io.WriteString(live, s+"\n")
 			r := parseApplyResponseLine(result, s)
 			if r != nil {
+				last = *r
 				out <- *r
 			}
 		}
 		if err := sc.Err(); err != nil {
+			// terraform failed (non-zero exit) or its output couldn't be read.
 			log.Error(err, "parseAsyncApplyResponse")
+			last.Errors = append(last.Errors, err.Error())
+			out <- last
 		}
 
 		close(out)
@@ -341,7 +360,8 @@ func normalizeAction(s string) string {
 func (t *Terraform) Output(ctx context.Context, env []string, dir string) (map[string]interface{}, error) {
 	log := logr.FromContext(ctx).WithName("TFOutput")
 
-	o, _, err := exe.Run(log, &exe.Opt{Dir: dir, Env: env}, "", "terraform", "output", "-json", "-no-color")
+	// outputs contain credentials like kube_admin_config.
+	o, err := t.runSecretOutput(ctx, log, env, dir, "output", "-json", "-no-color")
 	if err != nil {
 		return nil, err
 	}
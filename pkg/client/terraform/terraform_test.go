@@ -1,11 +1,19 @@
 package terraform
 
 import (
+	"bytes"
+	"context"
+	"github.com/go-logr/logr"
+	"github.com/mmlt/environment-operator/pkg/util/exe"
 	"github.com/mmlt/testr"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -523,7 +531,8 @@ func TestParseAsyncApplyResponse(t *testing.T) {
 			rd, wr := io.Pipe()
 
 			// start parser
-			ch := tf.parseAsyncApplyResponse(log, rd)
+			live := &bytes.Buffer{}
+			ch := tf.parseAsyncApplyResponse(log, rd, live)
 
 			// send input
 			go func() {
@@ -543,6 +552,7 @@ func TestParseAsyncApplyResponse(t *testing.T) {
 			}
 
 			assert.Equal(t, tst.want, rs)
+			assert.Equal(t, strings.Join(tst.in, ""), live.String(), "all input lines are written live")
 		})
 	}
 }
@@ -552,3 +562,101 @@ func newExitError(code int) error {
 	cmd := exec.Command("exit", strconv.Itoa(code))
 	return cmd.Run()
 }
+
+func TestTerraform_Plan(t *testing.T) {
+	tests := []struct {
+		it         string
+		invocation exe.Invocation
+		want       TFResult
+	}{
+		{
+			it:         "must report a plan with changes",
+			invocation: exe.Invocation{Stdout: "Plan: 1 to add, 2 to change, 3 to destroy.", ExitCode: 2},
+			want:       TFResult{Info: 1, PlanAdded: 1, PlanChanged: 2, PlanDeleted: 3, Text: "Plan: 1 to add, 2 to change, 3 to destroy."},
+		},
+		{
+			it:         "must report errors",
+			invocation: exe.Invocation{Stdout: "Error: boom", ExitCode: 1},
+			want: TFResult{Errors: []string{"terraform [plan -out=newplan -detailed-exitcode -input=false -no-color]: exit status 1 - "},
+				Text: "Error: boom"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			inv := tst.invocation
+			inv.Name = "terraform"
+			inv.Args = []string{"plan", "-out=newplan", "-detailed-exitcode", "-input=false", "-no-color"}
+			tf := &Terraform{Runner: &exe.Replayer{Transcript: &exe.Transcript{Invocations: []exe.Invocation{inv}}}}
+
+			got := tf.Plan(logr.NewContext(context.Background(), testr.New(t)), nil, "/tmp/tf")
+
+			assert.Equal(t, tst.want, *got)
+		})
+	}
+}
+
+func TestTerraform_StartApply(t *testing.T) {
+	tf := &Terraform{Runner: &exe.Replayer{Transcript: &exe.Transcript{Invocations: []exe.Invocation{
+		{
+			Name:  "terraform",
+			Args:  []string{"apply", "-auto-approve", "-input=false", "-no-color", "newplan"},
+			Async: true,
+			Stdout: "module.aks1.azurerm_kubernetes_cluster.this: Creating...\n" +
+				"Error: creating Managed Kubernetes Cluster: QuotaExceeded\n",
+			ExitCode: 1,
+		},
+	}}}}
+
+	ch, err := tf.StartApply(logr.NewContext(context.Background(), testr.New(t)), nil, "/tmp/tf")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var last TFApplyResult
+	for r := range ch {
+		last = r
+	}
+	assert.Equal(t, 1, last.Creating)
+	if assert.Len(t, last.Errors, 2) {
+		assert.Equal(t, "creating Managed Kubernetes Cluster: QuotaExceeded", last.Errors[0])
+		assert.Contains(t, last.Errors[1], "exit status 1", "non-zero exit is an error")
+	}
+}
+
+func TestTerraform_recordSecretOutput(t *testing.T) {
+	const credential = "c2VjcmV0LWt1YmVjb25maWc="
+	dir, err := ioutil.TempDir("", "terraform")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "transcript.yaml")
+
+	rp := &exe.Replayer{Transcript: &exe.Transcript{Invocations: []exe.Invocation{
+		{
+			Name:   "terraform",
+			Args:   []string{"output", "-json", "-no-color"},
+			Stdout: `{"kube_admin_config_raw":{"sensitive":true,"type":"string","value":"` + credential + `"}}`,
+		},
+		{
+			Name:   "terraform",
+			Args:   []string{"show", "-json", "newplan"},
+			Stdout: `{"resource_changes":[{"change":{"after":{"kube_admin_config_raw":"` + credential + `"}}}]}`,
+		},
+	}}}
+	tf := &Terraform{Runner: &exe.Recorder{Runner: rp, Path: path}}
+	ctx := logr.NewContext(context.Background(), testr.New(t))
+
+	out, err := tf.Output(ctx, nil, "/tmp/tf")
+	assert.NoError(t, err)
+	assert.Contains(t, out, "kube_admin_config_raw", "output is returned to the caller")
+	_, err = tf.GetPlan(ctx, nil, "/tmp/tf")
+	assert.NoError(t, err)
+
+	b, err := ioutil.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, string(b), "show")
+	assert.NotContains(t, string(b), credential)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/go-logr/logr"
-	"os/exec"
 	"time"
 )
 
@@ -42,7 +41,7 @@ func (t *TerraformFake) Plan(ctx context.Context, env []string, dir string) *TFR
 }
 
 // StartApply implements Terraformer.
-func (t *TerraformFake) StartApply(ctx context.Context, env []string, dir string) (*exec.Cmd, chan TFApplyResult, error) {
+func (t *TerraformFake) StartApply(ctx context.Context, env []string, dir string) (chan TFApplyResult, error) {
 	t.ApplyTally++
 
 	out := make(chan TFApplyResult)
@@ -60,11 +59,11 @@ func (t *TerraformFake) StartApply(ctx context.Context, env []string, dir string
 		close(out)
 	}()
 
-	return nil, out, nil
+	return out, nil
 }
 
 // StartDestroy implements Terraformer.
-func (t *TerraformFake) StartDestroy(ctx context.Context, env []string, dir string) (*exec.Cmd, chan TFApplyResult, error) {
+func (t *TerraformFake) StartDestroy(ctx context.Context, env []string, dir string) (chan TFApplyResult, error) {
 	t.DestroyTally++
 
 	out := make(chan TFApplyResult)
@@ -82,7 +81,7 @@ func (t *TerraformFake) StartDestroy(ctx context.Context, env []string, dir stri
 		close(out)
 	}()
 
-	return nil, out, nil
+	return out, nil
 }
 
 // Output implements Terraformer.
@@ -146,7 +145,42 @@ func (t *TerraformFake) SetupFakeResultsForCreate(clusters map[string]interface{
 		{Creating: 1, Modifying: 2, Destroying: 1, Object: "module.aks1.azurerm_kubernetes_cluster.this", Action: "creation", Elapsed: "6m22s"},
 		{Creating: 1, Modifying: 2, Destroying: 1, TotalAdded: 1, TotalChanged: 2, TotalDestroyed: 1, Object: "", Action: "", Elapsed: ""}}
 
-	t.ShowPlanResult = "{}"
+	// note that the following is only a fragment of a 'terraform plan' result,
+	// see pkg/client/terraform/testdata for complete examples"
+	t.ShowPlanResult = `{
+  "resource_changes": [
+    {
+      "address": "azurerm_route_table.env",
+      "type": "azurerm_route_table",
+      "change": {
+        "actions": [
+          "update"
+        ]
+      }
+    },
+    {
+      "address": "module.aks1.azurerm_subnet.this",
+      "module_address": "module.aks1",
+      "type": "azurerm_subnet",
+      "change": {
+        "actions": [
+          "update"
+        ]
+      }
+    },
+    {
+      "address": "module.aks1.azurerm_kubernetes_cluster.this",
+      "module_address": "module.aks1",
+      "type": "azurerm_kubernetes_cluster",
+      "change": {
+        "actions": [
+          "delete",
+          "create"
+        ]
+      }
+    }
+  ]
+}`
 
 	t.OutputResult = map[string]interface{}{
 		"clusters": map[string]interface{}{
@@ -0,0 +1,94 @@
+package cloud
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"k8s.io/apimachinery/pkg/types"
+	"sync"
+)
+
+// Account is the cloud access of an Environment.
+type Account struct {
+	// Cloud provides generic cloud access functions.
+	Cloud Cloud
+	// Azure is the azure client that is logged in by Cloud.
+	Azure azure.AZer
+}
+
+// Accounts keeps an Account per Environment that has its own credentials spec.
+type Accounts struct {
+	// Default is the Account of Environments without credentials spec.
+	Default Account
+	// New returns an Account for Environment nsn with credentials spec.
+	// CredentialsFn returns the content of the spec Secret.
+	New func(nsn types.NamespacedName, spec v1.CredentialsSpec, credentialsFn func() ([]byte, error)) Account
+	// Cleanup (optional) is called when the Account of Environment nsn is removed, for example to remove its
+	// az cli config dir.
+	Cleanup func(nsn types.NamespacedName)
+
+	mu       sync.Mutex
+	accounts map[types.NamespacedName]account
+}
+
+// Account is an Account and the spec it's created from.
+type account struct {
+	Account
+	spec v1.CredentialsSpec
+}
+
+// Set returns the Account of Environment nsn with credentials spec.
+// An Account is created when the spec is set for the first time or when it has changed, the Account is reused
+// otherwise so its login is kept.
+// An empty spec results in the Default Account.
+func (a *Accounts) Set(nsn types.NamespacedName, spec v1.CredentialsSpec, credentialsFn func() ([]byte, error)) Account {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if spec == (v1.CredentialsSpec{}) {
+		a.delete(nsn)
+		return a.Default
+	}
+
+	if acc, ok := a.accounts[nsn]; ok && acc.spec == spec {
+		return acc.Account
+	}
+
+	if a.accounts == nil {
+		a.accounts = make(map[types.NamespacedName]account)
+	}
+	acc := account{Account: a.New(nsn, spec, credentialsFn), spec: spec}
+	a.accounts[nsn] = acc
+	return acc.Account
+}
+
+// Get returns the Account of Environment nsn as set by Set.
+// The Default Account is returned when Environment nsn has no Account.
+func (a *Accounts) Get(nsn types.NamespacedName) Account {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if acc, ok := a.accounts[nsn]; ok {
+		return acc.Account
+	}
+	return a.Default
+}
+
+// Delete removes the Account of Environment nsn, typically because the Environment is deleted.
+func (a *Accounts) Delete(nsn types.NamespacedName) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.delete(nsn)
+}
+
+// Delete removes the Account of Environment nsn (if any).
+// The caller must hold a.mu.
+func (a *Accounts) delete(nsn types.NamespacedName) {
+	if _, ok := a.accounts[nsn]; !ok {
+		return
+	}
+	delete(a.accounts, nsn)
+	if a.Cleanup != nil {
+		a.Cleanup(nsn)
+	}
+}
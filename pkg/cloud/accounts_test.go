@@ -0,0 +1,51 @@
+package cloud
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestAccounts(t *testing.T) {
+	def := &Fake{}
+	var created int
+	var cleaned []types.NamespacedName
+	a := &Accounts{
+		Default: Account{Cloud: def},
+		New: func(nsn types.NamespacedName, spec v1.CredentialsSpec, credentialsFn func() ([]byte, error)) Account {
+			created++
+			return Account{Cloud: &Azure{Account: nsn.String(), Vault: spec.Vault, CredentialsFn: credentialsFn}}
+		},
+		Cleanup: func(nsn types.NamespacedName) {
+			cleaned = append(cleaned, nsn)
+		},
+	}
+	nsn := types.NamespacedName{Namespace: "default", Name: "env1"}
+	creds := func() ([]byte, error) { return nil, nil }
+
+	assert.Same(t, def, a.Set(nsn, v1.CredentialsSpec{}, creds).Cloud, "no spec uses default")
+	assert.Equal(t, 0, created)
+
+	spec := v1.CredentialsSpec{SecretName: "env1-sp", Vault: "kv1"}
+	acc := a.Set(nsn, spec, creds)
+	assert.Equal(t, "kv1", acc.Cloud.(*Azure).Vault)
+	assert.Same(t, acc.Cloud, a.Set(nsn, spec, creds).Cloud, "same spec reuses account")
+	assert.Same(t, acc.Cloud, a.Get(nsn).Cloud)
+	assert.Equal(t, 1, created)
+
+	spec.Vault = "kv2"
+	assert.Equal(t, "kv2", a.Set(nsn, spec, creds).Cloud.(*Azure).Vault, "changed spec creates account")
+	assert.Equal(t, 2, created)
+
+	assert.Same(t, def, a.Set(nsn, v1.CredentialsSpec{}, creds).Cloud, "removed spec uses default")
+	assert.Same(t, def, a.Get(nsn).Cloud)
+	assert.Same(t, def, a.Get(types.NamespacedName{Name: "unknown"}).Cloud)
+	assert.Equal(t, []types.NamespacedName{nsn}, cleaned, "removed spec cleans up")
+
+	a.Set(nsn, spec, creds)
+	a.Delete(nsn)
+	assert.Same(t, def, a.Get(nsn).Cloud, "deleted environment uses default")
+	a.Delete(nsn)
+	assert.Equal(t, []types.NamespacedName{nsn, nsn}, cleaned, "deleted environment cleans up once")
+}
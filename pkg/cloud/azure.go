@@ -1,67 +1,219 @@
 package cloud
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/mmlt/environment-operator/pkg/client/azure"
 	gocache "github.com/patrickmn/go-cache"
 	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 )
 
 type Azure struct {
 	Client azure.AZer
+	// Identity selects how to login, zero value means IdentitySP.
+	Identity Identity
 	// CredentialsFile is the path to a JSON formatted file containing client_id, client_secret and tenant of a
 	// ServicePrincipal that is allowed to access the MasterKeyVault and AzureRM.
+	// It's only used by IdentitySP.
 	CredentialsFile string
+	// CredentialsFn (optional) returns the JSON formatted ServicePrincipal, it takes precedence over CredentialsFile.
+	// It's only used by IdentitySP.
+	CredentialsFn func() ([]byte, error)
+	// Account identifies the credentials in metrics, empty means "default".
+	Account string
 	// Vault is the name of the KeyVault to access.
 	Vault string
 
 	Log logr.Logger
 
+	// Mu serializes logins.
+	mu sync.Mutex
 	// Secret contains the envop SP after first successful login.
 	secret *ServicePrincipal
+	// Credentials is the content of CredentialsFile at the last successful login.
+	credentials []byte
 
 	// Cache contains response values to support rate-limiting.
 	cache *gocache.Cache
 }
 
+// Identity is the kind of Azure identity envop uses.
+type Identity string
+
+const (
+	// IdentitySP is a ServicePrincipal with a client secret read from the credentials file.
+	IdentitySP Identity = "sp"
+	// IdentityMSI is the managed identity of the host.
+	// The AZURE_CLIENT_ID environment variable (optional) selects a user assigned identity.
+	IdentityMSI Identity = "msi"
+	// IdentityWorkload is an AKS workload identity.
+	// The AZURE_CLIENT_ID, AZURE_TENANT_ID and AZURE_FEDERATED_TOKEN_FILE environment variables are set by the
+	// workload identity webhook.
+	IdentityWorkload Identity = "workload"
+)
+
+// Identities are the valid Identity values.
+var Identities = []Identity{IdentitySP, IdentityMSI, IdentityWorkload}
+
 // Login performs a cloud provider login (a prerequisite for most cli commands)
+// The login is reused until the content of CredentialsFile changes (secret rotation) or an auth failure occurs.
 func (a *Azure) Login() (*ServicePrincipal, error) {
-	if a.secret != nil {
-		return a.secret, nil
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.login("")
+}
+
+// Relogin discards the current login and performs a new one.
+// Reason is the cause of the relogin as reported by the logins metric.
+func (a *Azure) relogin(reason string) (*ServicePrincipal, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.secret = nil
+	return a.login(reason)
+}
+
+// Watch checks the credentials every interval and logs in again when its content has changed.
+// The file is polled instead of watched for events because a mounted Secret is updated by swapping symlinks.
+// Watch returns when ctx is done.
+func (a *Azure) Watch(ctx context.Context, interval time.Duration) {
+	if a.identity() != IdentitySP {
+		return
 	}
 
-	// get login secret
-	b, err := ioutil.ReadFile(a.CredentialsFile)
-	if err != nil {
-		return nil, err
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			_, err := a.Login()
+			if err != nil {
+				a.Log.Error(err, "watch credentials")
+			}
+		case <-ctx.Done():
+			return
+		}
 	}
-	var sp ServicePrincipal
-	err = json.Unmarshal(b, &sp)
-	if err != nil {
-		return nil, fmt.Errorf("SP JSON: %w", err)
+}
+
+// Login performs a login when there is no login yet or when the content of the CredentialsFile has changed.
+// Reason is the cause of the login, empty means the reason is derived from the current state.
+func (a *Azure) login(reason string) (*ServicePrincipal, error) {
+	var creds []byte
+	if a.identity() == IdentitySP {
+		b, err := a.readCredentials()
+		if err != nil {
+			return nil, err
+		}
+		creds = b
 	}
-	if len(sp.ClientID) == 0 || len(sp.ClientSecret) == 0 || len(sp.Tenant) == 0 {
-		return nil, fmt.Errorf("login secret: client_id, cliebnt_secret or tenant field not set")
+	if a.secret != nil && bytes.Equal(creds, a.credentials) {
+		return a.secret, nil
+	}
+	if reason == "" {
+		reason = "initial"
+		if a.secret != nil {
+			reason = "credentials_changed"
+		}
 	}
 
-	// login
-	err = a.Client.LoginSP(sp.ClientID, sp.ClientSecret, sp.Tenant)
+	var (
+		sp  *ServicePrincipal
+		err error
+	)
+	switch a.identity() {
+	case IdentitySP:
+		sp, err = parseServicePrincipal(creds)
+		if err != nil {
+			return nil, err
+		}
+		err = a.Client.LoginSP(sp.ClientID, sp.ClientSecret, sp.Tenant)
+	case IdentityMSI:
+		sp = &ServicePrincipal{
+			ClientID: os.Getenv("AZURE_CLIENT_ID"),
+			Tenant:   os.Getenv("AZURE_TENANT_ID"),
+			UseMSI:   true,
+		}
+		err = a.Client.LoginMSI(sp.ClientID)
+	case IdentityWorkload:
+		sp = &ServicePrincipal{
+			ClientID:           os.Getenv("AZURE_CLIENT_ID"),
+			Tenant:             os.Getenv("AZURE_TENANT_ID"),
+			FederatedTokenFile: os.Getenv("AZURE_FEDERATED_TOKEN_FILE"),
+		}
+		if sp.ClientID == "" || sp.Tenant == "" || sp.FederatedTokenFile == "" {
+			return nil, fmt.Errorf("workload identity: AZURE_CLIENT_ID, AZURE_TENANT_ID or AZURE_FEDERATED_TOKEN_FILE not set")
+		}
+		err = a.Client.LoginFederated(sp.ClientID, sp.Tenant, sp.FederatedTokenFile)
+	default:
+		return nil, fmt.Errorf("unknown identity: %s", a.Identity)
+	}
+	logins.WithLabelValues(a.account(), reason, strconv.FormatBool(err == nil)).Inc()
 	if err != nil {
 		return nil, err
 	}
 
-	a.secret = &sp
+	a.secret = sp
+	a.credentials = creds
+	var expiry float64
+	if sp.ExpiresOn != nil {
+		expiry = float64(sp.ExpiresOn.Unix())
+	}
+	credentialsExpiry.WithLabelValues(a.account()).Set(expiry)
 
-	a.Log.Info("Logged in")
+	a.Log.Info("Logged in", "identity", a.identity(), "reason", reason)
 
 	return a.secret, nil
 }
 
+// ReadCredentials returns the JSON formatted ServicePrincipal from CredentialsFn or CredentialsFile.
+func (a *Azure) readCredentials() ([]byte, error) {
+	if a.CredentialsFn != nil {
+		return a.CredentialsFn()
+	}
+	return ioutil.ReadFile(a.CredentialsFile)
+}
+
+// Account returns the name that identifies the credentials in metrics.
+func (a *Azure) account() string {
+	if a.Account == "" {
+		return "default"
+	}
+	return a.Account
+}
+
+// Identity returns the Identity to login with.
+func (a *Azure) identity() Identity {
+	if a.Identity == "" {
+		return IdentitySP
+	}
+	return a.Identity
+}
+
+// ParseServicePrincipal parses a JSON formatted ServicePrincipal.
+func parseServicePrincipal(b []byte) (*ServicePrincipal, error) {
+	var sp ServicePrincipal
+	err := json.Unmarshal(b, &sp)
+	if err != nil {
+		return nil, fmt.Errorf("SP JSON: %w", err)
+	}
+	if len(sp.ClientID) == 0 || len(sp.ClientSecret) == 0 || len(sp.Tenant) == 0 {
+		return nil, fmt.Errorf("login secret: client_id, cliebnt_secret or tenant field not set")
+	}
+	return &sp, nil
+}
+
 // VaultGet reads a secret from a vault.
 // Vault access is rate limited to once per 5m.
+// An auth failure results in a new login and a retry.
 func (a *Azure) VaultGet(name, field string) (string, error) {
 	_, err := a.Login()
 	if err != nil {
@@ -78,6 +230,14 @@ func (a *Azure) VaultGet(name, field string) (string, error) {
 		v = x.(string)
 	} else {
 		v, err = a.Client.KeyvaultSecret(name, a.Vault)
+		if azure.IsAuthFailure(err) {
+			a.Log.Info("Auth failure, login again", "error", err.Error())
+			_, err = a.relogin("auth_failure")
+			if err != nil {
+				return "", fmt.Errorf("login: %w", err)
+			}
+			v, err = a.Client.KeyvaultSecret(name, a.Vault)
+		}
 		if err != nil {
 			return "", err
 		}
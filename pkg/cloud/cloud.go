@@ -1,6 +1,8 @@
 // Package cloud provides cloud generic operations.
 package cloud
 
+import "time"
+
 type Cloud interface {
 	// Login perform cloud provider login.
 	Login() (*ServicePrincipal, error)
@@ -8,8 +10,18 @@ type Cloud interface {
 	VaultGet(name, field string) (string, error)
 }
 
+// ServicePrincipal is the identity that is used to access the cloud.
 type ServicePrincipal struct {
 	ClientID     string `json:"client_id"`
 	ClientSecret string `json:"client_secret"`
 	Tenant       string `json:"tenant"`
+	// ExpiresOn (optional) is the time the client secret expires.
+	// It's not looked up in Azure AD, when it's not in the credentials file the expiry is unknown.
+	ExpiresOn *time.Time `json:"expires_on,omitempty"`
+
+	// UseMSI is true when the managed identity of the host is used instead of a client secret.
+	// ClientID (optional) selects a user assigned identity.
+	UseMSI bool `json:"-"`
+	// FederatedTokenFile is the path of a federated token (workload identity) that is used instead of a client secret.
+	FederatedTokenFile string `json:"-"`
 }
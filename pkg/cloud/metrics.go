@@ -0,0 +1,23 @@
+package cloud
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// CredentialsExpiry is the time the credentials of an account expire, zero when unknown.
+	credentialsExpiry = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "envop_credentials_expiry_timestamp_seconds",
+		Help: "Time the envop credentials expire in seconds since epoch, 0 when unknown.",
+	}, []string{"account"})
+	// Logins counts the cloud logins by reason (initial, credentials_changed, auth_failure) and success.
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "envop_credentials_logins_total",
+		Help: "Number of cloud logins by account, reason and success.",
+	}, []string{"account", "reason", "success"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(credentialsExpiry, logins)
+}
@@ -0,0 +1,77 @@
+package cloud
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+)
+
+// ReloginAZ is an azure.AZer that logs in again and retries once when a call fails with an auth failure.
+// It's used by the steps so they recover from rotated or revoked credentials like VaultGet does.
+type reloginAZ struct {
+	azure.AZer
+	relogin func(reason string) (*ServicePrincipal, error)
+	log     logr.Logger
+}
+
+// AZ returns the Client of a that logs in again when a call fails with an auth failure.
+func (a *Azure) AZ() azure.AZer {
+	return &reloginAZ{AZer: a.Client, relogin: a.relogin, log: a.Log}
+}
+
+// Retry calls fn and calls it again after a new login when it fails with an auth failure.
+func (c *reloginAZ) retry(fn func() error) error {
+	err := fn()
+	if !azure.IsAuthFailure(err) {
+		return err
+	}
+	if c.log != nil {
+		c.log.Info("Auth failure, login again", "error", err.Error())
+	}
+	if _, lerr := c.relogin("auth_failure"); lerr != nil {
+		return err
+	}
+	return fn()
+}
+
+// AKSNodepoolList implements azure.AZer.
+func (c *reloginAZ) AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) (r []azure.AKSNodepool, err error) {
+	err = c.retry(func() error {
+		r, err = c.AZer.AKSNodepoolList(ctx, resourceGroup, cluster)
+		return err
+	})
+	return
+}
+
+// AKSNodepool implements azure.AZer.
+func (c *reloginAZ) AKSNodepool(ctx context.Context, resourceGroup, cluster, nodepool string) (r *azure.AKSNodepool, err error) {
+	err = c.retry(func() error {
+		r, err = c.AZer.AKSNodepool(ctx, resourceGroup, cluster, nodepool)
+		return err
+	})
+	return
+}
+
+// AKSNodepoolUpgrade implements azure.AZer.
+func (c *reloginAZ) AKSNodepoolUpgrade(ctx context.Context, resourceGroup, cluster, nodepool, version string) (r *azure.AKSNodepool, err error) {
+	err = c.retry(func() error {
+		r, err = c.AZer.AKSNodepoolUpgrade(ctx, resourceGroup, cluster, nodepool, version)
+		return err
+	})
+	return
+}
+
+// Autoscaler implements azure.AZer.
+func (c *reloginAZ) Autoscaler(ctx context.Context, enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
+	return c.retry(func() error {
+		return c.AZer.Autoscaler(ctx, enable, resourceGroup, cluster, pool, minCount, maxCount)
+	})
+}
+
+// AllAutoscalers implements azure.AZer.
+func (c *reloginAZ) AllAutoscalers(ctx context.Context, enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
+	return c.retry(func() error {
+		return c.AZer.AllAutoscalers(ctx, enable, clusters, resourceGroup, log)
+	})
+}
@@ -0,0 +1,94 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"github.com/go-logr/logr"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+// NodepoolsAZ is an azure.AZer that returns errs[i] on the i-th AKSNodepoolList call.
+type nodepoolsAZ struct {
+	azure.AZer
+	errs  []error
+	calls int
+}
+
+func (c *nodepoolsAZ) AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) ([]azure.AKSNodepool, error) {
+	err := c.errs[c.calls]
+	c.calls++
+	if err != nil {
+		return nil, err
+	}
+	return []azure.AKSNodepool{{Name: "default"}}, nil
+}
+
+func TestReloginAZ(t *testing.T) {
+	authErr := &azure.APIError{StatusCode: http.StatusUnauthorized}
+	otherErr := errors.New("boom")
+	tests := []struct {
+		it          string
+		errs        []error
+		loginErr    error
+		wantCalls   int
+		wantRelogin int
+		wantErr     error
+	}{
+		{
+			it:        "should not login again on success",
+			errs:      []error{nil},
+			wantCalls: 1,
+		},
+		{
+			it:        "should not login again on other errors",
+			errs:      []error{otherErr},
+			wantCalls: 1,
+			wantErr:   otherErr,
+		},
+		{
+			it:          "should login again and retry once on an auth failure",
+			errs:        []error{authErr, nil},
+			wantCalls:   2,
+			wantRelogin: 1,
+		},
+		{
+			it:          "should return the auth failure when it persists",
+			errs:        []error{authErr, authErr},
+			wantCalls:   2,
+			wantRelogin: 1,
+			wantErr:     authErr,
+		},
+		{
+			it:          "should return the auth failure when the login fails",
+			errs:        []error{authErr},
+			loginErr:    otherErr,
+			wantCalls:   1,
+			wantRelogin: 1,
+			wantErr:     authErr,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			fake := &nodepoolsAZ{errs: tst.errs}
+			var relogins int
+			c := &reloginAZ{
+				AZer: fake,
+				relogin: func(reason string) (*ServicePrincipal, error) {
+					assert.Equal(t, "auth_failure", reason)
+					relogins++
+					return &ServicePrincipal{}, tst.loginErr
+				},
+				log: logr.Discard(),
+			}
+
+			_, err := c.AKSNodepoolList(context.Background(), "rg", "cluster")
+
+			assert.Equal(t, tst.wantErr, err)
+			assert.Equal(t, tst.wantCalls, fake.calls)
+			assert.Equal(t, tst.wantRelogin, relogins)
+		})
+	}
+}
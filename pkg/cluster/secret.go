@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"github.com/mmlt/environment-operator/pkg/events"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -75,6 +76,9 @@ type Client struct {
 
 	// Labels is the set of labels that is required.
 	Labels labels.Set
+
+	// Events (optional) emits an event for each Secret that is created, updated or deleted.
+	Events *events.Emitter
 }
 
 // List reads cluster Secrets and returns a []Cluster.
@@ -117,6 +121,7 @@ func (cl Client) Create(ctx context.Context, namespace string, clusters []Cluste
 		if err != nil {
 			return err
 		}
+		cl.emit(ctx, events.TypeClusterSecretCreated, sec.Namespace, sec.Name, cluster)
 	}
 
 	return nil
@@ -138,6 +143,7 @@ func (cl Client) Update(ctx context.Context, namespace string, clusters []Cluste
 		if err != nil {
 			return err
 		}
+		cl.emit(ctx, events.TypeClusterSecretUpdated, sec.Namespace, sec.Name, cluster)
 	}
 
 	return nil
@@ -155,11 +161,24 @@ func (cl Client) Delete(ctx context.Context, namespace string, clusters []Cluste
 		if err != nil {
 			return err
 		}
+		cl.emit(ctx, events.TypeClusterSecretDeleted, sec.Namespace, sec.Name, cluster)
 	}
 
 	return nil
 }
 
+// Emit emits an event of typ for the Secret namespace/name with cluster data (without credentials).
+func (cl Client) emit(ctx context.Context, typ, namespace, name string, cluster Cluster) {
+	cl.Events.Emit(ctx, typ, "/api/v1/namespaces/"+namespace+"/secrets", name, events.ClusterSecret{
+		Namespace:   namespace,
+		Name:        name,
+		Environment: cluster.Environment,
+		Cluster:     cluster.Name,
+		Domain:      cluster.Domain,
+		Provider:    cluster.Provider,
+	})
+}
+
 // Diff compares current with desired state and returns clusters to create, update, delete.
 func Diff(current, desired []Cluster) (create, update, delete []Cluster) {
 	// index states
@@ -0,0 +1,147 @@
+// Package events publishes envop lifecycle events as CloudEvents (https://cloudevents.io) to HTTP endpoints and
+// message buses.
+package events
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	multierror "github.com/hashicorp/go-multierror"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"time"
+)
+
+// Event types.
+// The version suffix changes when the data schema of an event type changes in a non backwards compatible way.
+const (
+	// TypeStep is the type of a StepTransition event.
+	TypeStep = "nl.mmlt.clusterops.step.v1"
+	// TypeSourceApplied is the type of a SourceApplied event.
+	TypeSourceApplied = "nl.mmlt.clusterops.source.applied.v1"
+	// TypePlan is the type of a PlanSummary event.
+	TypePlan = "nl.mmlt.clusterops.plan.v1"
+	// TypeClusterSecretCreated, TypeClusterSecretUpdated and TypeClusterSecretDeleted are the types of ClusterSecret
+	// events.
+	TypeClusterSecretCreated = "nl.mmlt.clusterops.cluster.secret.created.v1"
+	TypeClusterSecretUpdated = "nl.mmlt.clusterops.cluster.secret.updated.v1"
+	TypeClusterSecretDeleted = "nl.mmlt.clusterops.cluster.secret.deleted.v1"
+)
+
+// Event is a CloudEvent (spec version 1.0) in structured JSON format.
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// StepTransition is the data of a TypeStep event; a step has changed state.
+type StepTransition struct {
+	Namespace     string       `json:"namespace"`
+	Environment   string       `json:"environment"`
+	Step          string       `json:"step"`
+	State         v1.StepState `json:"state"`
+	PreviousState v1.StepState `json:"previousState,omitempty"`
+	Message       string       `json:"message,omitempty"`
+	Hash          string       `json:"hash,omitempty"`
+	Added         int          `json:"added"`
+	Changed       int          `json:"changed"`
+	Deleted       int          `json:"deleted"`
+}
+
+// SourceApplied is the data of a TypeSourceApplied event; a step has applied a source revision.
+type SourceApplied struct {
+	Namespace   string        `json:"namespace"`
+	Environment string        `json:"environment"`
+	Step        string        `json:"step"`
+	Generation  int64         `json:"generation"`
+	Source      v1.StepSource `json:"source"`
+}
+
+// PlanSummary is the data of a TypePlan event; steps are planned to run.
+type PlanSummary struct {
+	Namespace   string `json:"namespace"`
+	Environment string `json:"environment"`
+	Generation  int64  `json:"generation"`
+	// Steps are the names of the steps that are planned to run.
+	Steps []string `json:"steps"`
+}
+
+// ClusterSecret is the data of the TypeClusterSecret* events; a Secret with cluster access data has changed.
+// The data doesn't contain the cluster credentials.
+type ClusterSecret struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Environment string `json:"environment"`
+	Cluster     string `json:"cluster"`
+	Domain      string `json:"domain,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+}
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(ctx context.Context, e Event) error
+}
+
+// Publishers publishes events to multiple publishers.
+type Publishers []Publisher
+
+// Publish publishes e to all publishers.
+func (ps Publishers) Publish(ctx context.Context, e Event) error {
+	var errs error
+	for _, p := range ps {
+		err := p.Publish(ctx, e)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Emitter creates events and publishes them.
+// A nil Emitter doesn't emit events.
+type Emitter struct {
+	Publisher Publisher
+	Log       logr.Logger
+}
+
+// For testing.
+var (
+	timeNow = time.Now
+	newID   = func() string { return string(uuid.NewUUID()) }
+)
+
+// Emit publishes an event of typ with data.
+// Source is an URI-reference that identifies the context in which the event happened, see EnvironmentSource,
+// subject identifies the subject of the event within source.
+// Errors are logged.
+func (em *Emitter) Emit(ctx context.Context, typ, source, subject string, data interface{}) {
+	if em == nil || em.Publisher == nil {
+		return
+	}
+
+	e := Event{
+		SpecVersion:     "1.0",
+		ID:              newID(),
+		Source:          source,
+		Type:            typ,
+		Subject:         subject,
+		Time:            timeNow().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+	err := em.Publisher.Publish(ctx, e)
+	if err != nil && em.Log != nil {
+		em.Log.Error(err, "emit event", "type", typ, "source", source, "subject", subject)
+	}
+}
+
+// EnvironmentSource returns the event source of the environment namespace/name.
+func EnvironmentSource(namespace, name string) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/environments/%s", v1.GroupVersion, namespace, name)
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"errors"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Recorder is a Publisher that records events.
+type recorder struct {
+	events []Event
+	err    error
+}
+
+func (r *recorder) Publish(_ context.Context, e Event) error {
+	r.events = append(r.events, e)
+	return r.err
+}
+
+// FakeConn is a NATSConn that records the published messages.
+type fakeConn struct {
+	subjects []string
+	data     []string
+}
+
+func (c *fakeConn) Publish(subject string, data []byte) error {
+	c.subjects = append(c.subjects, subject)
+	c.data = append(c.data, string(data))
+	return nil
+}
+
+func testEvent() Event {
+	return Event{
+		SpecVersion:     "1.0",
+		ID:              "id-1",
+		Source:          EnvironmentSource("default", "dev"),
+		Type:            TypeStep,
+		Subject:         "Infra",
+		Time:            time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC),
+		DataContentType: "application/json",
+		Data: StepTransition{Namespace: "default", Environment: "dev", Step: "Infra", State: v1.StateReady,
+			PreviousState: v1.StateRunning, Message: "done", Hash: "123", Added: 1},
+	}
+}
+
+const testEventJSON = `{"specversion":"1.0","id":"id-1","source":"/apis/clusterops.mmlt.nl/v1/namespaces/default/environments/dev",` +
+	`"type":"nl.mmlt.clusterops.step.v1","subject":"Infra","time":"2006-01-02T15:04:05Z","datacontenttype":"application/json",` +
+	`"data":{"namespace":"default","environment":"dev","step":"Infra","state":"Ready","previousState":"Running",` +
+	`"message":"done","hash":"123","added":1,"changed":0,"deleted":0}}`
+
+func TestHTTP_Publish(t *testing.T) {
+	tests := []struct {
+		it     string
+		status int
+		err    bool
+	}{
+		{
+			it:     "should post the event in structured mode",
+			status: http.StatusAccepted,
+		},
+		{
+			it:     "should return an error when the endpoint fails",
+			status: http.StatusInternalServerError,
+			err:    true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			var body, contentType string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+				contentType = r.Header.Get("Content-Type")
+				w.WriteHeader(tst.status)
+			}))
+			defer srv.Close()
+
+			err := (&HTTP{URL: srv.URL}).Publish(context.Background(), testEvent())
+
+			assert.Equal(t, tst.err, err != nil)
+			assert.Equal(t, "application/cloudevents+json; charset=utf-8", contentType)
+			assert.JSONEq(t, testEventJSON, body)
+		})
+	}
+}
+
+func TestNATS_Publish(t *testing.T) {
+	c := &fakeConn{}
+
+	err := (&NATS{Conn: c, Subject: "envop"}).Publish(context.Background(), testEvent())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"envop.nl.mmlt.clusterops.step.v1"}, c.subjects)
+	require.Len(t, c.data, 1)
+	assert.JSONEq(t, testEventJSON, c.data[0])
+}
+
+func TestEmitter_Emit(t *testing.T) {
+	origTimeNow, origNewID := timeNow, newID
+	timeNow = func() time.Time { return time.Date(2006, 1, 2, 16, 4, 5, 0, time.FixedZone("x", 3600)) }
+	newID = func() string { return "id-1" }
+	defer func() {
+		timeNow, newID = origTimeNow, origNewID
+	}()
+
+	r1 := &recorder{err: errors.New("unavailable")}
+	r2 := &recorder{}
+	em := &Emitter{Publisher: Publishers{r1, r2}}
+
+	em.Emit(context.Background(), TypeStep, EnvironmentSource("default", "dev"), "Infra", testEvent().Data)
+
+	assert.Equal(t, []Event{testEvent()}, r1.events)
+	assert.Equal(t, []Event{testEvent()}, r2.events, "an error of one publisher doesn't stop the others")
+
+	var nilEmitter *Emitter
+	nilEmitter.Emit(context.Background(), TypeStep, "", "", nil)
+}
@@ -0,0 +1,35 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mmlt/environment-operator/pkg/util/webhook"
+	"net/http"
+)
+
+// HTTP publishes events to an HTTP endpoint (CloudEvents HTTP protocol binding, structured content mode).
+type HTTP struct {
+	// URL of the endpoint.
+	URL string
+	// Client (optional) is the HTTP client used to publish events.
+	Client *http.Client
+}
+
+var _ Publisher = &HTTP{}
+
+// Publish posts e to the endpoint.
+// A failed post isn't retried, the event is lost (at-most-once delivery).
+func (h *HTTP) Publish(ctx context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	err = webhook.Post(ctx, h.Client, h.URL, "application/cloudevents+json; charset=utf-8", b)
+	if err != nil {
+		return fmt.Errorf("events: %s: %w", h.URL, err)
+	}
+
+	return nil
+}
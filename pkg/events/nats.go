@@ -0,0 +1,38 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// NATSConn is the part of *nats.Conn that is used to publish events.
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+}
+
+// NATS publishes events to a NATS subject.
+// The events are published to the subject Subject.<event type>.
+type NATS struct {
+	// Conn is the connection to the NATS server, typically a *nats.Conn.
+	Conn NATSConn
+	// Subject is the prefix of the subjects events are published to.
+	Subject string
+}
+
+var _ Publisher = &NATS{}
+
+// Publish publishes e in structured JSON format.
+func (n *NATS) Publish(_ context.Context, e Event) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	err = n.Conn.Publish(n.Subject+"."+e.Type, b)
+	if err != nil {
+		return fmt.Errorf("events: nats: %w", err)
+	}
+
+	return nil
+}
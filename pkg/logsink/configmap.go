@@ -0,0 +1,201 @@
+package logsink
+
+import (
+	"context"
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// RunLabel is the label that holds the run ID of a log ConfigMap.
+	RunLabel = "clusterops.mmlt.nl/run"
+	// ChunkAnnotation is the annotation that holds the log name and the index of the chunk in a log ConfigMap.
+	chunkAnnotation = "clusterops.mmlt.nl/log-chunk"
+
+	defaultChunkSize = 512 * 1024
+	defaultMaxSize   = 2 * 1024 * 1024
+)
+
+// ConfigMaps stores logs in ConfigMaps in the namespace of the environment.
+// A log is split in chunks of at most ChunkSize bytes, each chunk is stored in its own ConfigMap.
+// Logs that are larger than MaxSize are truncated at the start (the end of a log is most interesting).
+// Logs are stored in plaintext, they are readable by everyone that is allowed to read ConfigMaps in the namespace.
+type ConfigMaps struct {
+	// Client is the client to the cluster that stores the ConfigMaps.
+	Client client.Client
+	// Labels are added to each ConfigMap.
+	Labels map[string]string
+	// ChunkSize is the max number of bytes per ConfigMap (default 512KiB).
+	ChunkSize int
+	// MaxSize is the max number of bytes per log (default 2MiB).
+	MaxSize int
+}
+
+var _ Sink = &ConfigMaps{}
+
+// Put stores text as the log with name of run.
+func (c *ConfigMaps) Put(ctx context.Context, run Run, name string, text []byte) error {
+	max := c.MaxSize
+	if max <= 0 {
+		max = defaultMaxSize
+	}
+	size := c.ChunkSize
+	if size <= 0 {
+		size = defaultChunkSize
+	}
+
+	if len(text) > max {
+		start := len(text) - max
+		for start < len(text) && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		text = text[start:]
+	}
+
+	for i, chunk := range chunks(text, size) {
+		cm := c.configMap(run, name, i)
+		cm.Data = map[string]string{name: string(chunk)}
+
+		err := c.Client.Create(ctx, cm)
+		if apierrors.IsAlreadyExists(err) {
+			err = c.update(ctx, cm)
+		}
+		if err != nil {
+			return fmt.Errorf("logsink: put %s: %w", cm.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Chunks splits text in chunks of at most size bytes.
+// Chunks end at a rune boundary so multi-byte UTF-8 characters aren't split (ConfigMap Data must be valid UTF-8).
+// Empty text returns one empty chunk.
+func chunks(text []byte, size int) [][]byte {
+	var r [][]byte
+	for len(text) > size {
+		end := size
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		if end == 0 {
+			// size is smaller than a rune.
+			end = size
+		}
+		r = append(r, text[:end])
+		text = text[end:]
+	}
+	return append(r, text)
+}
+
+// Get returns the logs of run by name.
+func (c *ConfigMaps) Get(ctx context.Context, run Run) (map[string][]byte, error) {
+	l, err := c.list(ctx, run)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(l, func(i, j int) bool {
+		ni, ii := chunkOf(l[i])
+		nj, ij := chunkOf(l[j])
+		if ni != nj {
+			return ni < nj
+		}
+		return ii < ij
+	})
+
+	r := make(map[string][]byte)
+	for _, cm := range l {
+		n, _ := chunkOf(cm)
+		r[n] = append(r[n], cm.Data[n]...)
+	}
+
+	return r, nil
+}
+
+// Delete removes the logs of run.
+func (c *ConfigMaps) Delete(ctx context.Context, run Run) error {
+	l, err := c.list(ctx, run)
+	if err != nil {
+		return err
+	}
+
+	for _, cm := range l {
+		cm := cm
+		err = c.Client.Delete(ctx, &cm)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("logsink: delete %s: %w", cm.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Update replaces the data of the existing ConfigMap with the data of cm.
+func (c *ConfigMaps) update(ctx context.Context, cm *corev1.ConfigMap) error {
+	current := &corev1.ConfigMap{}
+	err := c.Client.Get(ctx, client.ObjectKeyFromObject(cm), current)
+	if err != nil {
+		return err
+	}
+	current.Data = cm.Data
+	return c.Client.Update(ctx, current)
+}
+
+// List returns the ConfigMaps of run.
+func (c *ConfigMaps) list(ctx context.Context, run Run) ([]corev1.ConfigMap, error) {
+	l := &corev1.ConfigMapList{}
+	err := c.Client.List(ctx, l, client.InNamespace(run.Namespace), client.MatchingLabels(runLabels(run)))
+	if err != nil {
+		return nil, fmt.Errorf("logsink: list: %w", err)
+	}
+	return l.Items, nil
+}
+
+// ConfigMap returns the (empty) ConfigMap for chunk i of log name of run.
+func (c *ConfigMaps) configMap(run Run, name string, i int) *corev1.ConfigMap {
+	lbls := runLabels(run)
+	for k, v := range c.Labels {
+		lbls[k] = v
+	}
+
+	base := strings.TrimSuffix(name, ".txt")
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: run.Namespace,
+			Name:      strings.ToLower(fmt.Sprintf("%s-%s-%s-%s-%d", run.Environment, run.Step, run.ID, base, i)),
+			Labels:    lbls,
+			Annotations: map[string]string{
+				chunkAnnotation: fmt.Sprintf("%s/%d", name, i),
+			},
+		},
+	}
+}
+
+// RunLabels returns the labels that identify the ConfigMaps of run.
+func runLabels(run Run) map[string]string {
+	return map[string]string{
+		v1.RunEnvironmentLabel: run.Environment,
+		v1.RunStepLabel:        run.Step,
+		RunLabel:               run.ID,
+	}
+}
+
+// ChunkOf returns the log name and chunk index of cm.
+func chunkOf(cm corev1.ConfigMap) (string, int) {
+	a := cm.Annotations[chunkAnnotation]
+	i := strings.LastIndex(a, "/")
+	if i < 0 {
+		return a, 0
+	}
+	n, _ := strconv.Atoi(a[i+1:])
+	return a[:i], n
+}
@@ -0,0 +1,54 @@
+package logsink
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Dir stores logs as files in a directory, typically on a persistent volume.
+// The files are stored as Path/namespace/environment/step/id/name.
+type Dir struct {
+	Path string
+}
+
+var _ Sink = &Dir{}
+
+// Put stores text as the log with name of run.
+func (d *Dir) Put(_ context.Context, run Run, name string, text []byte) error {
+	p := filepath.Join(d.Path, filepath.FromSlash(run.path()))
+	err := os.MkdirAll(p, 0750)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(p, name), text, 0640)
+}
+
+// Get returns the logs of run by name.
+func (d *Dir) Get(_ context.Context, run Run) (map[string][]byte, error) {
+	p := filepath.Join(d.Path, filepath.FromSlash(run.path()))
+	fis, err := ioutil.ReadDir(p)
+	if err != nil {
+		return nil, err
+	}
+
+	r := make(map[string][]byte, len(fis))
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(p, fi.Name()))
+		if err != nil {
+			return nil, err
+		}
+		r[fi.Name()] = b
+	}
+
+	return r, nil
+}
+
+// Delete removes the logs of run.
+func (d *Dir) Delete(_ context.Context, run Run) error {
+	return os.RemoveAll(filepath.Join(d.Path, filepath.FromSlash(run.path())))
+}
@@ -0,0 +1,38 @@
+// Package logsink stores the output of step runs.
+package logsink
+
+import (
+	"context"
+	"path"
+	"time"
+)
+
+// Sink stores the output (logs) of step runs.
+type Sink interface {
+	// Put stores text as the log with name of run.
+	Put(ctx context.Context, run Run, name string, text []byte) error
+	// Get returns the logs of run by name.
+	Get(ctx context.Context, run Run) (map[string][]byte, error)
+	// Delete removes the logs of run.
+	Delete(ctx context.Context, run Run) error
+}
+
+// Run identifies a step run.
+type Run struct {
+	// Namespace and Environment identify the environment the step belongs to.
+	Namespace, Environment string
+	// Step is the short name of the step.
+	Step string
+	// ID identifies the run of the step, see RunID.
+	ID string
+}
+
+// RunID returns the ID of a run that starts at t.
+func RunID(t time.Time) string {
+	return t.UTC().Format("20060102t150405z")
+}
+
+// Path returns run as a slash separated path.
+func (r Run) path() string {
+	return path.Join(r.Namespace, r.Environment, r.Step, r.ID)
+}
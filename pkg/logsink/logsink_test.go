@@ -0,0 +1,272 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSinks(t *testing.T) {
+	s3srv := httptest.NewServer(&fakeS3{})
+	defer s3srv.Close()
+	t.Setenv("AWS_ACCESS_KEY_ID", "id")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	tests := []struct {
+		it   string
+		sink Sink
+	}{
+		{
+			it:   "should store logs in a directory",
+			sink: &Dir{Path: t.TempDir()},
+		},
+		{
+			it:   "should store logs in chunked ConfigMaps",
+			sink: &ConfigMaps{Client: fake.NewClientBuilder().Build(), ChunkSize: 4},
+		},
+		{
+			it: "should store logs in a S3 bucket",
+			sink: &S3{Endpoint: strings.TrimPrefix(s3srv.URL, "http://"), Bucket: "logs", Prefix: "envop",
+				Region: "us-east-1", Insecure: true},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			ctx := context.Background()
+			run := Run{Namespace: "default", Environment: "dev", Step: "Infra", ID: RunID(time.Unix(0, 0))}
+			other := run
+			other.ID = "other"
+
+			require.NoError(t, tst.sink.Put(ctx, run, "init.txt", []byte("init output")))
+			require.NoError(t, tst.sink.Put(ctx, run, "plan.txt", []byte("plan output")))
+			require.NoError(t, tst.sink.Put(ctx, run, "plan.txt", []byte("new plan output")))
+			require.NoError(t, tst.sink.Put(ctx, other, "init.txt", []byte("other output")))
+
+			got, err := tst.sink.Get(ctx, run)
+			require.NoError(t, err)
+			assert.Equal(t, map[string][]byte{
+				"init.txt": []byte("init output"),
+				"plan.txt": []byte("new plan output"),
+			}, got)
+
+			require.NoError(t, tst.sink.Delete(ctx, run))
+			got, _ = tst.sink.Get(ctx, run)
+			assert.Empty(t, got)
+
+			got, err = tst.sink.Get(ctx, other)
+			require.NoError(t, err)
+			assert.Equal(t, map[string][]byte{"init.txt": []byte("other output")}, got)
+		})
+	}
+}
+
+func TestConfigMaps_Put_maxSize(t *testing.T) {
+	ctx := context.Background()
+	sink := &ConfigMaps{Client: fake.NewClientBuilder().Build(), ChunkSize: 3, MaxSize: 5}
+	run := Run{Namespace: "default", Environment: "dev", Step: "Infra", ID: "1"}
+
+	require.NoError(t, sink.Put(ctx, run, "apply.txt", []byte("0123456789")))
+
+	got, err := sink.Get(ctx, run)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"apply.txt": []byte("56789")}, got)
+}
+
+func TestConfigMaps_Put_utf8(t *testing.T) {
+	tests := []struct {
+		it      string
+		text    string
+		maxSize int
+		want    string
+	}{
+		{
+			it:   "should not split a multi-byte character at a chunk boundary",
+			text: "ab€cd€",
+			want: "ab€cd€",
+		},
+		{
+			it:      "should not split a multi-byte character when truncating",
+			text:    "ab€cd€",
+			maxSize: 6,
+			want:    "cd€",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			ctx := context.Background()
+			// '€' is 3 bytes, a chunk size of 4 puts a chunk boundary inside the first '€'.
+			sink := &ConfigMaps{Client: fake.NewClientBuilder().Build(), ChunkSize: 4, MaxSize: tst.maxSize}
+			run := Run{Namespace: "default", Environment: "dev", Step: "Infra", ID: "1"}
+
+			require.NoError(t, sink.Put(ctx, run, "apply.txt", []byte(tst.text)))
+
+			got, err := sink.Get(ctx, run)
+			require.NoError(t, err)
+			assert.Equal(t, tst.want, string(got["apply.txt"]))
+		})
+	}
+}
+
+func Test_chunks(t *testing.T) {
+	tests := []struct {
+		it   string
+		text string
+		size int
+		want []string
+	}{
+		{
+			it:   "should return one empty chunk for empty text",
+			size: 4,
+			want: []string{""},
+		},
+		{
+			it:   "should split at size",
+			text: "0123456789",
+			size: 4,
+			want: []string{"0123", "4567", "89"},
+		},
+		{
+			it:   "should end chunks at a rune boundary",
+			text: "ab€cd€",
+			size: 4,
+			want: []string{"ab", "€c", "d€"},
+		},
+		{
+			it:   "should split a rune when size is smaller than the rune",
+			text: "€",
+			size: 2,
+			want: []string{"\xe2\x82", "\xac"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			var got []string
+			for _, c := range chunks([]byte(tst.text), tst.size) {
+				got = append(got, string(c))
+			}
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
+
+func TestRunID(t *testing.T) {
+	got := RunID(time.Date(2006, 1, 2, 15, 4, 5, 0, time.FixedZone("x", 3600)))
+	assert.Equal(t, "20060102t140405z", got)
+}
+
+// FakeS3 is a minimal in-memory S3 server supporting path-style put, get, delete and list (v2) requests.
+type fakeS3 struct {
+	objects map[string][]byte
+	mu      sync.Mutex
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.objects == nil {
+		s.objects = make(map[string][]byte)
+	}
+
+	p := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	if len(p) == 1 || p[1] == "" {
+		// bucket request
+		if r.Method != http.MethodGet || r.URL.Query().Get("list-type") != "2" {
+			w.WriteHeader(http.StatusNotImplemented)
+			return
+		}
+		s.list(w, r.URL.Query().Get("prefix"))
+		return
+	}
+
+	key := p[1]
+	switch r.Method {
+	case http.MethodPut:
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.Header.Get("X-Amz-Content-Sha256") == "STREAMING-AWS4-HMAC-SHA256-PAYLOAD" {
+			b = decodeChunked(b)
+		}
+		s.objects[key] = b
+		w.Header().Set("ETag", `"etag"`)
+	case http.MethodGet, http.MethodHead:
+		b, ok := s.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(b)))
+		w.Header().Set("Last-Modified", time.Unix(0, 0).UTC().Format(http.TimeFormat))
+		w.Header().Set("ETag", `"etag"`)
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(b)
+		}
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// DecodeChunked returns the payload of an aws-chunked encoded body.
+func decodeChunked(b []byte) []byte {
+	var r []byte
+	for len(b) > 0 {
+		i := bytes.Index(b, []byte("\r\n"))
+		if i < 0 {
+			break
+		}
+		n, _ := strconv.ParseInt(strings.SplitN(string(b[:i]), ";", 2)[0], 16, 64)
+		b = b[i+2:]
+		if n == 0 || int(n) > len(b) {
+			break
+		}
+		r = append(r, b[:n]...)
+		b = bytes.TrimPrefix(b[n:], []byte("\r\n"))
+	}
+	return r
+}
+
+func (s *fakeS3) list(w http.ResponseWriter, prefix string) {
+	type content struct {
+		Key          string
+		Size         int
+		ETag         string
+		LastModified string
+	}
+	res := struct {
+		XMLName     xml.Name `xml:"ListBucketResult"`
+		Name        string
+		Prefix      string
+		KeyCount    int
+		IsTruncated bool
+		Contents    []content
+	}{Name: "logs", Prefix: prefix}
+
+	var keys []string
+	for k := range s.objects {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		res.Contents = append(res.Contents, content{Key: k, Size: len(s.objects[k]), ETag: `"etag"`,
+			LastModified: time.Unix(0, 0).UTC().Format(time.RFC3339)})
+	}
+	res.KeyCount = len(keys)
+
+	w.Header().Set("Content-Type", "application/xml")
+	_ = xml.NewEncoder(w).Encode(res)
+}
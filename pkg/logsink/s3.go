@@ -0,0 +1,142 @@
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"io/ioutil"
+	"path"
+	"strings"
+	"sync"
+)
+
+// S3 stores logs as objects in an S3 compatible bucket.
+// The objects are stored as Prefix/namespace/environment/step/id/name.
+// Credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY (or MINIO_ACCESS_KEY and MINIO_SECRET_KEY)
+// environment variables, when not set IAM is used.
+type S3 struct {
+	// Endpoint is the host[:port] of the S3 service, for example s3.amazonaws.com
+	Endpoint string
+	// Bucket is the name of the bucket to store logs in.
+	Bucket string
+	// Prefix (optional) is prepended to the object names.
+	Prefix string
+	// Region (optional) is the region of the bucket.
+	Region string
+	// Insecure is true to use http instead of https.
+	Insecure bool
+
+	client *minio.Client
+	mu     sync.Mutex
+}
+
+var _ Sink = &S3{}
+
+// Put stores text as the log with name of run.
+func (s *S3) Put(ctx context.Context, run Run, name string, text []byte) error {
+	c, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = c.PutObject(ctx, s.Bucket, s.key(run)+name, bytes.NewReader(text), int64(len(text)),
+		minio.PutObjectOptions{ContentType: "text/plain"})
+	if err != nil {
+		return fmt.Errorf("logsink: put %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Get returns the logs of run by name.
+func (s *S3) Get(ctx context.Context, run Run) (map[string][]byte, error) {
+	c, err := s.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := s.key(run)
+	r := make(map[string][]byte)
+	for o := range c.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if o.Err != nil {
+			return nil, fmt.Errorf("logsink: list: %w", o.Err)
+		}
+		b, err := s.getObject(ctx, c, o.Key)
+		if err != nil {
+			return nil, err
+		}
+		r[strings.TrimPrefix(o.Key, prefix)] = b
+	}
+
+	return r, nil
+}
+
+// Delete removes the logs of run.
+func (s *S3) Delete(ctx context.Context, run Run) error {
+	c, err := s.getClient()
+	if err != nil {
+		return err
+	}
+
+	for o := range c.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Prefix: s.key(run)}) {
+		if o.Err != nil {
+			return fmt.Errorf("logsink: list: %w", o.Err)
+		}
+		err = c.RemoveObject(ctx, s.Bucket, o.Key, minio.RemoveObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("logsink: delete %s: %w", o.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// GetObject returns the content of the object with key.
+func (s *S3) getObject(ctx context.Context, c *minio.Client, key string) ([]byte, error) {
+	o, err := c.GetObject(ctx, s.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("logsink: get %s: %w", key, err)
+	}
+	defer o.Close()
+
+	b, err := ioutil.ReadAll(o)
+	if err != nil {
+		return nil, fmt.Errorf("logsink: get %s: %w", key, err)
+	}
+
+	return b, nil
+}
+
+// Key returns the object name prefix of the logs of run.
+func (s *S3) key(run Run) string {
+	return path.Join(s.Prefix, run.path()) + "/"
+}
+
+// GetClient returns a client to the S3 service, the client is created on first use.
+func (s *S3) getClient() (*minio.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+		&credentials.IAM{},
+	})
+	c, err := minio.New(s.Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !s.Insecure,
+		Region: s.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("logsink: s3 client: %w", err)
+	}
+	s.client = c
+
+	return c, nil
+}
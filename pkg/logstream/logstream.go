@@ -0,0 +1,212 @@
+// Package logstream makes the output of running steps available to (remote) readers while the steps run.
+package logstream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// MaxLines is the max number of lines kept per stream, older lines are dropped.
+const maxLines = 10000
+
+// Hub keeps the output of the last run of each step and serves it over HTTP.
+type Hub struct {
+	streams map[key]*stream
+	mu      sync.Mutex
+}
+
+// Key identifies a step.
+type key struct {
+	namespace, environment, step string
+}
+
+// Stream is the output of a step run.
+type stream struct {
+	// Lines are the last maxLines lines of output.
+	lines []string
+	// Dropped is the number of lines dropped from the start of lines.
+	dropped int
+	// Partial is the last line when it isn't terminated by a newline yet.
+	partial string
+	// Done is true when the run has ended.
+	done bool
+	// Changed is closed (and replaced) when lines are added or the run has ended.
+	changed chan struct{}
+	mu      sync.Mutex
+}
+
+// Writer returns a writer for the output of a new run of step.
+// The output of a previous run of step is discarded.
+// Close the writer when the run has ended.
+func (h *Hub) Writer(namespace, environment, step string) io.WriteCloser {
+	s := &stream{changed: make(chan struct{})}
+
+	h.mu.Lock()
+	if h.streams == nil {
+		h.streams = make(map[key]*stream)
+	}
+	k := key{namespace, environment, step}
+	old := h.streams[k]
+	h.streams[k] = s
+	h.mu.Unlock()
+
+	if old != nil {
+		_ = old.Close()
+	}
+
+	return s
+}
+
+// ListenAndServe serves the output of steps at http://addr/logs/namespace/environment/step until ctx is done.
+// The output isn't protected by authentication, bind addr to localhost and use port-forwarding to access it.
+func (h *Hub) ListenAndServe(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/logs/", h)
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	err := srv.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// ServeHTTP writes the output of a step to w.
+// The request path is .../namespace/environment/step, with query parameter follow=true the response is kept open
+// until the run ends and new output is written as it arrives.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(p) < 3 {
+		http.Error(w, "expected path .../namespace/environment/step", http.StatusBadRequest)
+		return
+	}
+	k := key{p[len(p)-3], p[len(p)-2], p[len(p)-1]}
+
+	h.mu.Lock()
+	s := h.streams[k]
+	h.mu.Unlock()
+	if s == nil {
+		http.Error(w, fmt.Sprintf("no output for step %s of environment %s/%s", k.step, k.namespace, k.environment),
+			http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = s.copyTo(r.Context(), w, r.URL.Query().Get("follow") == "true")
+}
+
+// Write adds the lines in b to the stream.
+func (s *stream) Write(b []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return 0, io.ErrClosedPipe
+	}
+
+	text := s.partial + string(b)
+	lines := strings.Split(text, "\n")
+	s.partial = lines[len(lines)-1]
+	s.lines = append(s.lines, lines[:len(lines)-1]...)
+	if n := len(s.lines) - maxLines; n > 0 {
+		s.lines = s.lines[n:]
+		s.dropped += n
+	}
+	s.notify()
+
+	return len(b), nil
+}
+
+// Close ends the stream.
+func (s *stream) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return nil
+	}
+	if s.partial != "" {
+		s.lines = append(s.lines, s.partial)
+		s.partial = ""
+	}
+	s.done = true
+	s.notify()
+
+	return nil
+}
+
+// Notify wakes up the readers waiting for a change.
+// Must be called with mu held.
+func (s *stream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// CopyTo writes the lines of the stream to w.
+// When follow is true it waits for new lines until the stream ends or ctx is done.
+func (s *stream) copyTo(ctx context.Context, w io.Writer, follow bool) error {
+	flusher, _ := w.(http.Flusher)
+
+	// next is the index of the next line to write (including dropped lines).
+	next := 0
+	for {
+		s.mu.Lock()
+		if next < s.dropped {
+			next = s.dropped
+		}
+		lines := append([]string(nil), s.lines[next-s.dropped:]...)
+		next += len(lines)
+		done, changed := s.done, s.changed
+		s.mu.Unlock()
+
+		bw := bufio.NewWriter(w)
+		for _, l := range lines {
+			_, _ = bw.WriteString(l)
+			_ = bw.WriteByte('\n')
+		}
+		err := bw.Flush()
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if done || !follow {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries w, see FromContext.
+func NewContext(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, contextKey{}, w)
+}
+
+// FromContext returns the writer for live output carried by ctx.
+// When ctx doesn't carry a writer a writer that discards all output is returned.
+func FromContext(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(contextKey{}).(io.Writer); ok {
+		return w
+	}
+	return ioutil.Discard
+}
@@ -0,0 +1,135 @@
+package logstream
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHub_ServeHTTP(t *testing.T) {
+	tests := []struct {
+		it       string
+		path     string
+		write    []string
+		close    bool
+		wantCode int
+		want     string
+	}{
+		{
+			it:       "should return the output written so far",
+			path:     "/logs/default/dev/Infra",
+			write:    []string{"line 1\nline", " 2\npartial"},
+			wantCode: http.StatusOK,
+			want:     "line 1\nline 2\n",
+		},
+		{
+			it:       "should return the partial last line when the run has ended",
+			path:     "/logs/default/dev/Infra?follow=true",
+			write:    []string{"line 1\npartial"},
+			close:    true,
+			wantCode: http.StatusOK,
+			want:     "line 1\npartial\n",
+		},
+		{
+			it:       "should return not found for an unknown step",
+			path:     "/logs/default/dev/Addonsxyz",
+			wantCode: http.StatusNotFound,
+			want:     "no output for step Addonsxyz of environment default/dev\n",
+		},
+		{
+			it:       "should reject an invalid path",
+			path:     "/logs/dev",
+			wantCode: http.StatusBadRequest,
+			want:     "expected path .../namespace/environment/step\n",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			h := &Hub{}
+			w := h.Writer("default", "dev", "Infra")
+			for _, s := range tst.write {
+				_, err := io.WriteString(w, s)
+				require.NoError(t, err)
+			}
+			if tst.close {
+				require.NoError(t, w.Close())
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tst.path, nil))
+
+			assert.Equal(t, tst.wantCode, rec.Code)
+			assert.Equal(t, tst.want, rec.Body.String())
+		})
+	}
+}
+
+func TestHub_follow(t *testing.T) {
+	h := &Hub{}
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	w := h.Writer("default", "dev", "Infra")
+	_, err := io.WriteString(w, "init\n")
+	require.NoError(t, err)
+
+	resp, err := http.Get(srv.URL + "/logs/default/dev/Infra?follow=true")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	go func() {
+		for i := 1; i <= 3; i++ {
+			time.Sleep(10 * time.Millisecond)
+			_, _ = io.WriteString(w, fmt.Sprintf("apply %d\n", i))
+		}
+		_ = w.Close()
+	}()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "init\napply 1\napply 2\napply 3\n", string(b))
+}
+
+func TestHub_Writer_newRun(t *testing.T) {
+	h := &Hub{}
+	w1 := h.Writer("default", "dev", "Infra")
+	_, _ = io.WriteString(w1, "run 1\n")
+	w2 := h.Writer("default", "dev", "Infra")
+	_, _ = io.WriteString(w2, "run 2\n")
+
+	_, err := io.WriteString(w1, "late\n")
+	assert.Error(t, err, "a previous run is closed")
+
+	var b bytes.Buffer
+	h.mu.Lock()
+	s := h.streams[key{"default", "dev", "Infra"}]
+	h.mu.Unlock()
+	require.NoError(t, s.copyTo(context.Background(), &b, false))
+	assert.Equal(t, "run 2\n", b.String())
+}
+
+func TestStream_maxLines(t *testing.T) {
+	s := &stream{changed: make(chan struct{})}
+	_, _ = io.WriteString(s, strings.Repeat("x\n", maxLines+2)+"last\n")
+
+	var b bytes.Buffer
+	require.NoError(t, s.copyTo(context.Background(), &b, false))
+	assert.Equal(t, maxLines, strings.Count(b.String(), "\n"))
+	assert.True(t, strings.HasSuffix(b.String(), "x\nlast\n"))
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, ioutil.Discard, FromContext(context.Background()))
+
+	var b bytes.Buffer
+	assert.Equal(t, &b, FromContext(NewContext(context.Background(), &b)))
+}
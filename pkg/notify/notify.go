@@ -0,0 +1,191 @@
+// Package notify sends notifications about step transitions to Slack, Teams and generic webhook endpoints.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	multierror "github.com/hashicorp/go-multierror"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/util/webhook"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// DefaultTemplate is the message text template used when a NotificationSpec doesn't specify one.
+const defaultTemplate = `{{.Namespace}}/{{.Environment}} {{.Step}} {{.Type}}: {{.Message}}` +
+	`{{if .Suppressed}} ({{.Suppressed}} earlier notifications suppressed){{end}}`
+
+// DefaultMinInterval is the min time between notifications when a NotificationSpec doesn't specify one.
+const defaultMinInterval = 10 * time.Second
+
+// Event is a step transition.
+type Event struct {
+	Type        v1.NotificationEvent `json:"type"`
+	Namespace   string               `json:"namespace"`
+	Environment string               `json:"environment"`
+	Step        string               `json:"step"`
+	State       v1.StepState         `json:"state"`
+	Message     string               `json:"message"`
+	Time        time.Time            `json:"time"`
+	// Suppressed is the number of events that are not sent to the endpoint since the previous event because of
+	// rate limiting.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+// Notifier sends events to endpoints.
+type Notifier struct {
+	// Client (optional) is the HTTP client used to send notifications.
+	Client *http.Client
+
+	// Limits keeps track of the notifications sent per endpoint and environment.
+	limits map[string]*limit
+	mu     sync.Mutex
+}
+
+// Limit is the rate limiter state of an endpoint and environment.
+type limit struct {
+	last       time.Time
+	suppressed int
+}
+
+// Notify sends event e to the endpoints specified by specs that subscribe to e.Type.
+// Events that arrive within MinInterval of the previous event to the same endpoint and environment are suppressed,
+// except for the events of a step that has ended (StepSucceeded, StepFailed and BudgetExceeded) that are always sent.
+func (n *Notifier) Notify(ctx context.Context, specs []v1.NotificationSpec, e Event) error {
+	var errs error
+	for _, spec := range specs {
+		if !subscribed(spec, e.Type) {
+			continue
+		}
+		ev, ok := n.allow(spec, e)
+		if !ok {
+			continue
+		}
+		err := n.send(ctx, spec, ev)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("notification %s: %w", spec.Name, err))
+		}
+	}
+	return errs
+}
+
+// Allow returns e with the number of suppressed events and true when e can be sent to the endpoint of spec.
+func (n *Notifier) allow(spec v1.NotificationSpec, e Event) (Event, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.limits == nil {
+		n.limits = make(map[string]*limit)
+	}
+	k := spec.URL + " " + e.Namespace + "/" + e.Environment
+	l, ok := n.limits[k]
+	if !ok {
+		l = &limit{}
+		n.limits[k] = l
+	}
+
+	min := spec.MinInterval.Duration
+	if min == 0 {
+		min = defaultMinInterval
+	}
+	if !l.last.IsZero() && e.Time.Sub(l.last) < min && !final(e.Type) {
+		l.suppressed++
+		return e, false
+	}
+
+	e.Suppressed = l.suppressed
+	l.last = e.Time
+	l.suppressed = 0
+
+	return e, true
+}
+
+// Final returns true for the event types of a step that has ended, they are never suppressed because no later event
+// of the step would report them.
+func final(typ v1.NotificationEvent) bool {
+	return typ == v1.EventStepSucceeded || typ == v1.EventStepFailed || typ == v1.EventBudgetExceeded
+}
+
+// Send posts e to the endpoint of spec.
+// A failed post isn't retried (at-most-once delivery).
+func (n *Notifier) send(ctx context.Context, spec v1.NotificationSpec, e Event) error {
+	text, err := Text(spec.Template, e)
+	if err != nil {
+		return err
+	}
+	b, err := payload(spec.Type, text, e)
+	if err != nil {
+		return err
+	}
+
+	return webhook.Post(ctx, n.Client, spec.URL, "application/json", b)
+}
+
+// Text renders the message text of e with tmplt, an empty tmplt uses the default template.
+func Text(tmplt string, e Event) (string, error) {
+	if tmplt == "" {
+		tmplt = defaultTemplate
+	}
+	t, err := template.New("notification").Parse(tmplt)
+	if err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	var b bytes.Buffer
+	err = t.Execute(&b, e)
+	if err != nil {
+		return "", fmt.Errorf("template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// Payload returns the JSON body to post to an endpoint of type typ.
+func payload(typ v1.NotificationType, text string, e Event) ([]byte, error) {
+	switch typ {
+	case v1.NotificationSlack:
+		return json.Marshal(map[string]string{"text": text})
+	case v1.NotificationTeams:
+		return json.Marshal(map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    text,
+			"themeColor": themeColor(e.Type),
+			"text":       text,
+		})
+	case v1.NotificationWebhook:
+		return json.Marshal(struct {
+			Event
+			Text string `json:"text"`
+		}{e, text})
+	default:
+		return nil, fmt.Errorf("unknown type: %s", typ)
+	}
+}
+
+// ThemeColor returns the Teams card color for an event type.
+func themeColor(typ v1.NotificationEvent) string {
+	switch typ {
+	case v1.EventStepSucceeded:
+		return "2EB886"
+	case v1.EventStepFailed, v1.EventBudgetExceeded:
+		return "D00000"
+	default:
+		return "439FE0"
+	}
+}
+
+// Subscribed returns true if spec subscribes to events of type typ.
+func subscribed(spec v1.NotificationSpec, typ v1.NotificationEvent) bool {
+	if len(spec.Events) == 0 {
+		return true
+	}
+	for _, e := range spec.Events {
+		if e == typ {
+			return true
+		}
+	}
+	return false
+}
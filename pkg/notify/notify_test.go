@@ -0,0 +1,175 @@
+package notify
+
+import (
+	"context"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Endpoint is a local stand-in for a Slack, Teams or webhook endpoint that records the bodies it receives.
+type endpoint struct {
+	bodies []string
+	status int
+	mu     sync.Mutex
+}
+
+func (ep *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	b, _ := ioutil.ReadAll(r.Body)
+	ep.bodies = append(ep.bodies, string(b))
+	if ep.status != 0 {
+		w.WriteHeader(ep.status)
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	t0 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	event := func(typ v1.NotificationEvent, msg string, d time.Duration) Event {
+		return Event{Type: typ, Namespace: "default", Environment: "dev", Step: "Infra", State: v1.StateRunning,
+			Message: msg, Time: t0.Add(d)}
+	}
+
+	tests := []struct {
+		it     string
+		spec   v1.NotificationSpec
+		events []Event
+		status int
+		want   []string
+		err    string
+	}{
+		{
+			it:     "should post a Slack message",
+			spec:   v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack},
+			events: []Event{event(v1.EventStepStarted, "terraform init", 0)},
+			want:   []string{`{"text":"default/dev Infra StepStarted: terraform init"}`},
+		},
+		{
+			it:     "should post a Teams message card",
+			spec:   v1.NotificationSpec{Name: "t", Type: v1.NotificationTeams, Template: "{{.Step}} {{.Message}}"},
+			events: []Event{event(v1.EventStepFailed, "boom", 0)},
+			want: []string{`{"@context":"https://schema.org/extensions","@type":"MessageCard","summary":"Infra boom",` +
+				`"text":"Infra boom","themeColor":"D00000"}`},
+		},
+		{
+			it:     "should post the event to a generic webhook",
+			spec:   v1.NotificationSpec{Name: "w", Type: v1.NotificationWebhook, Template: "{{.Message}}"},
+			events: []Event{event(v1.EventBudgetExceeded, "plan limits exceeded", 0)},
+			want: []string{`{"type":"BudgetExceeded","namespace":"default","environment":"dev","step":"Infra",` +
+				`"state":"Running","message":"plan limits exceeded","time":"2006-01-02T15:04:05Z","text":"plan limits exceeded"}`},
+		},
+		{
+			it:   "should only post subscribed events",
+			spec: v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack, Template: "{{.Type}}", Events: []v1.NotificationEvent{v1.EventStepFailed}},
+			events: []Event{
+				event(v1.EventStepStarted, "", 0),
+				event(v1.EventStepFailed, "", time.Minute),
+			},
+			want: []string{`{"text":"StepFailed"}`},
+		},
+		{
+			it:   "should suppress starts within min interval and report the number suppressed",
+			spec: v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack},
+			events: []Event{
+				event(v1.EventStepStarted, "1", 0),
+				event(v1.EventStepStarted, "2", time.Second),
+				event(v1.EventStepStarted, "3", 2*time.Second),
+				event(v1.EventStepStarted, "4", 10*time.Second),
+			},
+			want: []string{
+				`{"text":"default/dev Infra StepStarted: 1"}`,
+				`{"text":"default/dev Infra StepStarted: 4 (2 earlier notifications suppressed)"}`,
+			},
+		},
+		{
+			it:   "should not suppress a success within min interval",
+			spec: v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack},
+			events: []Event{
+				event(v1.EventStepStarted, "1", 0),
+				event(v1.EventStepSucceeded, "2", time.Second),
+				event(v1.EventStepStarted, "3", 2*time.Second),
+				event(v1.EventStepSucceeded, "4", 3*time.Second),
+			},
+			want: []string{
+				`{"text":"default/dev Infra StepStarted: 1"}`,
+				`{"text":"default/dev Infra StepSucceeded: 2"}`,
+				`{"text":"default/dev Infra StepSucceeded: 4 (1 earlier notifications suppressed)"}`,
+			},
+		},
+		{
+			it:   "should not suppress failures",
+			spec: v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack},
+			events: []Event{
+				event(v1.EventStepStarted, "1", 0),
+				event(v1.EventStepStarted, "2", time.Second),
+				event(v1.EventBudgetExceeded, "3", 2*time.Second),
+				event(v1.EventStepFailed, "4", 3*time.Second),
+			},
+			want: []string{
+				`{"text":"default/dev Infra StepStarted: 1"}`,
+				`{"text":"default/dev Infra BudgetExceeded: 3 (1 earlier notifications suppressed)"}`,
+				`{"text":"default/dev Infra StepFailed: 4"}`,
+			},
+		},
+		{
+			it:     "should return an error when the endpoint fails",
+			spec:   v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack},
+			events: []Event{event(v1.EventStepStarted, "", 0)},
+			status: http.StatusForbidden,
+			want:   []string{`{"text":"default/dev Infra StepStarted: "}`},
+			err:    "1 error occurred:\n\t* notification s: post: 403 Forbidden\n\n",
+		},
+		{
+			it:     "should return an error on an invalid template",
+			spec:   v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack, Template: "{{.Unknown}}"},
+			events: []Event{event(v1.EventStepStarted, "", 0)},
+			err:    "1 error occurred:\n\t* notification s: template: template: notification:1:2: executing \"notification\" at <.Unknown>: can't evaluate field Unknown in type notify.Event\n\n",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			ep := &endpoint{status: tst.status}
+			srv := httptest.NewServer(ep)
+			defer srv.Close()
+
+			spec := tst.spec
+			spec.URL = srv.URL
+			n := &Notifier{}
+			var err error
+			for _, e := range tst.events {
+				err = n.Notify(context.Background(), []v1.NotificationSpec{spec}, e)
+			}
+
+			if tst.err != "" {
+				require.Error(t, err)
+				assert.Equal(t, tst.err, err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tst.want, ep.bodies)
+		})
+	}
+}
+
+func TestNotifier_Notify_perEnvironment(t *testing.T) {
+	ep := &endpoint{}
+	srv := httptest.NewServer(ep)
+	defer srv.Close()
+
+	t0 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	spec := v1.NotificationSpec{Name: "s", Type: v1.NotificationSlack, URL: srv.URL, Template: "{{.Environment}}"}
+	n := &Notifier{}
+	for _, env := range []string{"dev", "test"} {
+		err := n.Notify(context.Background(), []v1.NotificationSpec{spec}, Event{Environment: env, Time: t0})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, []string{`{"text":"dev"}`, `{"text":"test"}`}, ep.bodies, "rate limits are per environment")
+}
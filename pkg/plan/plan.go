@@ -29,6 +29,9 @@ type Planner struct {
 	AllowedStepTypes map[step.Type]struct{}
 	// Cloud provides generic cloud access functions.
 	Cloud cloud.Cloud
+	// Accounts (optional) provides the Cloud and Azure of Environments with their own credentials.
+	// When nil Cloud and Azure are used for all Environments.
+	Accounts *cloud.Accounts
 	// Terraform is the terraform implementation to use.
 	Terraform terraform.Terraformer
 	// Kubectl is the kubectl implementation to use.
@@ -53,8 +56,8 @@ type Sourcer interface {
 
 // Plan returns an ordered collection of steps.
 // The step hash field reflects the current source/parameters for that step.
-func (p *Planner) Plan(nsn types.NamespacedName, src Sourcer, destroy bool, ispec v1.InfraSpec, cspec []v1.ClusterSpec) ([]step.Step, error) {
-	pl, ok := p.buildPlan(nsn, src, destroy, ispec, cspec)
+func (p *Planner) Plan(nsn types.NamespacedName, src Sourcer, destroy bool, ispec v1.InfraSpec, cspec []v1.ClusterSpec, rollout v1.RolloutSpec) ([]step.Step, error) {
+	pl, ok := p.buildPlan(nsn, src, destroy, ispec, cspec, rollout)
 	if !ok {
 		return nil, nil
 	}
@@ -68,7 +71,7 @@ func (p *Planner) Plan(nsn types.NamespacedName, src Sourcer, destroy bool, ispe
 }
 
 // PossibleSteps returns a set of possible step names.
-func (p *Planner) PossibleSteps(cspec []v1.ClusterSpec) map[string]struct{} {
+func (p *Planner) PossibleSteps(cspec []v1.ClusterSpec, rollout v1.RolloutSpec) map[string]struct{} {
 	r := make(map[string]struct{})
 
 	for _, t := range step.InfraTypes {
@@ -93,13 +96,28 @@ func (p *Planner) PossibleSteps(cspec []v1.ClusterSpec) map[string]struct{} {
 		}
 	}
 
+	if _, ok := p.AllowedStepTypes[step.TypeRolloutGate]; ok || p.AllowedStepTypes == nil {
+		// a gate follows each batch except the last.
+		for i := 1; i < len(Batches(rollout, cspec)); i++ {
+			r[string(step.TypeRolloutGate)+strconv.Itoa(i)] = struct{}{}
+		}
+	}
+
 	return r
 }
 
+// Account returns the Cloud and Azure to use for Environment nsn.
+func (p *Planner) account(nsn types.NamespacedName) cloud.Account {
+	if p.Accounts == nil {
+		return cloud.Account{Cloud: p.Cloud, Azure: p.Azure}
+	}
+	return p.Accounts.Get(nsn)
+}
+
 // BuildPlan builds a plan containing the steps to create/update/delete a target environment.
 // An environment is identified by nsn.
 // Returns false if not all prerequisites are fulfilled.
-func (p *Planner) buildPlan(nsn types.NamespacedName, src Sourcer, destroy bool, ispec v1.InfraSpec, cspec []v1.ClusterSpec) (plan, bool) {
+func (p *Planner) buildPlan(nsn types.NamespacedName, src Sourcer, destroy bool, ispec v1.InfraSpec, cspec []v1.ClusterSpec, rollout v1.RolloutSpec) (plan, bool) {
 	var pl plan
 	var ok bool
 	switch {
@@ -107,7 +125,7 @@ func (p *Planner) buildPlan(nsn types.NamespacedName, src Sourcer, destroy bool,
 		pl, ok = p.buildDestroyPlan(nsn, src, ispec, cspec)
 
 	default:
-		pl, ok = p.buildCreatePlan(nsn, src, ispec, cspec, p.Client)
+		pl, ok = p.buildCreatePlan(nsn, src, ispec, cspec, rollout, p.Client)
 	}
 	if !ok {
 		return nil, false
@@ -126,6 +144,7 @@ func (p *Planner) buildDestroyPlan(nsn types.NamespacedName, src Sourcer, ispec
 	tfPath := filepath.Join(tfw.Path, ispec.Main)
 
 	h := p.hash(tfw.Hash)
+	acc := p.account(nsn)
 
 	pl := make(plan, 0, 1)
 	pl = append(pl,
@@ -136,9 +155,9 @@ func (p *Planner) buildDestroyPlan(nsn types.NamespacedName, src Sourcer, ispec
 				Clusters: cspec,
 			},
 			SourcePath: tfPath,
-			Cloud:      p.Cloud,
+			Cloud:      acc.Cloud,
 			Terraform:  p.Terraform,
-			Azure:      p.Azure,
+			Azure:      acc.Azure,
 		})
 
 	return pl, true
@@ -146,7 +165,8 @@ func (p *Planner) buildDestroyPlan(nsn types.NamespacedName, src Sourcer, ispec
 
 // BuildCreatePlan builds a plan to create or update a target environment.
 // Returns false if workspaces are not prepped with sources.
-func (p *Planner) buildCreatePlan(nsn types.NamespacedName, src Sourcer, ispec v1.InfraSpec, cspec []v1.ClusterSpec, client cluster.Client) (plan, bool) {
+// Cluster steps are added in rollout batches, each batch except the last is followed by a RolloutGate step.
+func (p *Planner) buildCreatePlan(nsn types.NamespacedName, src Sourcer, ispec v1.InfraSpec, cspec []v1.ClusterSpec, rollout v1.RolloutSpec, client cluster.Client) (plan, bool) {
 	tfw, ok := src.Workspace(nsn, "")
 	if !ok || !tfw.Synced {
 		return nil, false
@@ -158,6 +178,7 @@ func (p *Planner) buildCreatePlan(nsn types.NamespacedName, src Sourcer, ispec v
 		cspecInfra = append(cspecInfra, s.Infra)
 	}
 	h := p.hash(tfw.Hash, ispec, cspecInfra)
+	acc := p.account(nsn)
 
 	pl := make(plan, 0, 1+4*len(cspec))
 	pl = append(pl,
@@ -168,8 +189,8 @@ func (p *Planner) buildCreatePlan(nsn types.NamespacedName, src Sourcer, ispec v
 				Clusters: cspec,
 			},
 			SourcePath: tfPath,
-			Cloud:      p.Cloud,
-			Azure:      p.Azure,
+			Cloud:      acc.Cloud,
+			Azure:      acc.Azure,
 			Terraform:  p.Terraform,
 			Client:     client,
 			Kubectl:    p.Kubectl,
@@ -182,40 +203,75 @@ func (p *Planner) buildCreatePlan(nsn types.NamespacedName, src Sourcer, ispec v
 			},
 		})
 
+	specs := make(map[string]v1.ClusterSpec, len(cspec))
 	for _, cl := range cspec {
-		cw, ok := src.Workspace(nsn, cl.Name)
-		if !ok || cw.Hash == "" {
-			return nil, false
+		specs[cl.Name] = cl
+	}
+
+	batches := Batches(rollout, cspec)
+	for bi, batch := range batches {
+		var (
+			names   []string
+			hashes  []string
+			kcPaths = make(map[string]string, len(batch))
+		)
+		for _, n := range batch {
+			cl := specs[n]
+			cw, ok := src.Workspace(nsn, cl.Name)
+			if !ok || cw.Hash == "" {
+				return nil, false
+			}
+
+			kcPath := filepath.Join(cw.Path, "kubeconfig")
+			mvPath := filepath.Join(cw.Path, cl.Addons.MKV)
+			kcPaths[cl.Name] = kcPath
+
+			az := acc.Azure
+			az.SetSubscription(ispec.AZ.Subscription[0].Name) // already validated
+			stps := []step.Step{
+				&step.AKSPoolStep{
+					Metaa:         stepMeta(nsn, cl.Name, step.TypeAKSPool, p.hash(tfw.Hash, ispec.AZ.ResourceGroup, cl.Infra.Version)),
+					ResourceGroup: ispec.AZ.ResourceGroup,
+					Cluster:       prefixedClusterName("aks", ispec.EnvName, cl.Name),
+					Version:       cl.Infra.Version,
+					Azure:         az,
+				},
+				&step.AKSAddonPreflightStep{
+					Metaa:   stepMeta(nsn, cl.Name, step.TypeAKSAddonPreflight, h),
+					KCPath:  kcPath,
+					Kubectl: p.Kubectl,
+				},
+				&step.AddonStep{
+					Metaa:           stepMeta(nsn, cl.Name, step.TypeAddons, p.hash(cw.Hash, cl.Addons.Jobs, cl.Addons.X)),
+					SourcePath:      cw.Path,
+					KCPath:          kcPath,
+					MasterVaultPath: mvPath,
+					JobPaths:        cl.Addons.Jobs,
+					Values:          cl.Addons.X,
+					Addon:           p.Addon,
+				},
+			}
+			for _, stp := range stps {
+				names = append(names, stp.GetID().ShortName())
+				hashes = append(hashes, stp.GetHash())
+			}
+			pl = append(pl, stps...)
 		}
 
-		kcPath := filepath.Join(cw.Path, "kubeconfig")
-		mvPath := filepath.Join(cw.Path, cl.Addons.MKV)
+		if bi == len(batches)-1 {
+			// no gate after the last batch.
+			break
+		}
 
-		az := p.Azure
-		az.SetSubscription(ispec.AZ.Subscription[0].Name) // already validated
 		pl = append(pl,
-			&step.AKSPoolStep{
-				Metaa:         stepMeta(nsn, cl.Name, step.TypeAKSPool, p.hash(tfw.Hash, ispec.AZ.ResourceGroup, cl.Infra.Version)),
-				ResourceGroup: ispec.AZ.ResourceGroup,
-				Cluster:       prefixedClusterName("aks", ispec.EnvName, cl.Name),
-				Version:       cl.Infra.Version,
-				Azure:         az,
-			},
-			&step.AKSAddonPreflightStep{
-				Metaa:   stepMeta(nsn, cl.Name, step.TypeAKSAddonPreflight, h),
-				KCPath:  kcPath,
-				Kubectl: p.Kubectl,
-			},
-			&step.AddonStep{
-				Metaa:           stepMeta(nsn, cl.Name, step.TypeAddons, p.hash(cw.Hash, cl.Addons.Jobs, cl.Addons.X)),
-				SourcePath:      cw.Path,
-				KCPath:          kcPath,
-				MasterVaultPath: mvPath,
-				JobPaths:        cl.Addons.Jobs,
-				Values:          cl.Addons.X,
-				Addon:           p.Addon,
-			},
-		)
+			&step.RolloutGateStep{
+				Metaa:      stepMeta(nsn, strconv.Itoa(bi+1), step.TypeRolloutGate, p.hash(hashes, rollout)),
+				Steps:      names,
+				SoakTime:   rollout.SoakTime.Duration,
+				KCPaths:    kcPaths,
+				Namespaces: rollout.HealthNamespaces,
+				Kubectl:    p.Kubectl,
+			})
 	}
 
 	return pl, true
@@ -121,7 +121,7 @@ func TestPlanner_Plan_ignore_parameters(t *testing.T) {
 						Name:        "test",
 						ClusterName: "",
 					},
-					Hash: "b134dc8eca86e844", //"b2e166111bf1c016",
+					Hash: "201e6e55798972db", //"4988ba81a27b4a1d",
 				},
 			},
 		},
@@ -148,7 +148,7 @@ func TestPlanner_Plan_ignore_parameters(t *testing.T) {
 						Name:        "test",
 						ClusterName: "",
 					},
-					Hash: "1762976ecb35a230", //"4b76faac2401a19f",
+					Hash: "5d5f80782a906bc7", //"47e3ca10b4626f93",
 				},
 			},
 		},
@@ -202,7 +202,7 @@ func TestPlanner_Plan_ignore_parameters(t *testing.T) {
 						Name:        "test",
 						ClusterName: "",
 					},
-					Hash: "ff311b4a7990bdc2", //"f122548f6c981695",
+					Hash: "9ba955b8082e931d", //"3c79eba91bc3f549",
 				},
 				{
 					ID: step.ID{
@@ -220,7 +220,7 @@ func TestPlanner_Plan_ignore_parameters(t *testing.T) {
 						Name:        "test",
 						ClusterName: "xyz",
 					},
-					Hash: "ff311b4a7990bdc2", //"f122548f6c981695",
+					Hash: "9ba955b8082e931d", //"3c79eba91bc3f549",
 				},
 				{
 					ID: step.ID{
@@ -245,7 +245,7 @@ func TestPlanner_Plan_ignore_parameters(t *testing.T) {
 
 				Log: l,
 			}
-			got, err := p.Plan(tt.args.nsn, tt.args.src, tt.args.destroy, tt.args.ispec, tt.args.cspec)
+			got, err := p.Plan(tt.args.nsn, tt.args.src, tt.args.destroy, tt.args.ispec, tt.args.cspec, v1.RolloutSpec{})
 
 			// collect metaa struct refs
 			var gotmeta []*step.Metaa
@@ -337,7 +337,7 @@ func TestPlanner_Plan_step_hash(t *testing.T) {
 			ispec := infraSpec("does/not/matter")
 			cspec := clusterSpec("does/not/matter/either")
 
-			p1, err := p.Plan(nsn, src, false, ispec, cspec)
+			p1, err := p.Plan(nsn, src, false, ispec, cspec, v1.RolloutSpec{})
 			assert.NoError(t, err)
 
 			if tt.mutateISpec != nil {
@@ -347,7 +347,7 @@ func TestPlanner_Plan_step_hash(t *testing.T) {
 				tt.mutateCSpec(&cspec)
 			}
 
-			p2, err := p.Plan(nsn, src, false, ispec, cspec)
+			p2, err := p.Plan(nsn, src, false, ispec, cspec, v1.RolloutSpec{})
 			assert.NoError(t, err)
 
 			// compare the step hashes of both plans and collect the names of the steps that have changed.
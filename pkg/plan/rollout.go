@@ -0,0 +1,43 @@
+package plan
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+)
+
+// Batches returns the names of the clusters in cspec grouped in the order they are rolled out.
+// Canaries form the first batch, the other clusters follow in batches of rollout.BatchSize.
+// Without a rollout spec all clusters are in one batch.
+func Batches(rollout v1.RolloutSpec, cspec []v1.ClusterSpec) [][]string {
+	canaries := make(map[string]bool, len(rollout.Canaries))
+	for _, n := range rollout.Canaries {
+		canaries[n] = true
+	}
+
+	var first, rest []string
+	for _, c := range cspec {
+		if canaries[c.Name] {
+			first = append(first, c.Name)
+		} else {
+			rest = append(rest, c.Name)
+		}
+	}
+
+	var r [][]string
+	if len(first) > 0 {
+		r = append(r, first)
+	}
+	size := int(rollout.BatchSize)
+	if size <= 0 {
+		size = len(rest)
+	}
+	for len(rest) > 0 {
+		n := size
+		if n > len(rest) {
+			n = len(rest)
+		}
+		r = append(r, rest[:n])
+		rest = rest[n:]
+	}
+
+	return r
+}
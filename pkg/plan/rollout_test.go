@@ -0,0 +1,105 @@
+package plan
+
+import (
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"github.com/mmlt/environment-operator/pkg/source"
+	"github.com/mmlt/environment-operator/pkg/step"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+)
+
+func TestBatches(t *testing.T) {
+	cspec := []v1.ClusterSpec{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}, {Name: "e"}}
+
+	tests := []struct {
+		it      string
+		rollout v1.RolloutSpec
+		cspec   []v1.ClusterSpec
+		want    [][]string
+	}{
+		{
+			it:   "should return all clusters in one batch when no rollout is specified",
+			want: [][]string{{"a", "b", "c", "d", "e"}},
+		},
+		{
+			it:      "should return canaries first",
+			rollout: v1.RolloutSpec{Canaries: []string{"d", "x"}},
+			want:    [][]string{{"d"}, {"a", "b", "c", "e"}},
+		},
+		{
+			it:      "should return batches of batchSize after the canaries",
+			rollout: v1.RolloutSpec{Canaries: []string{"a"}, BatchSize: 3},
+			want:    [][]string{{"a"}, {"b", "c", "d"}, {"e"}},
+		},
+		{
+			it:      "should return nothing when there are no clusters",
+			rollout: v1.RolloutSpec{Canaries: []string{"a"}, BatchSize: 3},
+			cspec:   []v1.ClusterSpec{},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			cs := cspec
+			if tst.cspec != nil {
+				cs = tst.cspec
+			}
+			got := Batches(tst.rollout, cs)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
+
+func TestPlanner_Plan_rollout(t *testing.T) {
+	src := fakeSource{
+		workspace: source.Workspace{
+			Path:   "does/not/matter",
+			Hash:   "9999",
+			Synced: true,
+		},
+	}
+	nsn := types.NamespacedName{Namespace: "default", Name: "test"}
+
+	var cspec []v1.ClusterSpec
+	for _, n := range []string{"a", "b", "c"} {
+		cl := clusterSpec("does/not/matter/either")[0]
+		cl.Name = n
+		cspec = append(cspec, cl)
+	}
+	rollout := v1.RolloutSpec{Canaries: []string{"b"}, BatchSize: 1}
+
+	p := &Planner{
+		Azure: &azure.AZFake{},
+		Log:   logr.Discard(),
+	}
+	pl, err := p.Plan(nsn, src, false, infraSpec("does/not/matter"), cspec, rollout)
+	assert.NoError(t, err)
+
+	var got []string
+	for _, s := range pl {
+		got = append(got, s.GetID().ShortName())
+	}
+	assert.Equal(t, []string{"Infra",
+		"AKSPoolb", "AKSAddonPreflightb", "Addonsb", "RolloutGate1",
+		"AKSPoola", "AKSAddonPreflighta", "Addonsa", "RolloutGate2",
+		"AKSPoolc", "AKSAddonPreflightc", "Addonsc"}, got)
+
+	gate := pl[4].(*step.RolloutGateStep)
+	assert.Equal(t, []string{"AKSPoolb", "AKSAddonPreflightb", "Addonsb"}, gate.Steps)
+	assert.Equal(t, []string{"b"}, keys(gate.KCPaths))
+
+	possible := p.PossibleSteps(cspec, rollout)
+	for _, n := range got {
+		assert.Contains(t, possible, n)
+	}
+}
+
+func keys(m map[string]string) []string {
+	var r []string
+	for k := range m {
+		r = append(r, k)
+	}
+	return r
+}
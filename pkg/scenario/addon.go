@@ -0,0 +1,58 @@
+package scenario
+
+import (
+	"context"
+	"github.com/mmlt/environment-operator/pkg/client/addon"
+	"strconv"
+	"time"
+)
+
+// AddonClient is an Addonr that returns the results of a Scenario.
+// The cluster is derived from the kubeconfig path.
+type AddonClient struct {
+	Scenario *Scenario
+	// Interval is the time between results, zero means 1s.
+	Interval time.Duration
+}
+
+var _ addon.Addonr = &AddonClient{}
+
+// Start implements Addonr.
+// The results report the scenario Addons as changed followed by the scenario AddonErrors (if any).
+func (a *AddonClient) Start(ctx context.Context, env []string, dir, jobPath, valuesPath, kubeconfigPath, masterVaultPath string) (chan addon.KTResult, error) {
+	cl, err := a.Scenario.clusterFromKubeconfigPath(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	objs := cl.Addons
+	if len(objs) == 0 {
+		objs = []string{"namespace/kube-system unchanged"}
+	}
+	var rs []addon.KTResult
+	for i, o := range objs {
+		rs = append(rs, addon.KTResult{Changed: i + 1, Object: o, ObjectID: strconv.Itoa(i + 1), Action: "apply"})
+	}
+	if len(cl.AddonErrors) > 0 {
+		last := rs[len(rs)-1]
+		last.Errors = cl.AddonErrors
+		rs = append(rs, last)
+	}
+
+	out := make(chan addon.KTResult)
+	go func() {
+		ticker := time.NewTicker(interval(a.Interval))
+		defer ticker.Stop()
+		for _, v := range rs {
+			select {
+			case <-ticker.C:
+				out <- v
+			case <-ctx.Done():
+				return
+			}
+		}
+		close(out)
+	}()
+
+	return out, nil
+}
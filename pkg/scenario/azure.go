@@ -0,0 +1,156 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"sync"
+)
+
+// AzureClient is an AZer that returns the results of a Scenario.
+type AzureClient struct {
+	Scenario *Scenario
+
+	mu sync.Mutex
+	// polls is the number of AKSNodepool calls per cluster/pool.
+	polls map[string]int
+	// versions are the upgraded versions per cluster/pool.
+	versions map[string]string
+}
+
+var _ azure.AZer = &AzureClient{}
+
+// SetSubscription implements AZer.
+func (c *AzureClient) SetSubscription(sub string) {}
+
+// LoginSP implements AZer.
+func (c *AzureClient) LoginSP(user, password, tenant string) error {
+	return nil
+}
+
+// LoginMSI implements AZer.
+func (c *AzureClient) LoginMSI(clientID string) error {
+	return nil
+}
+
+// LoginFederated implements AZer.
+func (c *AzureClient) LoginFederated(clientID, tenant, tokenFile string) error {
+	return nil
+}
+
+// Logout implements AZer.
+func (c *AzureClient) Logout() error {
+	return nil
+}
+
+// KeyvaultSecret implements AZer.
+func (c *AzureClient) KeyvaultSecret(name, vaultName string) (string, error) {
+	return "", nil
+}
+
+// AKSNodepoolList implements AZer.
+func (c *AzureClient) AKSNodepoolList(ctx context.Context, resourceGroup, cluster string) ([]azure.AKSNodepool, error) {
+	cl, ok := c.Scenario.cluster(cluster)
+	if !ok {
+		return nil, fmt.Errorf("scenario: no cluster %s", cluster)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r := make([]azure.AKSNodepool, len(cl.Nodepools))
+	for i, p := range cl.Nodepools {
+		if v, ok := c.versions[cluster+"/"+p.Name]; ok {
+			p.OrchestratorVersion = v
+		}
+		if p.ProvisioningState == "" {
+			p.ProvisioningState = azure.Succeeded
+		}
+		p.ResourceGroup = resourceGroup
+		r[i] = p
+	}
+	return r, nil
+}
+
+// AKSNodepool implements AZer.
+// Subsequent calls return the scenario ProvisioningStates.
+func (c *AzureClient) AKSNodepool(ctx context.Context, resourceGroup, cluster, nodepool string) (*azure.AKSNodepool, error) {
+	cl, p, err := c.nodepool(cluster, nodepool)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.polls == nil {
+		c.polls = make(map[string]int)
+	}
+	k := cluster + "/" + nodepool
+	i := c.polls[k]
+	c.polls[k]++
+
+	p.ProvisioningState = azure.Succeeded
+	if n := len(cl.ProvisioningStates); n > 0 {
+		if i >= n {
+			i = n - 1
+		}
+		p.ProvisioningState = cl.ProvisioningStates[i]
+	}
+	if v, ok := c.versions[k]; ok {
+		p.OrchestratorVersion = v
+	}
+	p.ResourceGroup = resourceGroup
+	return &p, nil
+}
+
+// AKSNodepoolUpgrade implements AZer.
+// The upgrade returns the scenario UpgradeError or succeeds immediately.
+func (c *AzureClient) AKSNodepoolUpgrade(ctx context.Context, resourceGroup, cluster, nodepool, version string) (*azure.AKSNodepool, error) {
+	cl, p, err := c.nodepool(cluster, nodepool)
+	if err != nil {
+		return nil, err
+	}
+	if cl.UpgradeError != "" {
+		return nil, fmt.Errorf("%s", cl.UpgradeError)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.versions == nil {
+		c.versions = make(map[string]string)
+	}
+	c.versions[cluster+"/"+nodepool] = version
+
+	p.OrchestratorVersion = version
+	p.ProvisioningState = azure.Succeeded
+	p.ResourceGroup = resourceGroup
+	return &p, nil
+}
+
+// Autoscaler implements AZer.
+func (c *AzureClient) Autoscaler(ctx context.Context, enable bool, resourceGroup string, cluster string, pool string, minCount int, maxCount int) error {
+	return nil
+}
+
+// AllAutoscalers implements AZer.
+func (c *AzureClient) AllAutoscalers(ctx context.Context, enable bool, clusters []v1.ClusterSpec, resourceGroup string, log logr.Logger) error {
+	return nil
+}
+
+// Nodepool returns the scenario cluster and nodepool.
+func (c *AzureClient) nodepool(cluster, nodepool string) (Cluster, azure.AKSNodepool, error) {
+	cl, ok := c.Scenario.cluster(cluster)
+	if !ok {
+		return Cluster{}, azure.AKSNodepool{}, fmt.Errorf("scenario: no cluster %s", cluster)
+	}
+	for _, p := range cl.Nodepools {
+		if p.Name == nodepool {
+			return cl, p, nil
+		}
+	}
+	return Cluster{}, azure.AKSNodepool{}, fmt.Errorf("scenario: no nodepool %s in cluster %s", nodepool, cluster)
+}
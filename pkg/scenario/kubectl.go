@@ -0,0 +1,188 @@
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"github.com/mmlt/environment-operator/pkg/client/kubectl"
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sync"
+)
+
+// KubectlClient is a Kubectrler that returns the results of a Scenario.
+// The cluster is derived from the kubeconfig path.
+type KubectlClient struct {
+	Scenario *Scenario
+
+	mu sync.Mutex
+	// pods are the states of the pods that are run, per kubeconfig path and pod name.
+	pods map[string]string
+}
+
+var _ kubectl.Kubectrler = &KubectlClient{}
+
+// PodState implements Kubectrler.
+// A pod that is run is PodRunning once and after that it has the scenario Probe state.
+func (k *KubectlClient) PodState(ctx context.Context, kubeconfigPath, namespace, name string) (string, error) {
+	cl, err := k.Scenario.clusterFromKubeconfigPath(kubeconfigPath)
+	if err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := kubeconfigPath + "/" + namespace + "/" + name
+	s, ok := k.pods[key]
+	if !ok {
+		return "", nil
+	}
+	if s == "PodRunning" {
+		k.pods[key] = probe(cl)
+	}
+	return s, nil
+}
+
+// PodRun implements Kubectrler.
+func (k *KubectlClient) PodRun(ctx context.Context, kubeconfigPath, namespace, name, image, cmd string) error {
+	_, err := k.Scenario.clusterFromKubeconfigPath(kubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.pods == nil {
+		k.pods = make(map[string]string)
+	}
+	key := kubeconfigPath + "/" + namespace + "/" + name
+	if _, ok := k.pods[key]; ok {
+		return fmt.Errorf("pod already present")
+	}
+	k.pods[key] = "PodRunning"
+	return nil
+}
+
+// PodLog implements Kubectrler.
+func (k *KubectlClient) PodLog(ctx context.Context, kubeconfigPath, namespace, name string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	switch k.pods[kubeconfigPath+"/"+namespace+"/"+name] {
+	case "PodRunning":
+		return "", nil
+	case "PodCompleted":
+		return `  "kind": "Status",` + "\n", nil
+	case "PodError":
+		return "", fmt.Errorf("an error happened")
+	default:
+		return "", fmt.Errorf("no pod present")
+	}
+}
+
+// PodDelete implements Kubectrler.
+func (k *KubectlClient) PodDelete(ctx context.Context, kubeconfigPath, namespace, name string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := kubeconfigPath + "/" + namespace + "/" + name
+	if _, ok := k.pods[key]; !ok {
+		return fmt.Errorf("no pod present")
+	}
+	delete(k.pods, key)
+	return nil
+}
+
+// StorageClasses implements Kubectrler.
+// It returns a default StorageClass.
+func (k *KubectlClient) StorageClasses(ctx context.Context, kubeconfigPath string) ([]storagev1.StorageClass, error) {
+	_, err := k.Scenario.clusterFromKubeconfigPath(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []storagev1.StorageClass{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "default",
+				Annotations: map[string]string{
+					"storageclass.kubernetes.io/is-default-class": "true",
+				},
+			},
+		},
+	}
+	return r, nil
+}
+
+// Nodes implements Kubectrler.
+// It returns a Ready node and the scenario NotReadyNodes.
+func (k *KubectlClient) Nodes(ctx context.Context, kubeconfigPath string) ([]v1.Node, error) {
+	cl, err := k.Scenario.clusterFromKubeconfigPath(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []v1.Node{node("node-0", v1.ConditionTrue)}
+	for _, n := range cl.NotReadyNodes {
+		r = append(r, node(n, v1.ConditionFalse))
+	}
+	return r, nil
+}
+
+// Pods implements Kubectrler.
+// It returns a Running and Ready pod and the scenario NotReadyPods.
+func (k *KubectlClient) Pods(ctx context.Context, kubeconfigPath, namespace string) ([]v1.Pod, error) {
+	cl, err := k.Scenario.clusterFromKubeconfigPath(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if namespace == "" {
+		namespace = "kube-system"
+	}
+	r := []v1.Pod{pod(namespace, "addon-0", v1.ConditionTrue)}
+	for _, n := range cl.NotReadyPods {
+		r = append(r, pod(namespace, n, v1.ConditionFalse))
+	}
+	return r, nil
+}
+
+// WipeCluster implements Kubectrler.
+func (k *KubectlClient) WipeCluster(ctx context.Context, kubeconfigPath string) error {
+	return nil
+}
+
+// Probe returns the final state of the probe pod of cl.
+func probe(cl Cluster) string {
+	if cl.Probe == "" {
+		return "PodCompleted"
+	}
+	return cl.Probe
+}
+
+// Node returns a Node with Ready condition status.
+func node(name string, status v1.ConditionStatus) v1.Node {
+	return v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{
+				{Type: v1.NodeReady, Status: status},
+			},
+		},
+	}
+}
+
+// Pod returns a Running Pod with Ready condition status.
+func pod(namespace, name string, status v1.ConditionStatus) v1.Pod {
+	return v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status: v1.PodStatus{
+			Phase: v1.PodRunning,
+			Conditions: []v1.PodCondition{
+				{Type: v1.PodReady, Status: status},
+			},
+		},
+	}
+}
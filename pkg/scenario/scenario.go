@@ -0,0 +1,137 @@
+// Package scenario provides fake clients that return the results described in a scenario file.
+// A scenario is used by the dryruncontroller to rehearse changes to Environment resources.
+package scenario
+
+import (
+	"encoding/base64"
+	"fmt"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"io/ioutil"
+	"path/filepath"
+	"sigs.k8s.io/yaml"
+	"strings"
+)
+
+// Scenario describes the results of the terraform, az, kubectl and kubectl-tmplt invocations.
+type Scenario struct {
+	// Terraform describes the results of terraform.
+	Terraform Terraform `json:"terraform"`
+	// Clusters describes per cluster name the results of az, kubectl and kubectl-tmplt.
+	// Clusters are also the clusters in the terraform output.
+	Clusters map[string]Cluster `json:"clusters"`
+}
+
+// Terraform describes the results of terraform.
+type Terraform struct {
+	// Changes are the resource changes in the plan.
+	// The plan, apply and destroy results are derived from the changes.
+	Changes []ResourceChange `json:"changes,omitempty"`
+	// Errors injects failures.
+	Errors TerraformErrors `json:"errors,omitempty"`
+}
+
+// ResourceChange is a change in the terraform plan.
+type ResourceChange struct {
+	// Address is the resource address, for example module.aks1.azurerm_kubernetes_cluster.this
+	Address string `json:"address"`
+	// Type is the resource type, for example azurerm_kubernetes_cluster
+	Type string `json:"type"`
+	// Actions are the plan actions; create, update and/or delete.
+	Actions []string `json:"actions"`
+	// Before (optional) is the state of the resource before the change.
+	Before map[string]interface{} `json:"before,omitempty"`
+	// After (optional) is the state of the resource after the change.
+	After map[string]interface{} `json:"after,omitempty"`
+}
+
+// TerraformErrors are the error messages returned by terraform commands, empty means success.
+type TerraformErrors struct {
+	Init    string `json:"init,omitempty"`
+	Plan    string `json:"plan,omitempty"`
+	Apply   string `json:"apply,omitempty"`
+	Destroy string `json:"destroy,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+// Cluster describes the results of az, kubectl and kubectl-tmplt for a cluster.
+type Cluster struct {
+	// KubeAdminConfig (optional) is the kube_admin_config in the terraform output.
+	// Values of client_certificate, client_key and cluster_ca_certificate are base64 encoded.
+	KubeAdminConfig map[string]string `json:"kubeAdminConfig,omitempty"`
+
+	// Nodepools are the AKS node pools of the cluster, a pool without provisioningState has state "Succeeded".
+	Nodepools []azure.AKSNodepool `json:"nodepools,omitempty"`
+	// ProvisioningStates are the node pool provisioning states returned on subsequent requests.
+	// When all states have been returned the last one is repeated, empty means "Succeeded".
+	ProvisioningStates []azure.ProvisioningState `json:"provisioningStates,omitempty"`
+	// UpgradeError (optional) is returned by a node pool upgrade.
+	UpgradeError string `json:"upgradeError,omitempty"`
+
+	// Probe is the state of the preflight probe pod; PodCompleted (default), PodRunning (never completes) or PodError.
+	Probe string `json:"probe,omitempty"`
+	// NotReadyNodes are the names of Nodes that are not Ready.
+	NotReadyNodes []string `json:"notReadyNodes,omitempty"`
+	// NotReadyPods are the names of Pods that are not Ready.
+	NotReadyPods []string `json:"notReadyPods,omitempty"`
+
+	// Addons are the objects applied by kubectl-tmplt, empty means a single object.
+	Addons []string `json:"addons,omitempty"`
+	// AddonErrors are the errors reported by kubectl-tmplt.
+	AddonErrors []string `json:"addonErrors,omitempty"`
+}
+
+// Load reads a YAML scenario from path.
+func Load(path string) (*Scenario, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := &Scenario{}
+	err = yaml.UnmarshalStrict(b, s)
+	if err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Cluster returns the cluster with name.
+// Name can be the cluster name or the name as used in Azure, for example "saks001someenv-mycluster".
+func (s *Scenario) cluster(name string) (Cluster, bool) {
+	if c, ok := s.Clusters[name]; ok {
+		return c, true
+	}
+	for n, c := range s.Clusters {
+		if strings.HasSuffix(name, "-"+n) {
+			return c, true
+		}
+	}
+	return Cluster{}, false
+}
+
+// ClusterFromKubeconfigPath returns the cluster addressed by a kubeconfig path.
+// The path is expected to be <cluster workspace>/kubeconfig with the cluster name being the workspace name.
+func (s *Scenario) clusterFromKubeconfigPath(path string) (Cluster, error) {
+	n := filepath.Base(filepath.Dir(path))
+	c, ok := s.cluster(n)
+	if !ok {
+		return Cluster{}, fmt.Errorf("scenario: no cluster %s", n)
+	}
+	return c, nil
+}
+
+// KubeAdminConfig returns the kube_admin_config of c with defaults for missing values.
+func (c Cluster) kubeAdminConfig() map[string]interface{} {
+	fake := base64.StdEncoding.EncodeToString([]byte("fake"))
+	r := map[string]interface{}{
+		"client_certificate":     fake,
+		"client_key":             fake,
+		"cluster_ca_certificate": fake,
+		"host":                   "https://api.kubernetes.example.com:443",
+		"password":               "4ee5bb2",
+		"username":               "someadmin",
+	}
+	for k, v := range c.KubeAdminConfig {
+		r[k] = v
+	}
+	return r
+}
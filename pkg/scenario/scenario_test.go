@@ -0,0 +1,179 @@
+package scenario
+
+import (
+	"context"
+	"github.com/mmlt/environment-operator/pkg/client/azure"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	sc, err := Load("testdata/scenario.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, sc.Terraform.Changes, 3)
+	assert.Equal(t, []azure.ProvisioningState{azure.Updating, azure.Succeeded}, sc.Clusters["cpe"].ProvisioningStates)
+	assert.Equal(t, []string{"coredns-0"}, sc.Clusters["xyz"].NotReadyPods)
+
+	dir, err := ioutil.TempDir("", "scenario")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	p := filepath.Join(dir, "typo.yaml")
+	err = ioutil.WriteFile(p, []byte("clusters:\n  xyz:\n    prob: PodError\n"), 0640)
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = Load(p)
+	assert.Error(t, err, "unknown fields are rejected")
+}
+
+func TestTerraformClient(t *testing.T) {
+	tests := []struct {
+		it        string
+		errors    TerraformErrors
+		wantPlan  terraform.TFResult
+		wantApply terraform.TFApplyResult
+	}{
+		{
+			it:       "should_derive_plan_and_apply_results_from_changes",
+			wantPlan: terraform.TFResult{Info: 1, PlanAdded: 1, PlanChanged: 2, PlanDeleted: 1},
+			wantApply: terraform.TFApplyResult{Creating: 1, Modifying: 2, Destroying: 1,
+				TotalAdded: 1, TotalChanged: 2, TotalDestroyed: 1},
+		},
+		{
+			it:        "should_inject_failures",
+			errors:    TerraformErrors{Plan: "boom", Apply: "Error: quota exceeded"},
+			wantPlan:  terraform.TFResult{Errors: []string{"boom"}},
+			wantApply: terraform.TFApplyResult{Creating: 1, Modifying: 2, Destroying: 1, Errors: []string{"Error: quota exceeded"}},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			sc, err := Load("testdata/scenario.yaml")
+			if !assert.NoError(t, err) {
+				return
+			}
+			sc.Terraform.Errors = tst.errors
+			tf := &TerraformClient{Scenario: sc, Interval: time.Millisecond}
+
+			assert.Equal(t, &tst.wantPlan, tf.Plan(context.Background(), nil, ""))
+
+			ch, err := tf.StartApply(context.Background(), nil, "")
+			if !assert.NoError(t, err) {
+				return
+			}
+			var last terraform.TFApplyResult
+			for r := range ch {
+				last = r
+			}
+			assert.Equal(t, tst.wantApply, last)
+		})
+	}
+}
+
+func TestTerraformClient_GetPlan(t *testing.T) {
+	sc, err := Load("testdata/scenario.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	tf := &TerraformClient{Scenario: sc}
+
+	plan, err := tf.GetPlan(context.Background(), nil, "")
+	if !assert.NoError(t, err) {
+		return
+	}
+	changes, err := terraform.ResourceChangesFromPlan(plan)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, changes, 3) {
+		assert.Equal(t, "module.aks1.azurerm_kubernetes_cluster.this", changes[1].Address)
+		assert.True(t, changes[2].Action.IsReplace())
+	}
+
+	out, err := tf.Output(context.Background(), nil, "")
+	if assert.NoError(t, err) {
+		assert.Len(t, out["clusters"].(map[string]interface{})["value"], 2)
+	}
+}
+
+func TestAzureClient_AKSNodepoolUpgrade(t *testing.T) {
+	sc, err := Load("testdata/scenario.yaml")
+	if !assert.NoError(t, err) {
+		return
+	}
+	az := &AzureClient{Scenario: sc}
+	cluster := "raks001someenv-cpe"
+
+	pools, err := az.AKSNodepoolList(context.Background(), "rg", cluster)
+	if assert.NoError(t, err) && assert.Len(t, pools, 1) {
+		assert.Equal(t, azure.Succeeded, pools[0].ProvisioningState)
+		assert.Equal(t, "1.20.7", pools[0].OrchestratorVersion)
+	}
+
+	for _, want := range []azure.ProvisioningState{azure.Updating, azure.Succeeded, azure.Succeeded} {
+		p, err := az.AKSNodepool(context.Background(), "rg", cluster, "default")
+		if assert.NoError(t, err) {
+			assert.Equal(t, want, p.ProvisioningState)
+		}
+	}
+
+	_, err = az.AKSNodepoolUpgrade(context.Background(), "rg", cluster, "default", "1.21.2")
+	assert.NoError(t, err)
+
+	pools, err = az.AKSNodepoolList(context.Background(), "rg", cluster)
+	if assert.NoError(t, err) && assert.Len(t, pools, 1) {
+		assert.Equal(t, "1.21.2", pools[0].OrchestratorVersion, "upgraded version")
+	}
+
+	_, err = az.AKSNodepoolList(context.Background(), "rg", "raks001someenv-unknown")
+	assert.EqualError(t, err, "scenario: no cluster raks001someenv-unknown")
+}
+
+func TestKubectlClient_PodState(t *testing.T) {
+	tests := []struct {
+		it    string
+		probe string
+		want  []string
+	}{
+		{
+			it:   "should_complete_the_probe_pod_by_default",
+			want: []string{"PodRunning", "PodCompleted", "PodCompleted"},
+		},
+		{
+			it:    "should_fail_the_probe_pod",
+			probe: "PodError",
+			want:  []string{"PodRunning", "PodError", "PodError"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			sc := &Scenario{Clusters: map[string]Cluster{"xyz": {Probe: tst.probe}}}
+			kc := &KubectlClient{Scenario: sc}
+			kcPath := "/tmp/workspace/default/env/xyz/kubeconfig"
+
+			s, err := kc.PodState(context.Background(), kcPath, "kube-system", "probe")
+			assert.NoError(t, err)
+			assert.Empty(t, s, "no pod before run")
+
+			assert.NoError(t, kc.PodRun(context.Background(), kcPath, "kube-system", "probe", "image", "cmd"))
+			var got []string
+			for range tst.want {
+				s, err := kc.PodState(context.Background(), kcPath, "kube-system", "probe")
+				assert.NoError(t, err)
+				got = append(got, s)
+			}
+			assert.Equal(t, tst.want, got)
+
+			assert.NoError(t, kc.PodDelete(context.Background(), kcPath, "kube-system", "probe"))
+		})
+	}
+}
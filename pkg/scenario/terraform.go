@@ -0,0 +1,199 @@
+package scenario
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"time"
+)
+
+// TerraformClient is a Terraformer that returns the results of a Scenario.
+type TerraformClient struct {
+	Scenario *Scenario
+	// Interval is the time between apply or destroy results, zero means 1s.
+	Interval time.Duration
+}
+
+var _ terraform.Terraformer = &TerraformClient{}
+
+// Init implements Terraformer.
+func (t *TerraformClient) Init(ctx context.Context, env []string, dir string) *terraform.TFResult {
+	return result(t.Scenario.Terraform.Errors.Init)
+}
+
+// Plan implements Terraformer.
+func (t *TerraformClient) Plan(ctx context.Context, env []string, dir string) *terraform.TFResult {
+	r := result(t.Scenario.Terraform.Errors.Plan)
+	if len(r.Errors) > 0 {
+		return r
+	}
+	for _, c := range t.Scenario.Terraform.Changes {
+		for _, a := range c.Actions {
+			switch a {
+			case "create":
+				r.PlanAdded++
+			case "update":
+				r.PlanChanged++
+			case "delete":
+				r.PlanDeleted++
+			}
+		}
+	}
+	return r
+}
+
+// GetPlan implements Terraformer.
+func (t *TerraformClient) GetPlan(ctx context.Context, env []string, dir string) (*gabs.Container, error) {
+	var chgs []interface{}
+	for _, c := range t.Scenario.Terraform.Changes {
+		chg := map[string]interface{}{
+			"actions": c.Actions,
+		}
+		if c.Before != nil {
+			chg["before"] = c.Before
+		}
+		if c.After != nil {
+			chg["after"] = c.After
+		}
+		chgs = append(chgs, map[string]interface{}{
+			"address": c.Address,
+			"type":    c.Type,
+			"change":  chg,
+		})
+	}
+
+	// round-trip to get the same types as a parsed 'terraform show' result.
+	b, err := json.Marshal(map[string]interface{}{"resource_changes": chgs})
+	if err != nil {
+		return nil, err
+	}
+	return gabs.ParseJSON(b)
+}
+
+// StartApply implements Terraformer.
+// The results report the changes of the plan.
+func (t *TerraformClient) StartApply(ctx context.Context, env []string, dir string) (chan terraform.TFApplyResult, error) {
+	var rs []terraform.TFApplyResult
+	var last terraform.TFApplyResult
+	for _, c := range t.Scenario.Terraform.Changes {
+		for _, a := range c.Actions {
+			var ing, tion string
+			switch a {
+			case "create":
+				last.Creating++
+				ing, tion = "creating", "creation"
+			case "update":
+				last.Modifying++
+				ing, tion = "modifying", "modifications"
+			case "delete":
+				last.Destroying++
+				ing, tion = "destroying", "destruction"
+			default:
+				continue
+			}
+			last.Object = c.Address
+			last.Action = ing
+			rs = append(rs, last)
+			last.Action = tion
+			last.Elapsed = "1s"
+			rs = append(rs, last)
+			last.Elapsed = ""
+		}
+	}
+
+	last.Object, last.Action = "", ""
+	if msg := t.Scenario.Terraform.Errors.Apply; msg != "" {
+		last.Errors = []string{msg}
+	} else {
+		last.TotalAdded, last.TotalChanged, last.TotalDestroyed = last.Creating, last.Modifying, last.Destroying
+	}
+	rs = append(rs, last)
+
+	return t.play(ctx, rs), nil
+}
+
+// StartDestroy implements Terraformer.
+// The results report the destruction of the resources of the plan changes.
+func (t *TerraformClient) StartDestroy(ctx context.Context, env []string, dir string) (chan terraform.TFApplyResult, error) {
+	var rs []terraform.TFApplyResult
+	var last terraform.TFApplyResult
+	for _, c := range t.Scenario.Terraform.Changes {
+		last.Destroying++
+		last.Object = c.Address
+		last.Action = "destroying"
+		rs = append(rs, last)
+		last.Action = "destruction"
+		last.Elapsed = "1s"
+		rs = append(rs, last)
+		last.Elapsed = ""
+	}
+
+	last.Object, last.Action = "", ""
+	if msg := t.Scenario.Terraform.Errors.Destroy; msg != "" {
+		last.Errors = []string{msg}
+	} else {
+		last.TotalDestroyed = last.Destroying
+	}
+	rs = append(rs, last)
+
+	return t.play(ctx, rs), nil
+}
+
+// Output implements Terraformer.
+// The output contains the kube_admin_config of the scenario clusters.
+func (t *TerraformClient) Output(ctx context.Context, env []string, dir string) (map[string]interface{}, error) {
+	if msg := t.Scenario.Terraform.Errors.Output; msg != "" {
+		return nil, fmt.Errorf("terraform output: %s", msg)
+	}
+
+	clusters := make(map[string]interface{}, len(t.Scenario.Clusters))
+	for n, c := range t.Scenario.Clusters {
+		clusters[n] = map[string]interface{}{
+			"kube_admin_config": c.kubeAdminConfig(),
+		}
+	}
+
+	return map[string]interface{}{
+		"clusters": map[string]interface{}{
+			"value": clusters,
+		},
+	}, nil
+}
+
+// Play sends rs to the returned channel with Interval in between.
+func (t *TerraformClient) play(ctx context.Context, rs []terraform.TFApplyResult) chan terraform.TFApplyResult {
+	out := make(chan terraform.TFApplyResult)
+	go func() {
+		ticker := time.NewTicker(interval(t.Interval))
+		defer ticker.Stop()
+		for _, v := range rs {
+			select {
+			case <-ticker.C:
+				out <- v
+			case <-ctx.Done():
+				return
+			}
+		}
+		close(out)
+	}()
+
+	return out
+}
+
+// Result returns a successful TFResult or a TFResult with msg as error when msg is not empty.
+func result(msg string) *terraform.TFResult {
+	if msg != "" {
+		return &terraform.TFResult{Errors: []string{msg}}
+	}
+	return &terraform.TFResult{Info: 1}
+}
+
+// Interval returns d or 1s when d is zero.
+func interval(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Second
+	}
+	return d
+}
@@ -2,12 +2,14 @@
 package source
 
 import (
+	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
 	"github.com/go-logr/logr"
 	multierror "github.com/hashicorp/go-multierror"
 	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/tracing"
 	"github.com/mmlt/environment-operator/pkg/util/exe"
 	otia10copy "github.com/otiai10/copy"
 	"hash"
@@ -48,6 +50,9 @@ type Sources struct {
 	// Repos keeps tack of the remote repos/filesystems.
 	repos map[v1.SourceSpec]repo
 
+	// Runner (optional) executes the git commands, nil means an exe.Executor is used.
+	Runner exe.Runner
+
 	Log logr.Logger
 }
 
@@ -67,6 +72,8 @@ type Workspace struct {
 	Spec v1.SourceSpec
 	// Hash of the content (limited to area).
 	Hash string
+	// Revision of the repo the content is copied from, for GIT repos this is the commit SHA.
+	Revision string
 	// Synced is true if the repo content is copied to the workspace.
 	// Synced is false as long as a repo hasn't been fetched or Get() isn't called or Get() has been called but new repo
 	// content is fetched.
@@ -130,7 +137,7 @@ func (ss *Sources) Get(nsn types.NamespacedName, name string) (bool, error) {
 		return false, fmt.Errorf("source: workspace not found: %s", name)
 	}
 
-	_, ok = ss.repos[w.Spec]
+	r, ok := ss.repos[w.Spec]
 	if !ok {
 		return false, fmt.Errorf("source: get(%s): repo not fetched yet", name)
 	}
@@ -146,6 +153,11 @@ func (ss *Sources) Get(nsn types.NamespacedName, name string) (bool, error) {
 	hs := hex.EncodeToString(h.Sum(nil))
 
 	if w.Hash == hs {
+		if w.Revision != r.hash {
+			// changes outside area.
+			w.Revision = r.hash
+			ss.workspaces[id] = w
+		}
 		return false, nil
 	}
 
@@ -160,6 +172,7 @@ func (ss *Sources) Get(nsn types.NamespacedName, name string) (bool, error) {
 	}
 
 	w.Hash = hs
+	w.Revision = r.hash
 	w.Synced = true
 	ss.workspaces[id] = w
 
@@ -178,12 +191,20 @@ func (ss *Sources) Workspace(nsn types.NamespacedName, name string) (Workspace,
 	return w, ok
 }
 
+// WorkspacePath returns the path of the workspace for a nsn + name.
+// The workspace doesn't need to be registered.
+func (ss *Sources) WorkspacePath(nsn types.NamespacedName, name string) string {
+	return ss.workspacePath(consumerID{nsn, defaultName(name)})
+}
+
 // FetchAll fetches all remote repo's or filesystems into a local repo directory.
 // The fetch rate is limited to at most once per N minutes.
-func (ss *Sources) FetchAll() error {
-	var errs error
+func (ss *Sources) FetchAll(ctx context.Context) (errs error) {
+	ctx, span := tracing.Start(ctx, "Sources.FetchAll")
+	defer func() { tracing.End(span, errs) }()
+
 	for _, w := range ss.workspaces {
-		err := ss.fetch(w.Spec)
+		err := ss.fetch(ctx, w.Spec)
 		if err != nil {
 			errs = multierror.Append(errs, err)
 		}
@@ -196,7 +217,7 @@ var timeNow = time.Now
 
 // Fetch fetches a remote repo or filesystem specified by spec into a local repo directory.
 // The fetch rate is limited to at most once per N minutes.
-func (ss *Sources) fetch(spec v1.SourceSpec) error {
+func (ss *Sources) fetch(ctx context.Context, spec v1.SourceSpec) error {
 	if ss.repos == nil {
 		ss.repos = make(map[v1.SourceSpec]repo)
 	}
@@ -206,7 +227,7 @@ func (ss *Sources) fetch(spec v1.SourceSpec) error {
 	var h string
 	switch spec.Type {
 	case v1.SourceTypeGIT:
-		h, err = ss.gitFetch(spec)
+		h, err = ss.gitFetch(ctx, spec)
 	case v1.SourceTypeLocal:
 		h, err = ss.localFetch(spec)
 	default:
@@ -256,7 +277,16 @@ func (ss *Sources) localFetch(spec v1.SourceSpec) (string, error) {
 }
 
 // GITFetch fetches content of a GIT repo and returns its hash.
-func (ss *Sources) gitFetch(spec v1.SourceSpec) (string, error) {
+func (ss *Sources) gitFetch(ctx context.Context, spec v1.SourceSpec) (string, error) {
+	git := func(dir string, args ...string) (string, error) {
+		var rn exe.Runner = &exe.Executor{Log: ss.Log}
+		if ss.Runner != nil {
+			rn = ss.Runner
+		}
+		r, err := rn.Run(ctx, exe.Command{Name: "git", Args: args, Dir: dir, Secrets: []string{spec.Token}})
+		return r.Stdout, err
+	}
+
 	p := ss.repoPath(spec)
 	_, err := os.Stat(p)
 	if os.IsNotExist(err) {
@@ -267,19 +297,22 @@ func (ss *Sources) gitFetch(spec v1.SourceSpec) (string, error) {
 			return "", err
 		}
 
-		_, _, err = exe.Run(ss.Log, &exe.Opt{Dir: d}, "", "git", "clone", urlWithToken(spec.URL, spec.Token))
+		_, err = git(d, "clone", urlWithToken(spec.URL, spec.Token))
 		if err != nil {
 			return "", err
 		}
 
-		_, _, err = exe.Run(ss.Log, &exe.Opt{Dir: p}, "", "git", "checkout", spec.Ref)
+		_, err = git(p, "checkout", spec.Ref)
 		if err != nil {
 			return "", err
 		}
 		ss.Log.Info("GIT-clone", "url", spec.URL, "ref", spec.Ref)
+	} else if isCommitSHA(spec.Ref) {
+		// A commit doesn't change, no need to pull.
+		ss.Log.V(2).Info("GIT-pull skipped for commit", "url", spec.URL, "ref", spec.Ref)
 	} else {
 		// Pull existing repo content.
-		_, _, err = exe.Run(ss.Log, &exe.Opt{Dir: p}, "", "git", "pull", "origin", spec.Ref)
+		_, err = git(p, "pull", "origin", spec.Ref)
 		if err != nil {
 			return "", err
 		}
@@ -287,7 +320,7 @@ func (ss *Sources) gitFetch(spec v1.SourceSpec) (string, error) {
 	}
 
 	// Get hash.
-	h, _, err := exe.Run(ss.Log, &exe.Opt{Dir: p}, "", "git", "rev-parse", spec.Ref)
+	h, err := git(p, "rev-parse", spec.Ref)
 	if err != nil {
 		return "", err
 	}
@@ -366,6 +399,15 @@ func defaultName(name string) string {
 	return name
 }
 
+// IsCommitSHA returns true if ref is a full (40 hex chars) GIT commit SHA.
+func isCommitSHA(ref string) bool {
+	if len(ref) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(ref)
+	return err == nil
+}
+
 // URLWithToken merges an optional token into url that starts with 'https://'
 func urlWithToken(url, token string) string {
 	if token == "" {
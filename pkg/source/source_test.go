@@ -1,6 +1,7 @@
 package source
 
 import (
+	"context"
 	v1 "github.com/mmlt/environment-operator/api/v1"
 	otia10copy "github.com/otiai10/copy"
 	"github.com/stretchr/testify/assert"
@@ -58,7 +59,7 @@ func TestSources_e2e_local_spec_change(t *testing.T) {
 			return
 		}
 
-		err = ss.FetchAll()
+		err = ss.FetchAll(context.Background())
 		if !assert.NoError(t, err, "FetchAll") {
 			return
 		}
@@ -117,6 +118,7 @@ func TestSources_e2e_local_content_change(t *testing.T) {
 	assert.NoError(t, err)
 	defer assert.NoError(t, os.RemoveAll(src))
 
+	var revision string
 	for _, mutation := range mutations {
 		spec := v1.SourceSpec{
 			Type: "local",
@@ -133,7 +135,7 @@ func TestSources_e2e_local_content_change(t *testing.T) {
 			return
 		}
 
-		err = ss.FetchAll()
+		err = ss.FetchAll(context.Background())
 		if !assert.NoError(t, err, "FetchAll") {
 			return
 		}
@@ -144,6 +146,11 @@ func TestSources_e2e_local_content_change(t *testing.T) {
 		}
 
 		assert.Equal(t, mutation.wantChanged, gotChanged, "<changed> at mutation '%s'", mutation.comment)
+
+		// revision reflects the whole repo, also when the area is unchanged.
+		w, _ := ss.Workspace(nsn, name)
+		assert.NotEqual(t, revision, w.Revision, "<revision> at mutation '%s'", mutation.comment)
+		revision = w.Revision
 	}
 
 	assert.FileExists(t, filepath.Join(ss.RootPath, "workspace", nsn.Namespace, nsn.Name, name, "content", "file2.txt"))
@@ -0,0 +1,86 @@
+package step
+
+import (
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"sort"
+)
+
+// ChangeCounts are the number of resources that are planned to be added, updated, deleted or replaced.
+// A replace is also counted as an add and a delete.
+type changeCounts struct {
+	added, updated, deleted, replaced int
+}
+
+// Count adds action to the receiver.
+func (c *changeCounts) count(a terraform.Action) {
+	if a&terraform.ActionCreate != 0 {
+		c.added++
+	}
+	if a&terraform.ActionUpdate != 0 {
+		c.updated++
+	}
+	if a&terraform.ActionDelete != 0 {
+		c.deleted++
+	}
+	if a.IsReplace() {
+		c.replaced++
+	}
+}
+
+// BudgetViolations returns a message for each budget limit that is exceeded by the planned changes.
+func budgetViolations(b v1.InfraBudget, changes []terraform.ResourceChange) []string {
+	var total changeCounts
+	types := map[string]*changeCounts{}
+	for _, chg := range changes {
+		total.count(chg.Action)
+		c, ok := types[chg.Type]
+		if !ok {
+			c = &changeCounts{}
+			types[chg.Type] = c
+		}
+		c.count(chg.Action)
+	}
+
+	msgs := limitViolations("", total, v1.ResourceBudget{
+		AddLimit:     b.AddLimit,
+		UpdateLimit:  b.UpdateLimit,
+		DeleteLimit:  b.DeleteLimit,
+		ReplaceLimit: b.ReplaceLimit,
+	})
+
+	// sort for stable messages.
+	names := make([]string, 0, len(b.ResourceTypes))
+	for n := range b.ResourceTypes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		c, ok := types[n]
+		if !ok {
+			continue
+		}
+		msgs = append(msgs, limitViolations(n+" ", *c, b.ResourceTypes[n])...)
+	}
+
+	return msgs
+}
+
+// LimitViolations returns a message for each limit in b that is exceeded by c.
+func limitViolations(prefix string, c changeCounts, b v1.ResourceBudget) []string {
+	var msgs []string
+	if b.AddLimit != nil && c.added > int(*b.AddLimit) {
+		msgs = append(msgs, fmt.Sprintf("%sadded %d exceeds addLimit %d", prefix, c.added, *b.AddLimit))
+	}
+	if b.UpdateLimit != nil && c.updated > int(*b.UpdateLimit) {
+		msgs = append(msgs, fmt.Sprintf("%schanged %d exceeds updateLimit %d", prefix, c.updated, *b.UpdateLimit))
+	}
+	if b.DeleteLimit != nil && c.deleted > int(*b.DeleteLimit) {
+		msgs = append(msgs, fmt.Sprintf("%sdeleted %d exceeds deleteLimit %d", prefix, c.deleted, *b.DeleteLimit))
+	}
+	if b.ReplaceLimit != nil && c.replaced > int(*b.ReplaceLimit) {
+		msgs = append(msgs, fmt.Sprintf("%sreplaced %d exceeds replaceLimit %d", prefix, c.replaced, *b.ReplaceLimit))
+	}
+	return msgs
+}
@@ -0,0 +1,65 @@
+package step
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_budgetViolations(t *testing.T) {
+	changes := []terraform.ResourceChange{
+		{Address: "module.aks1.azurerm_kubernetes_cluster.this", Type: "azurerm_kubernetes_cluster", Action: terraform.ActionReplace},
+		{Address: "module.aks1.azurerm_subnet.this", Type: "azurerm_subnet", Action: terraform.ActionUpdate},
+		{Address: "azurerm_route_table.env", Type: "azurerm_route_table", Action: terraform.ActionUpdate},
+	}
+	limit := func(i int32) *int32 { return &i }
+
+	tests := []struct {
+		it     string
+		budget v1.InfraBudget
+		want   []string
+	}{
+		{
+			it: "should allow any change when no budget is specified",
+		},
+		{
+			it: "should count a replace as an add and a delete",
+			budget: v1.InfraBudget{
+				AddLimit:    limit(1),
+				UpdateLimit: limit(2),
+				DeleteLimit: limit(0),
+			},
+			want: []string{"deleted 1 exceeds deleteLimit 0"},
+		},
+		{
+			it: "should deny replaces when they exceed the replaceLimit",
+			budget: v1.InfraBudget{
+				ReplaceLimit: limit(0),
+			},
+			want: []string{"replaced 1 exceeds replaceLimit 0"},
+		},
+		{
+			it: "should deny changes that exceed a resource type limit",
+			budget: v1.InfraBudget{
+				UpdateLimit: limit(1),
+				ResourceTypes: map[string]v1.ResourceBudget{
+					"azurerm_subnet":             {UpdateLimit: limit(1)},
+					"azurerm_kubernetes_cluster": {DeleteLimit: limit(0), ReplaceLimit: limit(0)},
+					"azurerm_virtual_network":    {DeleteLimit: limit(0)},
+				},
+			},
+			want: []string{
+				"changed 2 exceeds updateLimit 1",
+				"azurerm_kubernetes_cluster deleted 1 exceeds deleteLimit 0",
+				"azurerm_kubernetes_cluster replaced 1 exceeds replaceLimit 0",
+			},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := budgetViolations(tst.budget, changes)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package step
+
+import (
+	"fmt"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"path"
+)
+
+// PlanRuleViolations returns a message for each resource change that is denied by a rule.
+func planRuleViolations(rules []v1.PlanRule, changes []terraform.ResourceChange) []string {
+	var r []string
+	for _, chg := range changes {
+		for _, rule := range rules {
+			if !planRuleMatch(rule, chg) {
+				continue
+			}
+			r = append(r, fmt.Sprintf("rule %q denies %s %s", rule.Name, actionName(chg.Action), chg.Address))
+			break
+		}
+	}
+	return r
+}
+
+// PlanRuleMatch returns true if rule matches the resource change.
+func planRuleMatch(rule v1.PlanRule, chg terraform.ResourceChange) bool {
+	if rule.Type != "" && rule.Type != chg.Type {
+		return false
+	}
+
+	if rule.Address != "" {
+		ok, err := path.Match(rule.Address, chg.Address)
+		if err != nil || !ok {
+			return false
+		}
+	}
+
+	for k, v := range rule.Tags {
+		tv, ok := chg.Tags[k]
+		if !ok || (v != "" && v != tv) {
+			return false
+		}
+	}
+
+	if len(rule.Actions) == 0 {
+		return true
+	}
+	for _, a := range rule.Actions {
+		switch a {
+		case v1.PlanActionCreate:
+			if chg.Action&terraform.ActionCreate != 0 {
+				return true
+			}
+		case v1.PlanActionUpdate:
+			if chg.Action&terraform.ActionUpdate != 0 {
+				return true
+			}
+		case v1.PlanActionDelete:
+			if chg.Action&terraform.ActionDelete != 0 {
+				return true
+			}
+		case v1.PlanActionReplace:
+			if chg.Action.IsReplace() {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// ActionName returns the PlanAction name of a terraform action.
+func actionName(a terraform.Action) v1.PlanAction {
+	switch {
+	case a.IsReplace():
+		return v1.PlanActionReplace
+	case a&terraform.ActionDelete != 0:
+		return v1.PlanActionDelete
+	case a&terraform.ActionCreate != 0:
+		return v1.PlanActionCreate
+	default:
+		return v1.PlanActionUpdate
+	}
+}
@@ -0,0 +1,98 @@
+package step
+
+import (
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_planRuleViolations(t *testing.T) {
+	changes := []terraform.ResourceChange{
+		{
+			Address: "module.aks1.azurerm_kubernetes_cluster.this",
+			Type:    "azurerm_kubernetes_cluster",
+			Action:  terraform.ActionDelete,
+			Tags:    map[string]string{"CIName": "KUBERNETES"},
+		},
+		{
+			Address: "azurerm_subnet.aks1",
+			Type:    "azurerm_subnet",
+			Action:  terraform.ActionReplace,
+		},
+		{
+			Address: "azurerm_route_table.env",
+			Type:    "azurerm_route_table",
+			Action:  terraform.ActionUpdate,
+			Tags:    map[string]string{"protected": "yes"},
+		},
+	}
+
+	tests := []struct {
+		it    string
+		rules []v1.PlanRule
+		want  []string
+	}{
+		{
+			it: "should not report violations when there are no rules",
+		},
+		{
+			it: "should deny deletes of a resource type",
+			rules: []v1.PlanRule{
+				{Name: "keep-clusters", Type: "azurerm_kubernetes_cluster", Actions: []v1.PlanAction{v1.PlanActionDelete}},
+			},
+			want: []string{`rule "keep-clusters" denies delete module.aks1.azurerm_kubernetes_cluster.this`},
+		},
+		{
+			it: "should deny deletes that are part of a replace",
+			rules: []v1.PlanRule{
+				{Name: "no-delete", Actions: []v1.PlanAction{v1.PlanActionDelete}},
+			},
+			want: []string{
+				`rule "no-delete" denies delete module.aks1.azurerm_kubernetes_cluster.this`,
+				`rule "no-delete" denies replace azurerm_subnet.aks1`,
+			},
+		},
+		{
+			it: "should deny replaces only",
+			rules: []v1.PlanRule{
+				{Name: "no-replace", Actions: []v1.PlanAction{v1.PlanActionReplace}},
+			},
+			want: []string{`rule "no-replace" denies replace azurerm_subnet.aks1`},
+		},
+		{
+			it: "should match addresses by glob pattern",
+			rules: []v1.PlanRule{
+				{Name: "aks-modules", Address: "module.aks*.*"},
+			},
+			want: []string{`rule "aks-modules" denies delete module.aks1.azurerm_kubernetes_cluster.this`},
+		},
+		{
+			it: "should match tags with any value",
+			rules: []v1.PlanRule{
+				{Name: "protected", Tags: map[string]string{"protected": ""}},
+			},
+			want: []string{`rule "protected" denies update azurerm_route_table.env`},
+		},
+		{
+			it: "should not match tags with a different value",
+			rules: []v1.PlanRule{
+				{Name: "ci", Tags: map[string]string{"CIName": "NETWORK"}},
+			},
+		},
+		{
+			it: "should report a change once when multiple rules match",
+			rules: []v1.PlanRule{
+				{Name: "first", Type: "azurerm_subnet"},
+				{Name: "second", Address: "azurerm_subnet.*"},
+			},
+			want: []string{`rule "first" denies replace azurerm_subnet.aks1`},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := planRuleViolations(tst.rules, changes)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
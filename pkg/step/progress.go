@@ -0,0 +1,56 @@
+package step
+
+import (
+	"fmt"
+	"github.com/mmlt/environment-operator/pkg/client/addon"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"time"
+)
+
+// ProgressInterval is the min time between progress updates of a running step.
+var progressInterval = 15 * time.Second
+
+// Progress throttles the progress updates of a running step.
+type progress struct {
+	// Start is the time the progress reporting started.
+	start time.Time
+	// Last is the time of the last progress update.
+	last time.Time
+}
+
+// NewProgress returns a progress that starts at now.
+func newProgress(now time.Time) *progress {
+	return &progress{start: now, last: now}
+}
+
+// Due returns true when a progress update is due at now.
+func (p *progress) due(now time.Time) bool {
+	if now.Sub(p.last) < progressInterval {
+		return false
+	}
+	p.last = now
+	return true
+}
+
+// Elapsed returns the time between the start of p and now.
+func (p *progress) elapsed(now time.Time) time.Duration {
+	return now.Sub(p.start).Round(time.Second)
+}
+
+// ApplyProgress returns a message like "creating 3/12 azurerm_kubernetes_cluster.x (4m10s elapsed)" for a
+// terraform apply or destroy.
+// Planned is the number of objects that are planned to change, 0 if unknown.
+func applyProgress(r terraform.TFApplyResult, planned int, elapsed time.Duration) string {
+	n := fmt.Sprint(r.Creating + r.Modifying + r.Destroying)
+	if planned > 0 {
+		n = fmt.Sprintf("%s/%d", n, planned)
+	}
+	return fmt.Sprintf("%s %s %s (%s elapsed)", r.Action, n, r.Object, elapsed)
+}
+
+// AddonProgress returns a message like "job.yaml: created deployment/x (added=1 changed=0 deleted=0, 10s elapsed)"
+// for a kubectl-tmplt apply of job.
+func addonProgress(job string, r addon.KTResult, elapsed time.Duration) string {
+	return fmt.Sprintf("%s: %s %s (added=%d changed=%d deleted=%d, %s elapsed)",
+		job, r.Action, r.Object, r.Added, r.Changed, r.Deleted, elapsed)
+}
@@ -0,0 +1,80 @@
+package step
+
+import (
+	"github.com/mmlt/environment-operator/pkg/client/addon"
+	"github.com/mmlt/environment-operator/pkg/client/terraform"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_progress_due(t *testing.T) {
+	t0 := time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)
+	p := newProgress(t0)
+
+	tests := []struct {
+		it   string
+		now  time.Time
+		want bool
+	}{
+		{
+			it:   "should not be due before the interval has passed",
+			now:  t0.Add(progressInterval - time.Second),
+			want: false,
+		},
+		{
+			it:   "should be due after the interval has passed",
+			now:  t0.Add(progressInterval),
+			want: true,
+		},
+		{
+			it:   "should not be due right after an update",
+			now:  t0.Add(progressInterval + time.Second),
+			want: false,
+		},
+		{
+			it:   "should be due again after another interval",
+			now:  t0.Add(2 * progressInterval),
+			want: true,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			assert.Equal(t, tst.want, p.due(tst.now))
+		})
+	}
+
+	assert.Equal(t, 4*time.Minute+10*time.Second, p.elapsed(t0.Add(4*time.Minute+10*time.Second+300*time.Millisecond)))
+}
+
+func Test_applyProgress(t *testing.T) {
+	tests := []struct {
+		it      string
+		result  terraform.TFApplyResult
+		planned int
+		want    string
+	}{
+		{
+			it:      "should show the number of objects started of the planned objects",
+			result:  terraform.TFApplyResult{Creating: 2, Modifying: 1, Object: "azurerm_kubernetes_cluster.x", Action: "creating"},
+			planned: 12,
+			want:    "creating 3/12 azurerm_kubernetes_cluster.x (4m10s elapsed)",
+		},
+		{
+			it:     "should show the number of objects started when the planned number is unknown",
+			result: terraform.TFApplyResult{Destroying: 5, Object: "azurerm_subnet.y", Action: "destruction"},
+			want:   "destruction 5 azurerm_subnet.y (4m10s elapsed)",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := applyProgress(tst.result, tst.planned, 4*time.Minute+10*time.Second)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
+
+func Test_addonProgress(t *testing.T) {
+	got := addonProgress("job.yaml", addon.KTResult{Added: 1, Changed: 2, Object: "deployment/x", Action: "created"}, 10*time.Second)
+	assert.Equal(t, "job.yaml: created deployment/x (added=1 changed=2 deleted=0, 10s elapsed)", got)
+}
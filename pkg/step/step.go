@@ -2,8 +2,13 @@ package step
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/go-logr/logr"
 	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/logsink"
+	"github.com/mmlt/environment-operator/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +30,7 @@ type Meta interface {
 	GetLastUpdate() time.Time
 	GetLastError() error
 	SetOnUpdate(fn MetaUpdateFn)
+	SetLogSink(sink logsink.Sink, run logsink.Run)
 }
 
 // Metaa is the data that all steps have in common.
@@ -44,12 +50,23 @@ type Metaa struct {
 	lastError error
 	// OnUpdate (optional) is a function that is called after updating.
 	onUpdate MetaUpdateFn
+	// LogSink (optional) stores the output of the step as the logs of logRun.
+	logSink logsink.Sink
+	logRun  logsink.Run
+	// Span (optional) traces the current phase of Execute.
+	span trace.Span
 	// Mu is a mutex.
 	mu sync.Mutex
 }
 
 type MetaUpdateFn func(Meta)
 
+// Counter is implemented by steps that add, change or delete objects.
+type Counter interface {
+	// GetCounts returns the number of objects added, changed and deleted.
+	GetCounts() (added, changed, deleted int)
+}
+
 var _ Meta = &Metaa{}
 
 func (m *Metaa) GetID() ID {
@@ -101,6 +118,47 @@ func (m *Metaa) SetOnUpdate(fn MetaUpdateFn) {
 	m.onUpdate = fn
 }
 
+func (m *Metaa) SetLogSink(sink logsink.Sink, run logsink.Run) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.logSink = sink
+	m.logRun = run
+}
+
+// WriteLog writes text to dir/log/name and stores it in the log sink (if any).
+// Errors are logged.
+func (m *Metaa) writeLog(ctx context.Context, text, dir, name string, log logr.Logger) {
+	writeText(text, dir, name, log)
+
+	m.mu.Lock()
+	sink, run := m.logSink, m.logRun
+	m.mu.Unlock()
+	if sink == nil {
+		return
+	}
+
+	err := sink.Put(ctx, run, name, []byte(text))
+	if err != nil {
+		log.Info("writeLog", "error", err)
+	}
+}
+
+// Phase ends the span of the previous phase (if any) and returns ctx with a span for phase name.
+// The span of the last phase ends when the step reaches a final state.
+func (m *Metaa) phase(ctx context.Context, name string) context.Context {
+	ctx, span := tracing.Start(ctx, name)
+
+	m.mu.Lock()
+	prev := m.span
+	m.span = span
+	m.mu.Unlock()
+
+	tracing.End(prev, nil)
+
+	return ctx
+}
+
 // Update updates Step meta and notifies on-update listeners.
 func (m *Metaa) update(state v1.StepState, msg string) {
 	m.mu.Lock()
@@ -112,8 +170,18 @@ func (m *Metaa) update(state v1.StepState, msg string) {
 	m.Msg = msg
 	m.LastUpdate = time.Now()
 	f := m.onUpdate
+	var span trace.Span
+	if IsStateFinal(state) {
+		span, m.span = m.span, nil
+	}
 	m.mu.Unlock()
 
+	if state == v1.StateError {
+		tracing.End(span, errors.New(msg))
+	} else {
+		tracing.End(span, nil)
+	}
+
 	if f != nil {
 		f(m)
 	}
@@ -139,6 +207,7 @@ type ID struct {
 	// Namespace Name identifies the plan to which the step belongs.
 	Namespace, Name string
 	// ClusterName (optional) is the name of the target cluster.
+	// For RolloutGate steps it's the batch number.
 	ClusterName string
 }
 
@@ -156,6 +225,7 @@ const (
 	TypeAKSPool           Type = "AKSPool"
 	TypeAKSAddonPreflight Type = "AKSAddonPreflight"
 	TypeAddons            Type = "Addons"
+	TypeRolloutGate       Type = "RolloutGate"
 )
 
 // InfraTypes is an enumeration of types that apply to all clusters.
@@ -165,7 +235,7 @@ var InfraTypes = []Type{TypeInfra, TypeDestroy}
 var ClusterTypes = []Type{TypeAKSPool, TypeAKSAddonPreflight, TypeAddons}
 
 // Types is an enumeration of all types.
-var Types = append(InfraTypes, ClusterTypes...)
+var Types = append(append(InfraTypes, ClusterTypes...), TypeRolloutGate)
 
 // IsStateFinal returns true is state is a final state.
 // A step in final state has stopped executing.
@@ -10,6 +10,7 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // AddonStep performs a kubectl-tmplt apply.
@@ -38,6 +39,11 @@ type AddonStep struct {
 	Added, Changed, Deleted int
 }
 
+// GetCounts returns the number of objects added, changed and deleted.
+func (st *AddonStep) GetCounts() (int, int, int) {
+	return st.Added, st.Changed, st.Deleted
+}
+
 // Execute performs a kubectl-tmplt apply.
 func (st *AddonStep) Execute(ctx context.Context, env []string) {
 	log := logr.FromContext(ctx).WithName("AddonStep")
@@ -56,25 +62,22 @@ func (st *AddonStep) Execute(ctx context.Context, env []string) {
 	var totals []addon.KTResult
 	for _, job := range st.JobPaths {
 		st.update(v1.StateRunning, job)
+		pctx := st.phase(ctx, job)
 
 		// Start kubectl-tmplt
-		cmd, ch, err := st.Addon.Start(ctx, env, st.SourcePath, job, values, st.KCPath, st.MasterVaultPath)
+		ch, err := st.Addon.Start(pctx, env, st.SourcePath, job, values, st.KCPath, st.MasterVaultPath)
 		if err != nil {
 			st.error2(err, "start kubectl-tmplt")
 			return
 		}
 
-		// notify sink while waiting for command completion.
+		// report progress while waiting for command completion.
 		var last *addon.KTResult
+		p := newProgress(time.Now())
 		for r := range ch {
 			last = &r
-		}
-
-		if cmd != nil {
-			// real cmd (fakes are nil).
-			err := cmd.Wait()
-			if err != nil {
-				log.Error(err, "wait kubectl-tmplt")
+			if now := time.Now(); r.Object != "" && p.due(now) {
+				st.update(v1.StateRunning, addonProgress(job, r, p.elapsed(now)))
 			}
 		}
 
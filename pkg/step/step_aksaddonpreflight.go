@@ -36,10 +36,10 @@ func (st *AKSAddonPreflightStep) Execute(ctx context.Context, _ []string) {
 	st.update(v1.StateRunning, "check api-server connection")
 
 	// Remove possible leftover probe pod.
-	s, _ := st.Kubectl.PodState(st.KCPath, namespace, name)
+	s, _ := st.Kubectl.PodState(ctx, st.KCPath, namespace, name)
 	if s != "" {
 		// Pod already present, delete it
-		err = st.Kubectl.PodDelete(st.KCPath, namespace, name)
+		err = st.Kubectl.PodDelete(ctx, st.KCPath, namespace, name)
 		if err != nil {
 			st.error2(err, "delete pod")
 			return
@@ -48,7 +48,7 @@ func (st *AKSAddonPreflightStep) Execute(ctx context.Context, _ []string) {
 
 	// Run probe.
 	// TODO parameterize image (consider using envop config for this)
-	err = st.Kubectl.PodRun(st.KCPath, namespace, name, "docker.io/curlimages/curl:7.80.0",
+	err = st.Kubectl.PodRun(ctx, st.KCPath, namespace, name, "docker.io/curlimages/curl:7.80.0",
 		"until curl -ksS --max-time 2 https://kubernetes.default | grep Status ; do date -Iseconds; sleep 5 ; done")
 	if err != nil {
 		st.error2(err, "run pod")
@@ -58,7 +58,7 @@ func (st *AKSAddonPreflightStep) Execute(ctx context.Context, _ []string) {
 	s = ""
 	end := time.Now().Add(time.Minute)
 	for exp := backoff.NewExponential(10 * time.Second); !time.Now().After(end); exp.Sleep() {
-		s, err = st.Kubectl.PodState(st.KCPath, namespace, name)
+		s, err = st.Kubectl.PodState(ctx, st.KCPath, namespace, name)
 		// err is included in Msg below
 		if s == "PodCompleted" {
 			break
@@ -81,7 +81,7 @@ func (st *AKSAddonPreflightStep) Execute(ctx context.Context, _ []string) {
 	// On 20200821 when AKS provisioning is completed (according to terraform) it still takes 5 minutes or more for
 	// the default StorageClass to appear. During that time window PVC's that don't set 'storageClass:' will fail.
 	st.update(v1.StateRunning, "waiting for default StorageClass")
-	err = st.waitForDefaultStorageClass()
+	err = st.waitForDefaultStorageClass(ctx)
 	if err != nil {
 		st.error2(err, "waiting for default StorageClass")
 		return
@@ -91,12 +91,12 @@ func (st *AKSAddonPreflightStep) Execute(ctx context.Context, _ []string) {
 }
 
 // WaitForDefaultStorageClass waits until the target cluster contains a StorageClass with 'default' annotation.
-func (st *AKSAddonPreflightStep) waitForDefaultStorageClass() error {
+func (st *AKSAddonPreflightStep) waitForDefaultStorageClass(ctx context.Context) error {
 	var errTally int
 
 	end := time.Now().Add(10 * time.Minute)
 	for exp := backoff.NewExponential(30 * time.Second); !time.Now().After(end); exp.Sleep() {
-		scs, err := st.Kubectl.StorageClasses(st.KCPath)
+		scs, err := st.Kubectl.StorageClasses(ctx, st.KCPath)
 		if err != nil {
 			errTally++
 			if errTally > 3 {
@@ -38,7 +38,7 @@ func (st *AKSPoolStep) Execute(ctx context.Context, _ []string) {
 	st.update(v1.StateRunning, "upgrade k8s version")
 
 	// get the current state of the node pools.
-	pools, err := st.Azure.AKSNodepoolList(st.ResourceGroup, st.Cluster)
+	pools, err := st.Azure.AKSNodepoolList(ctx, st.ResourceGroup, st.Cluster)
 	if err != nil {
 		st.error2(err, "az aks nodepool list")
 		return
@@ -66,7 +66,7 @@ func (st *AKSPoolStep) Execute(ctx context.Context, _ []string) {
 
 		// Disable autoscaling during upgrade.
 		if pool.EnableAutoScaling {
-			err = st.Azure.Autoscaler(false, pool.ResourceGroup, st.Cluster, pool.Name, pool.MinCount, pool.MaxCount)
+			err = st.Azure.Autoscaler(ctx, false, pool.ResourceGroup, st.Cluster, pool.Name, pool.MinCount, pool.MaxCount)
 			log.Error(err, "disable autoscaler on cluster %s pool %s", st.Cluster, pool.Name)
 		}
 
@@ -79,7 +79,7 @@ func (st *AKSPoolStep) Execute(ctx context.Context, _ []string) {
 		_ = p // we might want to show the pool after upgrade
 
 		if pool.EnableAutoScaling {
-			err = st.Azure.Autoscaler(true, pool.ResourceGroup, st.Cluster, pool.Name, pool.MinCount, pool.MaxCount)
+			err = st.Azure.Autoscaler(ctx, true, pool.ResourceGroup, st.Cluster, pool.Name, pool.MinCount, pool.MaxCount)
 			if err != nil {
 				st.error2(err, "enable autoscaler")
 				return
@@ -92,7 +92,7 @@ func (st *AKSPoolStep) Execute(ctx context.Context, _ []string) {
 }
 
 // Upgrade
-func (st *AKSPoolStep) upgrade(_ context.Context, pool string, log logr.Logger) (*azure.AKSNodepool, error) {
+func (st *AKSPoolStep) upgrade(ctx context.Context, pool string, log logr.Logger) (*azure.AKSNodepool, error) {
 	stop := make(chan bool)
 
 	// start poller that provides status updates during the upgrade.
@@ -105,7 +105,7 @@ func (st *AKSPoolStep) upgrade(_ context.Context, pool string, log logr.Logger)
 				stop <- true
 				return
 			case <-ticker.C:
-				p, err := st.Azure.AKSNodepool(st.ResourceGroup, st.Cluster, pool)
+				p, err := st.Azure.AKSNodepool(ctx, st.ResourceGroup, st.Cluster, pool)
 				if err != nil {
 					log.Error(err, "poll nodepool")
 					continue
@@ -116,7 +116,7 @@ func (st *AKSPoolStep) upgrade(_ context.Context, pool string, log logr.Logger)
 	}()
 
 	// start upgrade (slow)
-	p, err := st.Azure.AKSNodepoolUpgrade(st.ResourceGroup, st.Cluster, pool, st.Version)
+	p, err := st.Azure.AKSNodepoolUpgrade(ctx, st.ResourceGroup, st.Cluster, pool, st.Version)
 
 	// stop poller
 	stop <- true
@@ -11,6 +11,7 @@ import (
 	"github.com/mmlt/environment-operator/pkg/tmplt"
 	"github.com/mmlt/environment-operator/pkg/util"
 	"strings"
+	"time"
 )
 
 // DestroyStep performs a terraform destroy.
@@ -41,6 +42,11 @@ type DestroyStep struct {
 // Any other number will deny Destroy.
 const deleteLimitForDestroy = 99
 
+// GetCounts returns the number of objects added, changed and deleted.
+func (st *DestroyStep) GetCounts() (int, int, int) {
+	return st.Added, st.Changed, st.Deleted
+}
+
 // Execute terraform destroy.
 func (st *DestroyStep) Execute(ctx context.Context, env []string) {
 	// Check budget.
@@ -57,6 +63,7 @@ func (st *DestroyStep) Execute(ctx context.Context, env []string) {
 
 	// Init
 	st.update(v1.StateRunning, "terraform init")
+	pctx := st.phase(ctx, "init")
 
 	err := tmplt.ExpandAll(st.SourcePath, ".tmplt", st.Values)
 	if err != nil {
@@ -69,19 +76,19 @@ func (st *DestroyStep) Execute(ctx context.Context, env []string) {
 		st.error2(err, "login")
 		return
 	}
-	xenv := terraformEnviron(sp, st.Values.Infra.State.Access)
+	xenv := TerraformEnviron(sp, st.Values.Infra.State.Access)
 	writeEnv(xenv, st.SourcePath, "infra.env", log) // useful when invoking terraform manually.
 	env = util.KVSliceMergeMap(env, xenv)
 
-	tfr := st.Terraform.Init(ctx, env, st.SourcePath)
-	writeText(tfr.Text, st.SourcePath, "init.txt", log)
+	tfr := st.Terraform.Init(pctx, env, st.SourcePath)
+	st.writeLog(ctx, tfr.Text, st.SourcePath, "init.txt", log)
 	if len(tfr.Errors) > 0 {
 		st.error2(nil, "terraform init "+tfr.Errors[0] /*first error only*/)
 		return
 	}
 
 	// Disable autoscaler
-	err = st.Azure.AllAutoscalers(false, st.Values.Clusters, st.Values.Infra.AZ.ResourceGroup, log)
+	err = st.Azure.AllAutoscalers(ctx, false, st.Values.Clusters, st.Values.Infra.AZ.ResourceGroup, log)
 	if err != nil {
 		st.error2(err, "disable autoscalers")
 		return
@@ -89,29 +96,26 @@ func (st *DestroyStep) Execute(ctx context.Context, env []string) {
 
 	// Destroy
 	st.update(v1.StateRunning, "terraform destroy")
+	pctx = st.phase(ctx, "destroy")
 
-	cmd, ch, err := st.Terraform.StartDestroy(ctx, env, st.SourcePath)
+	ch, err := st.Terraform.StartDestroy(pctx, env, st.SourcePath)
 	if err != nil {
 		st.error2(err, "start terraform destroy")
 		return
 	}
 
-	// keep last line of stdout/err
+	// report progress while waiting for command completion.
 	var last *terraform.TFApplyResult
+	p := newProgress(time.Now())
 	for r := range ch {
 		last = &r
-	}
-
-	if cmd != nil {
-		// real cmd (fakes are nil).
-		err := cmd.Wait()
-		if err != nil {
-			log.Error(err, "wait terraform destroy")
+		if now := time.Now(); r.Object != "" && p.due(now) {
+			st.update(v1.StateRunning, "terraform destroy "+applyProgress(r, 0, p.elapsed(now)))
 		}
 	}
 
 	if last != nil {
-		writeText(last.Text, st.SourcePath, "destroy.txt", log)
+		st.writeLog(ctx, last.Text, st.SourcePath, "destroy.txt", log)
 	}
 
 	// Return results.
@@ -21,8 +21,12 @@ import (
 	"sigs.k8s.io/yaml"
 	"sort"
 	"strings"
+	"time"
 )
 
+// BudgetExceededMsg is the start of the message of an InfraStep that fails because the plan exceeds the budget.
+const BudgetExceededMsg = "plan limits exceeded"
+
 // InfraStep performs a terraform init, plan, apply and creates cluster credentials.
 type InfraStep struct {
 	Metaa
@@ -59,6 +63,11 @@ type InfraValues struct {
 	Clusters []v1.ClusterSpec
 }
 
+// GetCounts returns the number of objects added, changed and deleted.
+func (st *InfraStep) GetCounts() (int, int, int) {
+	return st.Added, st.Changed, st.Deleted
+}
+
 // Execute performs the terraform commands.
 func (st *InfraStep) Execute(ctx context.Context, env []string) {
 	log := logr.FromContext(ctx).WithName("InfraStep")
@@ -66,6 +75,7 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 	log.Info("start")
 
 	st.update(v1.StateRunning, "terraform init")
+	pctx := st.phase(ctx, "init")
 
 	writeJSON(st.Values, st.SourcePath, "values.json", log)
 
@@ -80,12 +90,12 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 		st.error2(err, "login")
 		return
 	}
-	xenv := terraformEnviron(sp, st.Values.Infra.State.Access)
+	xenv := TerraformEnviron(sp, st.Values.Infra.State.Access)
 	writeEnv(xenv, st.SourcePath, "infra.env", log) // useful when invoking terraform manually.
 	env = util.KVSliceMergeMap(env, xenv)
 
-	tfr := st.Terraform.Init(ctx, env, st.SourcePath)
-	writeText(tfr.Text, st.SourcePath, "init.txt", log)
+	tfr := st.Terraform.Init(pctx, env, st.SourcePath)
+	st.writeLog(ctx, tfr.Text, st.SourcePath, "init.txt", log)
 	if len(tfr.Errors) > 0 {
 		st.error2(nil, "terraform init "+tfr.Errors[0] /*first error only*/)
 		return
@@ -93,9 +103,10 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 
 	// Plan
 	st.update(v1.StateRunning, "terraform plan")
+	pctx = st.phase(ctx, "plan")
 
-	tfr = st.Terraform.Plan(ctx, env, st.SourcePath)
-	writeText(tfr.Text, st.SourcePath, "plan.txt", log)
+	tfr = st.Terraform.Plan(pctx, env, st.SourcePath)
+	st.writeLog(ctx, tfr.Text, st.SourcePath, "plan.txt", log)
 	if len(tfr.Errors) > 0 {
 		st.error2(nil, "terraform plan "+tfr.Errors[0] /*first error only*/)
 		return
@@ -109,31 +120,34 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 		return
 	}
 
-	// Check budget.
-	var msgs []string
-	b := st.Values.Infra.Budget
-	if b.AddLimit != nil && tfr.PlanAdded > int(*b.AddLimit) {
-		msgs = append(msgs, fmt.Sprintf("added %d exceeds addLimit %d", tfr.PlanAdded, *b.AddLimit))
-	}
-	if b.UpdateLimit != nil && tfr.PlanChanged > int(*b.UpdateLimit) {
-		msgs = append(msgs, fmt.Sprintf("changed %d exceeds updateLimit %d", tfr.PlanChanged, *b.UpdateLimit))
+	// Get plan to check budget and rules and to determine if work-a-rounds for terraform/azure_rm issues are needed.
+	plan, err := st.Terraform.GetPlan(pctx, env, st.SourcePath)
+	if err != nil {
+		st.error2(err, "terraform get plan")
+		return
 	}
-	if b.DeleteLimit != nil && tfr.PlanDeleted > int(*b.DeleteLimit) {
-		msgs = append(msgs, fmt.Sprintf("deleted %d exceeds deleteLimit %d", tfr.PlanDeleted, *b.DeleteLimit))
+
+	changes, err := terraform.ResourceChangesFromPlan(plan)
+	if err != nil {
+		st.error2(err, "resource changes from terraform plan")
+		return
 	}
+
+	// Check budget.
+	msgs := budgetViolations(st.Values.Infra.Budget, changes)
 	if len(msgs) > 0 {
-		st.error2(nil, "plan limits exceeded: "+strings.Join(msgs, ", "))
+		st.error2(nil, BudgetExceededMsg+": "+strings.Join(msgs, ", "))
 		return
 	}
 
-	// Get plan to determine if work-a-rounds for terraform/azure_rm issues are needed.
-	plan, err := st.Terraform.GetPlan(ctx, env, st.SourcePath)
-	if err != nil {
-		st.error2(err, "terraform get plan")
+	// Check plan rules.
+	msgs = planRuleViolations(st.Values.Infra.PlanRules, changes)
+	if len(msgs) > 0 {
+		st.error2(nil, "plan rules violated: "+strings.Join(msgs, ", "))
 		return
 	}
 
-	err = st.wipeDeletedClusters(plan)
+	err = st.wipeDeletedClusters(ctx, plan)
 	if err != nil {
 		st.error2(err, "wipe deleted cluster(s)")
 		return
@@ -149,7 +163,7 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 		if p.Action&(terraform.ActionUpdate|terraform.ActionDelete) == 0 {
 			continue
 		}
-		err = st.Azure.Autoscaler(false, p.ResourceGroup, p.Cluster, p.Pool, p.MinCount, p.MaxCount)
+		err = st.Azure.Autoscaler(ctx, false, p.ResourceGroup, p.Cluster, p.Pool, p.MinCount, p.MaxCount)
 		if err != nil {
 			st.error2(err, "disable autoscaler")
 			return
@@ -159,17 +173,22 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 	// Apply
 	st.update(v1.StateRunning, fmt.Sprintf("terraform apply adds=%d changes=%d deletes=%d",
 		tfr.PlanAdded, tfr.PlanChanged, tfr.PlanDeleted))
+	pctx = st.phase(ctx, "apply")
 
-	cmd, ch, err := st.Terraform.StartApply(ctx, env, st.SourcePath)
+	ch, err := st.Terraform.StartApply(pctx, env, st.SourcePath)
 	if err != nil {
 		st.error2(err, "start terraform apply")
 		return
 	}
 
-	// notify sink while waiting for command completion.
+	// report progress while waiting for command completion.
 	var last *terraform.TFApplyResult
+	p := newProgress(time.Now())
 	for r := range ch {
 		last = &r
+		if now := time.Now(); r.Object != "" && p.due(now) {
+			st.update(v1.StateRunning, "terraform apply "+applyProgress(r, tfr.PlanAdded+tfr.PlanChanged+tfr.PlanDeleted, p.elapsed(now)))
+		}
 	}
 
 	// Re-enable autoscaling after change.
@@ -177,23 +196,15 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 		if p.Action&terraform.ActionUpdate == 0 {
 			continue
 		}
-		err = st.Azure.Autoscaler(true, p.ResourceGroup, p.Cluster, p.Pool, p.MinCount, p.MaxCount)
+		err = st.Azure.Autoscaler(ctx, true, p.ResourceGroup, p.Cluster, p.Pool, p.MinCount, p.MaxCount)
 		if err != nil {
 			st.error2(err, "enable autoscaler")
 			return
 		}
 	}
 
-	if cmd != nil {
-		// real cmd (fakes are nil).
-		err := cmd.Wait()
-		if err != nil {
-			log.Error(err, "wait terraform apply")
-		}
-	}
-
 	if last != nil {
-		writeText(last.Text, st.SourcePath, "apply.txt", log)
+		st.writeLog(ctx, last.Text, st.SourcePath, "apply.txt", log)
 	}
 
 	if last == nil {
@@ -208,7 +219,7 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 
 	// Update cluster access data.
 
-	to, err := st.Terraform.Output(ctx, env, st.SourcePath)
+	to, err := st.Terraform.Output(pctx, env, st.SourcePath)
 	if err != nil {
 		st.error2(err, "terraform output")
 		return
@@ -244,7 +255,7 @@ func (st *InfraStep) Execute(ctx context.Context, env []string) {
 
 // WipeDeletedClusters prevents node drain errors on cluster delete.
 // https://github.com/hashicorp/terraform-provider-azurerm/issues/10411
-func (st *InfraStep) wipeDeletedClusters(plan *gabs.Container) error {
+func (st *InfraStep) wipeDeletedClusters(ctx context.Context, plan *gabs.Container) error {
 	cs, err := terraform.ClustersFromPlan(plan)
 	if err != nil {
 		return err
@@ -266,7 +277,7 @@ func (st *InfraStep) wipeDeletedClusters(plan *gabs.Container) error {
 			return err
 		}
 
-		err = st.Kubectl.WipeCluster(file.Name())
+		err = st.Kubectl.WipeCluster(ctx, file.Name())
 		if err != nil {
 			return err
 		}
@@ -423,11 +434,29 @@ func kubeconfig(m map[string]interface{}, clusterName string) ([]byte, error) {
 }
 
 // TerraformEnviron returns terraform specific environment variables.
-func terraformEnviron(sp *cloud.ServicePrincipal, access string) map[string]string {
+// Depending on sp the azurerm provider authenticates with a client secret, a managed identity (MSI) or
+// a federated token (OIDC).
+func TerraformEnviron(sp *cloud.ServicePrincipal, access string) map[string]string {
 	r := make(map[string]string)
-	r["ARM_CLIENT_ID"] = sp.ClientID
-	r["ARM_CLIENT_SECRET"] = sp.ClientSecret
-	r["ARM_TENANT_ID"] = sp.Tenant
+	switch {
+	case sp.UseMSI:
+		r["ARM_USE_MSI"] = "true"
+		if sp.ClientID != "" {
+			r["ARM_CLIENT_ID"] = sp.ClientID
+		}
+		if sp.Tenant != "" {
+			r["ARM_TENANT_ID"] = sp.Tenant
+		}
+	case sp.FederatedTokenFile != "":
+		r["ARM_USE_OIDC"] = "true"
+		r["ARM_OIDC_TOKEN_FILE_PATH"] = sp.FederatedTokenFile
+		r["ARM_CLIENT_ID"] = sp.ClientID
+		r["ARM_TENANT_ID"] = sp.Tenant
+	default:
+		r["ARM_CLIENT_ID"] = sp.ClientID
+		r["ARM_CLIENT_SECRET"] = sp.ClientSecret
+		r["ARM_TENANT_ID"] = sp.Tenant
+	}
 	r["ARM_ACCESS_KEY"] = access
 	return r
 }
@@ -2,6 +2,7 @@ package step
 
 import (
 	"encoding/json"
+	"github.com/mmlt/environment-operator/pkg/cloud"
 	"github.com/mmlt/environment-operator/pkg/cluster"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -211,3 +212,40 @@ func testUnmarshall(t *testing.T, in string) map[string]interface{} {
 	}
 	return out
 }
+
+func TestTerraformEnviron(t *testing.T) {
+	tests := []struct {
+		it   string
+		in   cloud.ServicePrincipal
+		want map[string]string
+	}{
+		{
+			it: "should_set_client_secret_of_service_principal",
+			in: cloud.ServicePrincipal{ClientID: "id", ClientSecret: "s3cret", Tenant: "t"},
+			want: map[string]string{"ARM_CLIENT_ID": "id", "ARM_CLIENT_SECRET": "s3cret", "ARM_TENANT_ID": "t",
+				"ARM_ACCESS_KEY": "access"},
+		},
+		{
+			it:   "should_use_system_assigned_managed_identity",
+			in:   cloud.ServicePrincipal{UseMSI: true},
+			want: map[string]string{"ARM_USE_MSI": "true", "ARM_ACCESS_KEY": "access"},
+		},
+		{
+			it:   "should_use_user_assigned_managed_identity",
+			in:   cloud.ServicePrincipal{ClientID: "id", UseMSI: true},
+			want: map[string]string{"ARM_USE_MSI": "true", "ARM_CLIENT_ID": "id", "ARM_ACCESS_KEY": "access"},
+		},
+		{
+			it: "should_use_workload_identity",
+			in: cloud.ServicePrincipal{ClientID: "id", Tenant: "t", FederatedTokenFile: "/var/run/token"},
+			want: map[string]string{"ARM_USE_OIDC": "true", "ARM_OIDC_TOKEN_FILE_PATH": "/var/run/token",
+				"ARM_CLIENT_ID": "id", "ARM_TENANT_ID": "t", "ARM_ACCESS_KEY": "access"},
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := TerraformEnviron(&tst.in, "access")
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package step
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/kubectl"
+	corev1 "k8s.io/api/core/v1"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RolloutGateStep waits for a batch of clusters to soak and checks their health before the next batch is rolled out.
+type RolloutGateStep struct {
+	Metaa
+
+	/* Parameters */
+
+	// Steps are the short names of the steps in the batch.
+	Steps []string
+	// Since is the time the last step of the batch has completed.
+	// (it's set by the controller before Execute)
+	Since time.Time
+	// SoakTime is the time to wait after Since before the health is checked.
+	SoakTime time.Duration
+	// Previous is the status of this step before Execute.
+	// (it's set by the controller before Execute)
+	Previous v1.StepStatus
+	// KCPaths are the paths of the kube config files of the clusters in the batch, indexed by cluster name.
+	KCPaths map[string]string
+	// Namespaces are the namespaces in which all Pods must be healthy, empty means all namespaces.
+	Namespaces []string
+
+	// Kubectl is the kubectl implementation to use.
+	Kubectl kubectl.Kubectrler
+
+	/* Results */
+
+	// Remaining is the time to wait before Execute is called again when it returns without reaching a final state.
+	Remaining time.Duration
+}
+
+// HealthTimeout is the maximum time a gate waits for the batch clusters to become healthy.
+const healthTimeout = 5 * time.Minute
+
+// Execute checks if the soak time has passed and the clusters in the batch are healthy.
+// Execute doesn't wait, when the gate isn't passed yet it returns with Remaining set to the time to wait before
+// calling Execute again.
+func (st *RolloutGateStep) Execute(ctx context.Context, _ []string) {
+	log := logr.FromContext(ctx).WithName("RolloutGateStep")
+	log.Info("start")
+
+	now := time.Now()
+	until := st.Since.Add(st.SoakTime)
+	if d := until.Sub(now); d > 0 {
+		st.Remaining = d
+		st.running(fmt.Sprintf("soaking until %s", until.UTC().Format(time.RFC3339)))
+		return
+	}
+
+	clusters := make([]string, 0, len(st.KCPaths))
+	for n := range st.KCPaths {
+		clusters = append(clusters, n)
+	}
+	sort.Strings(clusters)
+
+	err := st.checkHealth(ctx, clusters)
+	if err == nil {
+		st.update(v1.StateReady, "healthy "+strings.Join(clusters, ","))
+		return
+	}
+	log.V(2).Info("unhealthy", "error", err)
+
+	msg := "waiting for healthy " + strings.Join(clusters, ",")
+	start := now
+	if st.unchanged(msg) {
+		start = st.Previous.LastTransitionTime.Time
+	}
+	if d := now.Sub(start); d < healthTimeout {
+		st.Remaining = healthRetry(d)
+		st.running(msg)
+		return
+	}
+
+	st.error2(err, "health gate")
+}
+
+// Running sets the state to Running with msg.
+// When the step already is Running with msg the state is left as-is to prevent a status update (and the reconcile
+// that's triggered by it) each time the gate is checked.
+func (st *RolloutGateStep) running(msg string) {
+	if st.unchanged(msg) {
+		return
+	}
+	st.update(v1.StateRunning, msg)
+}
+
+// Unchanged returns true when the previous state is Running with msg.
+func (st *RolloutGateStep) unchanged(msg string) bool {
+	return st.Previous.State == v1.StateRunning && st.Previous.Message == msg
+}
+
+// HealthRetry returns the time to wait before checking the health again of clusters that are unhealthy for d.
+// The wait increases with d from 15s to 1m.
+func healthRetry(d time.Duration) time.Duration {
+	if d < 15*time.Second {
+		return 15 * time.Second
+	}
+	if d > time.Minute {
+		return time.Minute
+	}
+	return d
+}
+
+// CheckHealth returns an error if one of the clusters has a Node or Pod that isn't healthy.
+func (st *RolloutGateStep) checkHealth(ctx context.Context, clusters []string) error {
+	namespaces := st.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{""}
+	}
+
+	for _, cl := range clusters {
+		p := st.KCPaths[cl]
+
+		nodes, err := st.Kubectl.Nodes(ctx, p)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", cl, err)
+		}
+		var pods []corev1.Pod
+		for _, ns := range namespaces {
+			pp, err := st.Kubectl.Pods(ctx, p, ns)
+			if err != nil {
+				return fmt.Errorf("cluster %s: %w", cl, err)
+			}
+			pods = append(pods, pp...)
+		}
+
+		err = clusterHealth(nodes, pods)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %w", cl, err)
+		}
+	}
+
+	return nil
+}
+
+// ClusterHealth returns an error if a Node isn't Ready or a Pod isn't Succeeded or Running and Ready.
+func clusterHealth(nodes []corev1.Node, pods []corev1.Pod) error {
+	var msgs []string
+
+	if len(nodes) == 0 {
+		msgs = append(msgs, "no nodes")
+	}
+	for _, n := range nodes {
+		if !hasCondition(n.Status.Conditions, corev1.NodeReady) {
+			msgs = append(msgs, "node "+n.Name+" not ready")
+		}
+	}
+
+	for _, p := range pods {
+		switch p.Status.Phase {
+		case corev1.PodSucceeded:
+			continue
+		case corev1.PodRunning:
+			ready := false
+			for _, c := range p.Status.Conditions {
+				if c.Type == corev1.PodReady && c.Status == corev1.ConditionTrue {
+					ready = true
+				}
+			}
+			if ready {
+				continue
+			}
+			msgs = append(msgs, "pod "+p.Namespace+"/"+p.Name+" not ready")
+		default:
+			msgs = append(msgs, fmt.Sprintf("pod %s/%s %s", p.Namespace, p.Name, p.Status.Phase))
+		}
+	}
+
+	if len(msgs) > 0 {
+		return fmt.Errorf("%s", strings.Join(msgs, ", "))
+	}
+	return nil
+}
+
+// HasCondition returns true if a Node condition of type t is True.
+func hasCondition(conditions []corev1.NodeCondition, t corev1.NodeConditionType) bool {
+	for _, c := range conditions {
+		if c.Type == t {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
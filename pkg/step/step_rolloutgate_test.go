@@ -0,0 +1,164 @@
+package step
+
+import (
+	"context"
+	"github.com/go-logr/logr"
+	v1 "github.com/mmlt/environment-operator/api/v1"
+	"github.com/mmlt/environment-operator/pkg/client/kubectl"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+// UnhealthyKubectl is a fake Kubectrler for clusters without Nodes.
+type unhealthyKubectl struct {
+	kubectl.KubectlFake
+}
+
+func (k *unhealthyKubectl) Nodes(ctx context.Context, kubeconfigPath string) ([]corev1.Node, error) {
+	return nil, nil
+}
+
+func TestRolloutGateStep_Execute(t *testing.T) {
+	now := time.Now()
+	running := func(msg string, ago time.Duration) v1.StepStatus {
+		return v1.StepStatus{State: v1.StateRunning, Message: msg, LastTransitionTime: metav1.Time{Time: now.Add(-ago)}}
+	}
+	soaking := "soaking until " + now.Add(time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		it            string
+		since         time.Time
+		previous      v1.StepStatus
+		kubectl       kubectl.Kubectrler
+		wantState     v1.StepState
+		wantUpdates   int
+		wantRemaining time.Duration
+	}{
+		{
+			it:            "should start soaking",
+			since:         now,
+			kubectl:       &kubectl.KubectlFake{},
+			wantState:     v1.StateRunning,
+			wantUpdates:   1,
+			wantRemaining: time.Hour,
+		},
+		{
+			it:            "should not update the status while soaking",
+			since:         now,
+			previous:      running(soaking, time.Minute),
+			kubectl:       &kubectl.KubectlFake{},
+			wantUpdates:   0,
+			wantRemaining: time.Hour,
+		},
+		{
+			it:          "should be Ready when soaked and healthy",
+			since:       now.Add(-2 * time.Hour),
+			previous:    running(soaking, time.Hour),
+			kubectl:     &kubectl.KubectlFake{},
+			wantState:   v1.StateReady,
+			wantUpdates: 1,
+		},
+		{
+			it:            "should retry when unhealthy",
+			since:         now.Add(-2 * time.Hour),
+			previous:      running(soaking, time.Hour),
+			kubectl:       &unhealthyKubectl{},
+			wantState:     v1.StateRunning,
+			wantUpdates:   1,
+			wantRemaining: 15 * time.Second,
+		},
+		{
+			it:            "should not update the status while retrying",
+			since:         now.Add(-2 * time.Hour),
+			previous:      running("waiting for healthy a", 2*time.Minute),
+			kubectl:       &unhealthyKubectl{},
+			wantUpdates:   0,
+			wantRemaining: time.Minute,
+		},
+		{
+			it:          "should error when unhealthy for too long",
+			since:       now.Add(-2 * time.Hour),
+			previous:    running("waiting for healthy a", healthTimeout),
+			kubectl:     &unhealthyKubectl{},
+			wantState:   v1.StateError,
+			wantUpdates: 1,
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			st := &RolloutGateStep{
+				Since:    tst.since,
+				SoakTime: time.Hour,
+				Previous: tst.previous,
+				KCPaths:  map[string]string{"a": "kubeconfig"},
+				Kubectl:  tst.kubectl,
+			}
+			var updates int
+			st.SetOnUpdate(func(Meta) { updates++ })
+
+			st.Execute(logr.NewContext(context.Background(), logr.Discard()), nil)
+
+			assert.Equal(t, tst.wantState, st.GetState())
+			assert.Equal(t, tst.wantUpdates, updates)
+			assert.InDelta(t, tst.wantRemaining, st.Remaining, float64(time.Second))
+		})
+	}
+}
+
+func Test_clusterHealth(t *testing.T) {
+	node := func(name string, ready corev1.ConditionStatus) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: ready},
+			}},
+		}
+	}
+	pod := func(name string, phase corev1.PodPhase, ready corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "addons", Name: name},
+			Status: corev1.PodStatus{
+				Phase: phase,
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodReady, Status: ready},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		it    string
+		nodes []corev1.Node
+		pods  []corev1.Pod
+		want  string
+	}{
+		{
+			it:    "should be healthy when all nodes and pods are ready",
+			nodes: []corev1.Node{node("n0", corev1.ConditionTrue), node("n1", corev1.ConditionTrue)},
+			pods:  []corev1.Pod{pod("p0", corev1.PodRunning, corev1.ConditionTrue), pod("job", corev1.PodSucceeded, corev1.ConditionFalse)},
+		},
+		{
+			it:   "should be unhealthy without nodes",
+			want: "no nodes",
+		},
+		{
+			it:    "should report nodes and pods that are not ready",
+			nodes: []corev1.Node{node("n0", corev1.ConditionTrue), node("n1", corev1.ConditionUnknown)},
+			pods:  []corev1.Pod{pod("p0", corev1.PodRunning, corev1.ConditionFalse), pod("p1", corev1.PodPending, corev1.ConditionFalse)},
+			want:  "node n1 not ready, pod addons/p0 not ready, pod addons/p1 Pending",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			err := clusterHealth(tst.nodes, tst.pods)
+			if tst.want == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tst.want)
+		})
+	}
+}
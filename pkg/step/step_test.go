@@ -1,8 +1,15 @@
 package step
 
 import (
+	"context"
 	"errors"
+	v1 "github.com/mmlt/environment-operator/api/v1"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
 	"testing"
 )
 
@@ -46,3 +53,49 @@ func TestTypesFromString(t *testing.T) {
 		})
 	}
 }
+
+func TestMetaa_phase(t *testing.T) {
+	tests := []struct {
+		it         string
+		final      v1.StepState
+		wantNames  []string
+		wantStatus []codes.Code
+	}{
+		{
+			it:         "should end all phase spans when step is ready",
+			final:      v1.StateReady,
+			wantNames:  []string{"init", "plan"},
+			wantStatus: []codes.Code{codes.Unset, codes.Unset},
+		},
+		{
+			it:         "should set error status of last phase span when step fails",
+			final:      v1.StateError,
+			wantNames:  []string{"init", "plan"},
+			wantStatus: []codes.Code{codes.Unset, codes.Error},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.it, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+			defer otel.SetTracerProvider(trace.NewNoopTracerProvider())
+
+			m := &Metaa{}
+			m.update(v1.StateRunning, "init")
+			m.phase(context.Background(), "init")
+			m.update(v1.StateRunning, "plan")
+			m.phase(context.Background(), "plan")
+			assert.Len(t, sr.Ended(), 1, "previous phase ended")
+			m.update(tt.final, "done")
+
+			var names []string
+			var status []codes.Code
+			for _, s := range sr.Ended() {
+				names = append(names, s.Name())
+				status = append(status, s.Status().Code)
+			}
+			assert.Equal(t, tt.wantNames, names)
+			assert.Equal(t, tt.wantStatus, status)
+		})
+	}
+}
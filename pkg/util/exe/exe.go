@@ -1,12 +1,13 @@
 package exe
 
 import (
-	"bytes"
 	"context"
-	"fmt"
 	"github.com/go-logr/logr"
-	"io"
+	"github.com/mmlt/environment-operator/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"os/exec"
+	"strings"
 )
 
 // Opt are the exec options, see https://godoc.org/os/exec#Cmd for details.
@@ -17,52 +18,39 @@ type Opt struct {
 	Env []string
 }
 
-// Run executes 'cmd' with 'stdin', 'args' and (optional) 'options'.
+// RunContext executes 'cmd' with 'stdin', 'args' and (optional) 'options' in a span that is a child of the span in ctx.
 // Return stdout and stderr upon completion.
-func Run(log logr.Logger, options *Opt, stdin string, cmd string, args ...string) (stdout, stderr string, err error) {
-	log.V(2).Info("Run", "cmd", cmd, "args", args)
-
-	c := exec.Command(cmd, args...)
-
+// See Executor for timeouts, retries and redaction.
+func RunContext(ctx context.Context, log logr.Logger, options *Opt, stdin string, cmd string, args ...string) (stdout, stderr string, err error) {
+	c := Command{Name: cmd, Args: args, Stdin: stdin}
 	if options != nil {
 		c.Env = options.Env
 		c.Dir = options.Dir
 	}
+	r, err := (&Executor{Log: log}).Run(ctx, c)
 
-	if stdin != "" {
-		sin, err := c.StdinPipe()
-		if err != nil {
-			log.Error(err, "should not happen")
-			return "", "", err
-		}
-
-		go func() {
-			_, err := io.WriteString(sin, stdin)
-			if err != nil {
-				log.Error(err, "write stdin")
-			}
-			err = sin.Close()
-			if err != nil {
-				log.Error(err, "close stdin")
-			}
-		}()
-	}
+	return r.Stdout, r.Stderr, err
+}
 
-	var sout, serr bytes.Buffer
-	c.Stdout, c.Stderr = &sout, &serr
-	err = c.Run()
-	stdout, stderr = string(sout.Bytes()), string(serr.Bytes())
-	log.V(3).Info("Run-result", "stderr", stderr, "stdout", stdout)
-	if err != nil {
-		return stdout, stderr, fmt.Errorf("%s %v: %w - %s", cmd, args, err, stderr)
+// StartSpan starts a span for the execution of cmd with args.
+// The span only contains the command name and sub-command (first arg), other args might contain secrets.
+func StartSpan(ctx context.Context, cmd string, args ...string) (context.Context, trace.Span) {
+	name := cmd
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name += " " + args[0]
 	}
+	return tracing.Start(ctx, "exe "+name, attribute.String("exe.command", cmd))
+}
 
-	return
+// Run executes 'cmd' with 'stdin', 'args' and (optional) 'options'.
+// Return stdout and stderr upon completion.
+func Run(log logr.Logger, options *Opt, stdin string, cmd string, args ...string) (stdout, stderr string, err error) {
+	return RunContext(context.Background(), log, options, stdin, cmd, args...)
 }
 
 // RunAsync returns an exec.Cmd that requires Start() and Wait() to run it.
 func RunAsync(ctx context.Context, log logr.Logger, options *Opt, _ /*stdin*/ string, cmd string, args ...string) *exec.Cmd {
-	log.V(2).Info("RunAsync", "cmd", cmd, "args", args)
+	log.V(2).Info("RunAsync", "cmd", cmd, "args", RedactArgs(args, nil))
 
 	c := exec.CommandContext(ctx, cmd, args...)
 
@@ -0,0 +1,165 @@
+package exe
+
+import (
+	"context"
+	"errors"
+	"github.com/go-logr/stdr"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestExecutor_Run(t *testing.T) {
+	var tests = []struct {
+		it           string
+		cmd          Command
+		wantErr      string
+		wantExitCode int
+		wantAttempts int
+		wantTimeout  bool
+	}{
+		{
+			it:           "should_return_exit_code_0_on_success",
+			cmd:          Command{Name: "true"},
+			wantExitCode: 0,
+			wantAttempts: 1,
+		}, {
+			it:           "should_return_exit_code_on_failure",
+			cmd:          Command{Name: "sh", Args: []string{"-c", "echo boom >&2; exit 3"}},
+			wantErr:      "sh [-c echo boom >&2; exit 3]: exit status 3 - boom\n",
+			wantExitCode: 3,
+			wantAttempts: 1,
+		}, {
+			it:           "should_retry_transient_errors",
+			cmd:          Command{Name: "sh", Args: []string{"-c", "echo TooManyRequests >&2; exit 1"}},
+			wantErr:      "sh [-c echo TooManyRequests >&2; exit 1]: exit status 1 - TooManyRequests\n",
+			wantExitCode: 1,
+			wantAttempts: 3,
+		}, {
+			it:           "should_redact_secrets_in_errors",
+			cmd:          Command{Name: "sh", Args: []string{"-c", "echo s3cret >&2; exit 1"}, Secrets: []string{"s3cret"}},
+			wantErr:      "sh [-c echo *** >&2; exit 1]: exit status 1 - ***\n",
+			wantExitCode: 1,
+			wantAttempts: 1,
+		}, {
+			it:           "should_stop_on_timeout",
+			cmd:          Command{Name: "sleep", Args: []string{"5"}, Timeout: 100 * time.Millisecond},
+			wantErr:      "sleep [5]: timeout after 100ms: context deadline exceeded - ",
+			wantExitCode: -1,
+			wantAttempts: 1,
+			wantTimeout:  true,
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			e := &Executor{
+				RetryDelay: time.Millisecond,
+				Transient: func(cmd Command, r Result) bool {
+					return r.Stderr == "TooManyRequests\n"
+				},
+				Log: stdr.New(nil),
+			}
+			r, err := e.Run(context.Background(), tst.cmd)
+			if tst.wantErr != "" {
+				assert.EqualError(t, err, tst.wantErr)
+				var ee *Error
+				assert.True(t, errors.As(err, &ee), "errors.As *Error")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tst.wantExitCode, r.ExitCode, "exit code")
+			assert.Equal(t, tst.wantAttempts, r.Attempts, "attempts")
+			assert.Equal(t, tst.wantTimeout, errors.Is(err, context.DeadlineExceeded), "timeout")
+		})
+	}
+}
+
+func TestExecutor_Run_exitError(t *testing.T) {
+	_, err := (&Executor{}).Run(context.Background(), Command{Name: "false"})
+
+	var ee *exec.ExitError
+	if assert.True(t, errors.As(err, &ee), "errors.As *exec.ExitError") {
+		assert.Equal(t, 1, ee.ExitCode())
+	}
+}
+
+func TestExecutor_Start_exitError(t *testing.T) {
+	rc, err := (&Executor{}).Start(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo partial; exit 3"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err := ioutil.ReadAll(rc)
+	assert.Equal(t, "partial\n", string(b))
+	assert.Equal(t, 3, ExitCode(err), "reader returns the exit error")
+}
+
+func TestRedactArgs(t *testing.T) {
+	var tests = []struct {
+		it      string
+		args    []string
+		secrets []string
+		want    []string
+	}{
+		{
+			it:   "should_redact_secret_flag_values",
+			args: []string{"login", "--service-principal", "-u", "user", "-p", "pw", "--tenant", "t"},
+			want: []string{"login", "--service-principal", "-u", "user", "-p", "***", "--tenant", "t"},
+		}, {
+			it:   "should_redact_url_credentials",
+			args: []string{"clone", "https://token@github.com/org/repo.git"},
+			want: []string{"clone", "https://***@github.com/org/repo.git"},
+		}, {
+			it:      "should_redact_secrets",
+			args:    []string{"--set", "password=pw"},
+			secrets: []string{"pw"},
+			want:    []string{"--set", "password=***"},
+		}, {
+			it:   "should_leave_other_args_as_is",
+			args: []string{"get", "pods", "-n", "default"},
+			want: []string{"get", "pods", "-n", "default"},
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := RedactArgs(tst.args, tst.secrets)
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	var tests = []struct {
+		it     string
+		cmd    Command
+		stderr string
+		want   bool
+	}{
+		{
+			it:     "should_detect_az_throttling",
+			cmd:    Command{Name: "az"},
+			stderr: "ERROR: (TooManyRequests) Too many requests",
+			want:   true,
+		}, {
+			it:     "should_not_retry_other_az_errors",
+			cmd:    Command{Name: "az"},
+			stderr: "ERROR: (ResourceNotFound) not found",
+			want:   false,
+		}, {
+			it:     "should_not_retry_unknown_commands",
+			cmd:    Command{Name: "terraform"},
+			stderr: "TooManyRequests",
+			want:   false,
+		},
+	}
+
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			got := IsTransient(tst.cmd, Result{Stderr: tst.stderr})
+			assert.Equal(t, tst.want, got)
+		})
+	}
+}
@@ -0,0 +1,319 @@
+package exe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sigs.k8s.io/yaml"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Starter is a Runner that is able to start commands without waiting for completion.
+type Starter interface {
+	Runner
+	// Start starts cmd and returns its combined stdout and stderr.
+	// The output reaches EOF when the command has completed.
+	Start(ctx context.Context, cmd Command) (io.ReadCloser, error)
+}
+
+// Transcript is a recording of command invocations.
+type Transcript struct {
+	Invocations []Invocation `json:"invocations"`
+}
+
+// Invocation is a recorded command invocation.
+type Invocation struct {
+	// Name of the command.
+	Name string `json:"name"`
+	// Args are the (redacted) arguments.
+	// When replaying an arg "*" matches any value.
+	Args []string `json:"args,omitempty"`
+	// EnvKeys are the names of the environment variables (values are not recorded).
+	EnvKeys []string `json:"envKeys,omitempty"`
+	// Async is true for a command that has been started (stdout contains the combined output).
+	Async bool `json:"async,omitempty"`
+	// Stdout and Stderr are the output of the command.
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	// ExitCode of the command.
+	ExitCode int `json:"exitCode"`
+	// Duration of the command, for example "1.5s".
+	Duration string `json:"duration,omitempty"`
+}
+
+// ReadTranscript reads a YAML transcript from path.
+func ReadTranscript(path string) (*Transcript, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transcript{}
+	err = yaml.Unmarshal(b, t)
+	if err != nil {
+		return nil, fmt.Errorf("transcript %s: %w", path, err)
+	}
+	return t, nil
+}
+
+// WriteTranscript writes t as YAML to path.
+func WriteTranscript(path string, t *Transcript) error {
+	b, err := yaml.Marshal(t)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0640)
+}
+
+// Recorder is a Runner that records the invocations of Runner in a transcript file at Path.
+// The file is rewritten after each invocation.
+type Recorder struct {
+	// Runner executes the commands.
+	Runner Runner
+	// Path of the transcript file.
+	Path string
+
+	mu         sync.Mutex
+	transcript Transcript
+}
+
+var _ Starter = &Recorder{}
+
+// Run implements Runner.
+// The stdout of a cmd with SecretOutput is not recorded.
+func (r *Recorder) Run(ctx context.Context, cmd Command) (Result, error) {
+	res, err := r.Runner.Run(ctx, cmd)
+
+	inv := invocation(cmd)
+	inv.Stdout = Redact(res.Stdout, cmd.Secrets)
+	if cmd.SecretOutput {
+		inv.Stdout = "<redacted>"
+	}
+	inv.Stderr = Redact(res.Stderr, cmd.Secrets)
+	inv.ExitCode = res.ExitCode
+	inv.Duration = res.Duration.String()
+	r.add(inv)
+
+	return res, err
+}
+
+// Start implements Starter.
+// Runner must implement Starter.
+// The output of a cmd with SecretOutput is not recorded.
+func (r *Recorder) Start(ctx context.Context, cmd Command) (io.ReadCloser, error) {
+	s, ok := r.Runner.(Starter)
+	if !ok {
+		return nil, fmt.Errorf("recorder: %T can't start commands", r.Runner)
+	}
+	rc, err := s.Start(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	inv := invocation(cmd)
+	inv.Async = true
+	return &teeReadCloser{
+		ReadCloser: rc,
+		start:      time.Now(),
+		done: func(out string, d time.Duration, err error) {
+			inv.Stdout = Redact(out, cmd.Secrets)
+			inv.ExitCode = ExitCode(err)
+			if cmd.SecretOutput {
+				inv.Stdout = "<redacted>"
+			}
+			inv.Duration = d.String()
+			r.add(inv)
+		},
+	}, nil
+}
+
+// Add adds inv to the transcript and writes it to file.
+func (r *Recorder) add(inv Invocation) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transcript.Invocations = append(r.transcript.Invocations, inv)
+	// best effort; a recording is a debug aid.
+	_ = WriteTranscript(r.Path, &r.transcript)
+}
+
+// Invocation returns an Invocation with the command details of cmd.
+func invocation(cmd Command) Invocation {
+	var keys []string
+	for _, kv := range cmd.Env {
+		keys = append(keys, strings.SplitN(kv, "=", 2)[0])
+	}
+	sort.Strings(keys)
+
+	return Invocation{
+		Name:    cmd.Name,
+		Args:    RedactArgs(cmd.Args, cmd.Secrets),
+		EnvKeys: keys,
+	}
+}
+
+// TeeReadCloser calls done with all data read and the time since start when EOF is reached.
+type teeReadCloser struct {
+	io.ReadCloser
+	start time.Time
+	done  func(string, time.Duration, error)
+
+	buf  bytes.Buffer
+	once sync.Once
+}
+
+func (t *teeReadCloser) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.Write(p[:n])
+	if err != nil {
+		e := err
+		if e == io.EOF {
+			e = nil
+		}
+		t.once.Do(func() { t.done(t.buf.String(), time.Since(t.start), e) })
+	}
+	return n, err
+}
+
+// Replayer is a Runner that returns the results of recorded invocations.
+type Replayer struct {
+	// Transcript contains the invocations to replay.
+	// An invocation is replayed once, when all matching invocations have been replayed the last one is repeated.
+	Transcript *Transcript
+	// Speed (optional) replays the invocations with their recorded duration divided by Speed.
+	Speed float64
+
+	mu sync.Mutex
+	// used invocations
+	used map[int]bool
+}
+
+var _ Starter = &Replayer{}
+
+// Run implements Runner.
+// A command without recorded invocation returns an error.
+func (r *Replayer) Run(ctx context.Context, cmd Command) (Result, error) {
+	inv, err := r.next(cmd, false)
+	if err != nil {
+		return Result{ExitCode: -1}, err
+	}
+
+	d := r.delay(inv)
+	select {
+	case <-ctx.Done():
+		return Result{ExitCode: -1}, ctx.Err()
+	case <-time.After(d):
+	}
+
+	res := Result{
+		Stdout:   inv.Stdout,
+		Stderr:   inv.Stderr,
+		ExitCode: inv.ExitCode,
+		Duration: d,
+		Attempts: 1,
+	}
+	if inv.ExitCode != 0 {
+		return res, &Error{
+			Name:     inv.Name,
+			Args:     RedactArgs(cmd.Args, cmd.Secrets),
+			ExitCode: inv.ExitCode,
+			Stderr:   inv.Stderr,
+			Err:      fmt.Errorf("exit status %d", inv.ExitCode),
+		}
+	}
+	return res, nil
+}
+
+// Start implements Starter.
+func (r *Replayer) Start(ctx context.Context, cmd Command) (io.ReadCloser, error) {
+	inv, err := r.next(cmd, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		lines := strings.SplitAfter(inv.Stdout, "\n")
+		d := r.delay(inv) / time.Duration(len(lines))
+		for _, l := range lines {
+			select {
+			case <-ctx.Done():
+				_ = pw.CloseWithError(ctx.Err())
+				return
+			case <-time.After(d):
+			}
+			if _, err := io.WriteString(pw, l); err != nil {
+				return
+			}
+		}
+		var err error
+		if inv.ExitCode != 0 {
+			err = &Error{
+				Name:     inv.Name,
+				Args:     RedactArgs(cmd.Args, cmd.Secrets),
+				ExitCode: inv.ExitCode,
+				Err:      fmt.Errorf("exit status %d", inv.ExitCode),
+			}
+		}
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// Next returns the next invocation that matches cmd.
+func (r *Replayer) next(cmd Command, async bool) (Invocation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.used == nil {
+		r.used = make(map[int]bool)
+	}
+
+	args := RedactArgs(cmd.Args, cmd.Secrets)
+	last := -1
+	for i, inv := range r.Transcript.Invocations {
+		if inv.Name != cmd.Name || inv.Async != async || !matchArgs(inv.Args, args) {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return inv, nil
+		}
+		last = i
+	}
+	if last >= 0 {
+		return r.Transcript.Invocations[last], nil
+	}
+
+	return Invocation{}, fmt.Errorf("replay: no recorded invocation of %s %v", cmd.Name, args)
+}
+
+// Delay returns the time it takes to replay inv.
+func (r *Replayer) delay(inv Invocation) time.Duration {
+	if r.Speed <= 0 || inv.Duration == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(inv.Duration)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(float64(d) / r.Speed)
+}
+
+// MatchArgs returns true when args match the recorded args (with "*" matching any value).
+func matchArgs(recorded, args []string) bool {
+	if len(recorded) != len(args) {
+		return false
+	}
+	for i, a := range recorded {
+		if a != "*" && a != args[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package exe
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "transcript")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "transcript.yaml")
+
+	rec := &Recorder{Runner: &Executor{}, Path: path}
+
+	_, err = rec.Run(context.Background(), Command{Name: "sh", Args: []string{"-c", "echo hello s3cret; echo oops >&2; exit 2"},
+		Env: []string{"B=2", "A=1"}, Secrets: []string{"s3cret"}})
+	assert.Error(t, err)
+
+	rc, err := rec.Start(context.Background(), Command{Name: "echo", Args: []string{"async"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "async\n", string(b))
+
+	rc, err = rec.Start(context.Background(), Command{Name: "echo", Args: []string{"kubeconfig"}, SecretOutput: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	_, err = ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+
+	got, err := ReadTranscript(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	for i := range got.Invocations {
+		assert.NotEmpty(t, got.Invocations[i].Duration)
+		got.Invocations[i].Duration = ""
+	}
+	want := &Transcript{Invocations: []Invocation{
+		{
+			Name:     "sh",
+			Args:     []string{"-c", "echo hello ***; echo oops >&2; exit 2"},
+			EnvKeys:  []string{"A", "B"},
+			Stdout:   "hello ***\n",
+			Stderr:   "oops\n",
+			ExitCode: 2,
+		},
+		{
+			Name:   "echo",
+			Args:   []string{"async"},
+			Async:  true,
+			Stdout: "async\n",
+		},
+		{
+			Name:   "echo",
+			Args:   []string{"kubeconfig"},
+			Async:  true,
+			Stdout: "<redacted>",
+		},
+	}}
+	assert.Equal(t, want, got)
+}
+
+func TestReplayer_Run(t *testing.T) {
+	rp := &Replayer{Transcript: &Transcript{Invocations: []Invocation{
+		{Name: "az", Args: []string{"aks", "nodepool", "list", "*"}, Stderr: "ERROR: (TooManyRequests)", ExitCode: 1},
+		{Name: "az", Args: []string{"aks", "nodepool", "list", "*"}, Stdout: "[]"},
+		{Name: "az", Args: []string{"logout"}},
+	}}}
+	cmd := Command{Name: "az", Args: []string{"aks", "nodepool", "list", "--cluster-name=x"}}
+
+	tests := []struct {
+		it         string
+		cmd        Command
+		wantStdout string
+		wantErr    string
+	}{
+		{
+			it:      "should replay the first matching invocation",
+			cmd:     cmd,
+			wantErr: "az [aks nodepool list --cluster-name=x]: exit status 1 - ERROR: (TooManyRequests)",
+		},
+		{
+			it:         "should replay the next matching invocation",
+			cmd:        cmd,
+			wantStdout: "[]",
+		},
+		{
+			it:         "should repeat the last matching invocation",
+			cmd:        cmd,
+			wantStdout: "[]",
+		},
+		{
+			it:      "should error when no invocation matches",
+			cmd:     Command{Name: "az", Args: []string{"login", "-p", "pw"}},
+			wantErr: "replay: no recorded invocation of az [login -p ***]",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			r, err := rp.Run(context.Background(), tst.cmd)
+			if tst.wantErr != "" {
+				assert.EqualError(t, err, tst.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tst.wantStdout, r.Stdout)
+		})
+	}
+}
+
+func TestReplayer_Start(t *testing.T) {
+	rp := &Replayer{Transcript: &Transcript{Invocations: []Invocation{
+		{Name: "terraform", Args: []string{"apply"}, Async: true, Stdout: "line 1\nline 2\n"},
+		{Name: "terraform", Args: []string{"destroy"}, Async: true, Stdout: "Error: oops\n", ExitCode: 1},
+	}}}
+
+	_, err := rp.Run(context.Background(), Command{Name: "terraform", Args: []string{"apply"}})
+	assert.Error(t, err, "async invocations are not replayed by Run")
+
+	rc, err := rp.Start(context.Background(), Command{Name: "terraform", Args: []string{"apply"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err := ioutil.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\n", string(b))
+
+	rc, err = rp.Start(context.Background(), Command{Name: "terraform", Args: []string{"destroy"}})
+	if !assert.NoError(t, err) {
+		return
+	}
+	b, err = ioutil.ReadAll(rc)
+	assert.Equal(t, "Error: oops\n", string(b))
+	assert.Equal(t, 1, ExitCode(err), "a recorded non-zero exit is replayed as error")
+}
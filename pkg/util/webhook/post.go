@@ -0,0 +1,43 @@
+// Package webhook posts payloads to HTTP endpoints.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the timeout of a post when no client is passed.
+const defaultTimeout = 10 * time.Second
+
+// Post posts body with contentType to url and returns an error when the response status is not 2xx.
+// A nil client uses a default client with a timeout.
+// Post doesn't retry, a failed post is lost (at-most-once delivery).
+func Post(ctx context.Context, client *http.Client, url, contentType string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection can be reused.
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("post: %s", resp.Status)
+	}
+
+	return nil
+}
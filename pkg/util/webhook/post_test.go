@@ -0,0 +1,56 @@
+package webhook
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPost(t *testing.T) {
+	tests := []struct {
+		it     string
+		status int
+		err    string
+	}{
+		{
+			it:     "should post the body",
+			status: http.StatusOK,
+		},
+		{
+			it:     "should accept any 2xx status",
+			status: http.StatusNoContent,
+		},
+		{
+			it:     "should return an error on a non 2xx status",
+			status: http.StatusForbidden,
+			err:    "post: 403 Forbidden",
+		},
+	}
+	for _, tst := range tests {
+		t.Run(tst.it, func(t *testing.T) {
+			var body, contentType, method string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+				contentType = r.Header.Get("Content-Type")
+				method = r.Method
+				w.WriteHeader(tst.status)
+			}))
+			defer srv.Close()
+
+			err := Post(context.Background(), nil, srv.URL, "application/json", []byte(`{"a":1}`))
+
+			if tst.err != "" {
+				assert.EqualError(t, err, tst.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, http.MethodPost, method)
+			assert.Equal(t, "application/json", contentType)
+			assert.Equal(t, `{"a":1}`, body)
+		})
+	}
+}